@@ -0,0 +1,157 @@
+// cmd/json2csv-server/grpc.go
+
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/pradnyoday/go-json2csv/json2csv"
+)
+
+// The gRPC service is described by json2csv.proto. Its messages are the
+// well-known wrapper types, so it needs no generated code: the service
+// descriptor below is what protoc-gen-go-grpc would write for it.
+var converterServiceDesc = grpc.ServiceDesc{
+	ServiceName: "json2csv.v1.Converter",
+	HandlerType: (*interface{})(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Convert",
+			Handler:       func(srv interface{}, stream grpc.ServerStream) error { return srv.(*server).grpcConvert(stream) },
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Export",
+			Handler:       func(srv interface{}, stream grpc.ServerStream) error { return srv.(*server).grpcExport(stream) },
+			ClientStreams: true,
+		},
+	},
+	Metadata: "json2csv.proto",
+}
+
+// grpcConvert converts the JSON streamed by the client, streaming the rows
+// back as they are produced.
+func (s *server) grpcConvert(stream grpc.ServerStream) error {
+	config := metadataValue(stream.Context(), "json2csv-config")
+	converter, err := s.grpcLookup(config)
+	if err != nil {
+		return err
+	}
+	if err := converter.Convert(s.newStreamReader(stream), streamWriter{stream}); err != nil {
+		log.Printf("json2csv-server: grpc convert %q: %v", config, err)
+		return grpcError(err)
+	}
+	return nil
+}
+
+// grpcExport converts the JSON streamed by the client into a file in the
+// sink directory and responds with the number of bytes written.
+func (s *server) grpcExport(stream grpc.ServerStream) error {
+	if s.sinkDir == "" {
+		return status.Error(codes.Unimplemented, "export is disabled")
+	}
+	config := metadataValue(stream.Context(), "json2csv-config")
+	converter, err := s.grpcLookup(config)
+	if err != nil {
+		return err
+	}
+	file := metadataValue(stream.Context(), "json2csv-file")
+	if !validFileName(file) {
+		return status.Errorf(codes.InvalidArgument, "invalid file name %q", file)
+	}
+	n, err := s.export(converter, file, s.newStreamReader(stream))
+	if err != nil {
+		log.Printf("json2csv-server: grpc export %q to %q: %v", config, file, err)
+		return grpcError(err)
+	}
+	return stream.SendMsg(wrapperspb.Int64(n))
+}
+
+// grpcLookup returns the converter of the config named config, or a
+// NotFound error if there is none.
+func (s *server) grpcLookup(config string) (*json2csv.Converter, error) {
+	converter, ok := s.converters[config]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown config %q", config)
+	}
+	return converter, nil
+}
+
+// grpcError returns the status error for a failed conversion.
+func grpcError(err error) error {
+	if tooLarge := (*http.MaxBytesError)(nil); errors.As(err, &tooLarge) {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	if s, ok := status.FromError(err); ok && s.Code() != codes.Unknown {
+		return s.Err() // E.g. the client canceled the call.
+	}
+	return status.Error(codes.InvalidArgument, err.Error())
+}
+
+// metadataValue returns the first value of the request metadata key, or "".
+func metadataValue(ctx context.Context, key string) string {
+	if values := metadata.ValueFromIncomingContext(ctx, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// streamReader reads the bytes of the messages the client streams, failing
+// like http.MaxBytesReader past limit bytes.
+type streamReader struct {
+	stream grpc.ServerStream
+	buf    []byte
+	limit  int64
+	left   int64 // Bytes of the limit not yet read
+	err    error
+}
+
+func (s *server) newStreamReader(stream grpc.ServerStream) *streamReader {
+	return &streamReader{stream: stream, limit: s.maxBodyBytes, left: s.maxBodyBytes}
+}
+
+func (sr *streamReader) Read(p []byte) (int, error) {
+	for len(sr.buf) == 0 {
+		if sr.err != nil {
+			return 0, sr.err
+		}
+		var chunk wrapperspb.BytesValue
+		if err := sr.stream.RecvMsg(&chunk); err != nil {
+			return 0, err // io.EOF once the client has sent everything
+		}
+		sr.buf = chunk.Value
+		if int64(len(sr.buf)) > sr.left {
+			sr.buf = sr.buf[:sr.left]
+			sr.err = &http.MaxBytesError{Limit: sr.limit}
+		}
+		sr.left -= int64(len(sr.buf))
+	}
+	n := copy(p, sr.buf)
+	sr.buf = sr.buf[n:]
+	return n, nil
+}
+
+// streamWriter sends every write to the client as a message, so rows are
+// streamed as the conversion produces them (in chunks of the output buffer
+// size).
+type streamWriter struct {
+	stream grpc.ServerStream
+}
+
+func (sw streamWriter) Write(p []byte) (int, error) {
+	// SendMsg encodes the message before it returns, so p is not retained.
+	if err := sw.stream.SendMsg(wrapperspb.Bytes(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,290 @@
+// cmd/json2csv-server/main.go
+
+// Command json2csv-server exposes json2csv conversions as an HTTP service.
+// Mappings are loaded at startup from JSON config files (see
+// json2csv.Config), each registered under a name:
+//
+//	json2csv-server -addr :8080 -config orders=orders.json -config users=users.json -sink-dir /exports
+//
+// Endpoints:
+//
+//	GET  /v1/configs                  lists the config names
+//	POST /v1/convert/{config}         converts the JSON array in the request
+//	                                  body and streams the rows back
+//	POST /v1/export/{config}/{file}   converts the request body into {file}
+//	                                  in the sink directory (-sink-dir) and
+//	                                  responds with a JSON summary
+//	GET  /healthz                     reports that the server is up
+//
+// With -grpc-addr, the conversions are also served over gRPC by the
+// json2csv.v1.Converter service described in json2csv.proto: Convert and
+// Export take the JSON as a stream of google.protobuf.BytesValue messages,
+// with the config (and the file for Export) named by the "json2csv-config"
+// and "json2csv-file" request metadata. Convert streams the rows back the
+// same way; Export responds with the number of bytes written.
+//
+// Request bodies are decoded as they arrive and rows are flushed to the
+// client as they are produced, so clients can stream large inputs with
+// chunked transfer encoding. A conversion error after the response has
+// started is reported in the X-Json2csv-Error trailer. Bodies larger than
+// -max-body-bytes are rejected.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/pradnyoday/go-json2csv/json2csv"
+)
+
+// configFlags collects repeated -config name=path flags.
+type configFlags map[string]string
+
+func (c configFlags) String() string { return fmt.Sprint(map[string]string(c)) }
+
+func (c configFlags) Set(value string) error {
+	name, path, ok := strings.Cut(value, "=")
+	if !ok || name == "" || path == "" {
+		return fmt.Errorf("expected name=path, got %q", value)
+	}
+	c[name] = path
+	return nil
+}
+
+func main() {
+	configs := configFlags{}
+	addr := flag.String("addr", ":8080", "listen address")
+	sinkDir := flag.String("sink-dir", "", "directory for /v1/export output files (export is disabled if empty)")
+	grpcAddr := flag.String("grpc-addr", "", "listen address of the gRPC service (disabled if empty)")
+	maxBodyBytes := flag.Int64("max-body-bytes", 1<<30, "maximum size of a request body in bytes")
+	flag.Var(configs, "config", "mapping config as name=path; may be repeated")
+	flag.Parse()
+
+	if len(configs) == 0 {
+		log.Fatal("json2csv-server: at least one -config is required")
+	}
+	s := &server{converters: map[string]*json2csv.Converter{}, sinkDir: *sinkDir, maxBodyBytes: *maxBodyBytes}
+	for name, path := range configs {
+		config, err := json2csv.LoadConfig(path)
+		if err != nil {
+			log.Fatalf("json2csv-server: config %q: %v", name, err)
+		}
+		options, err := config.Options()
+		if err != nil {
+			log.Fatalf("json2csv-server: config %q: %v", name, err)
+		}
+		converter, err := json2csv.NewConverter(options)
+		if err != nil {
+			log.Fatalf("json2csv-server: config %q: %v", name, err)
+		}
+		s.converters[name] = converter
+	}
+
+	httpServer := &http.Server{Addr: *addr, Handler: s.routes()}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	var grpcServer *grpc.Server
+	if *grpcAddr != "" {
+		listener, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		grpcServer = grpc.NewServer()
+		grpcServer.RegisterService(&converterServiceDesc, s)
+		log.Printf("json2csv-server: serving gRPC on %s", *grpcAddr)
+		go func() {
+			if err := grpcServer.Serve(listener); err != nil {
+				log.Fatal(err)
+			}
+		}()
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if grpcServer != nil {
+			go func() {
+				<-shutdownCtx.Done()
+				grpcServer.Stop()
+			}()
+			grpcServer.GracefulStop()
+		}
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("json2csv-server: listening on %s", *addr)
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+}
+
+type server struct {
+	converters   map[string]*json2csv.Converter
+	sinkDir      string
+	maxBodyBytes int64
+}
+
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok\n")
+	})
+	mux.HandleFunc("GET /v1/configs", s.handleConfigs)
+	mux.HandleFunc("POST /v1/convert/{config}", s.handleConvert)
+	mux.HandleFunc("POST /v1/export/{config}/{file}", s.handleExport)
+	return mux
+}
+
+func (s *server) handleConfigs(w http.ResponseWriter, r *http.Request) {
+	names := make([]string, 0, len(s.converters))
+	for name := range s.converters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"configs": names})
+}
+
+func (s *server) handleConvert(w http.ResponseWriter, r *http.Request) {
+	converter, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+
+	// Rows are streamed back while the body is still being read, which
+	// HTTP/1.1 servers do not allow by default. HTTP/2 always does, so the
+	// error for it is ignored.
+	http.NewResponseController(w).EnableFullDuplex()
+	body := http.MaxBytesReader(w, r.Body, s.maxBodyBytes)
+
+	w.Header().Set("Content-Type", contentType(converter.Options().Format))
+	w.Header().Set("Trailer", "X-Json2csv-Error")
+	if err := converter.Convert(body, flushWriter{w}); err != nil {
+		log.Printf("json2csv-server: convert %q: %v", r.PathValue("config"), err)
+		w.Header().Set("X-Json2csv-Error", err.Error())
+	}
+}
+
+func (s *server) handleExport(w http.ResponseWriter, r *http.Request) {
+	if s.sinkDir == "" {
+		writeError(w, http.StatusNotFound, errors.New("export is disabled"))
+		return
+	}
+	converter, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	file := r.PathValue("file")
+	if !validFileName(file) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid file name %q", file))
+		return
+	}
+
+	n, err := s.export(converter, file, http.MaxBytesReader(w, r.Body, s.maxBodyBytes))
+	if err != nil {
+		log.Printf("json2csv-server: export %q to %q: %v", r.PathValue("config"), file, err)
+		status := http.StatusUnprocessableEntity
+		if tooLarge := (*http.MaxBytesError)(nil); errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		writeError(w, status, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"file": file, "bytes": n})
+}
+
+// validFileName reports whether file names a file directly in the sink
+// directory that is not hidden.
+func validFileName(file string) bool {
+	return file == filepath.Base(file) && !strings.HasPrefix(file, ".")
+}
+
+// export converts body into file in the sink directory and returns the
+// number of bytes written. It writes to a temporary file first so a failed
+// export never replaces a good one.
+func (s *server) export(converter *json2csv.Converter, file string, body io.Reader) (int64, error) {
+	tmp, err := os.CreateTemp(s.sinkDir, "."+file+".tmp*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	counter := &countingWriter{w: tmp}
+	err = converter.Convert(body, counter)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(s.sinkDir, file))
+	}
+	return counter.n, err
+}
+
+// lookup returns the converter of the config named in the request path,
+// writing a 404 response if there is none.
+func (s *server) lookup(w http.ResponseWriter, r *http.Request) (*json2csv.Converter, bool) {
+	converter, ok := s.converters[r.PathValue("config")]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown config %q", r.PathValue("config")))
+	}
+	return converter, ok
+}
+
+func contentType(format json2csv.Format) string {
+	switch format {
+	case json2csv.FormatMarkdown:
+		return "text/markdown; charset=utf-8"
+	case json2csv.FormatHTML:
+		return "text/html; charset=utf-8"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// flushWriter flushes every write to the client, so rows are streamed as
+// the conversion produces them (in chunks of the output buffer size).
+type flushWriter struct {
+	w http.ResponseWriter
+}
+
+func (f flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if flusher, ok := f.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return n, err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/pradnyoday/go-json2csv/json2csv" // Import your package
 )
@@ -84,32 +85,20 @@ func main() {
 			{JSONPath: "user_name", CSVHeader: "User Name"},
 			{JSONPath: "is_active", CSVHeader: "Active Status", Transformer: json2csv.BoolToYesNo},
 			{JSONPath: "address.city", CSVHeader: "City"}, // Nested field from parent
-			{JSONPath: "created_at", CSVHeader: "Created At", Transformer: json2csv.FormatUnixTimestamp},
+			{JSONPath: "created_at", CSVHeader: "Created At", Transformer: json2csv.FormatUnixTimestampIn(time.Local, "")},
 
 			// Fields from the flattened array items, indicated by "[*]"
 			{JSONPath: "items[*].item_id", CSVHeader: "Item ID"},
 			{JSONPath: "items[*].price", CSVHeader: "Item Price"},
 			{JSONPath: "items[*].quantity", CSVHeader: "Quantity"},
-			// Custom transformer for joining an array of tags within the item.
-			{JSONPath: "items[*].tags", CSVHeader: "Item Tags", Transformer: func(value interface{}, originalRecord map[string]interface{}) (interface{}, error) {
-				if tags, ok := value.([]interface{}); ok {
-					// Join the tags array elements into a string
-					tagStrings := make([]string, len(tags))
-					for i, tag := range tags {
-						tagStrings[i] = fmt.Sprintf("%v", tag) // Basic conversion to string
-					}
-					return strings.Join(tagStrings, ";"), nil // Join with semicolon
-				}
-				// Return empty string or nil if tags is not an array or is null/missing
-				return "", nil
-			}},
+			// Join the tags array within the item with semicolons.
+			{JSONPath: "items[*].tags", CSVHeader: "Item Tags", Transformer: json2csv.JoinArray(";")},
             // Example of a field that might be missing in some items
             {JSONPath: "items[*].extra_field", CSVHeader: "Extra Item Field"},
              // Example of getting the item object itself (would be stringified map[...])
             // {JSONPath: "items[*]", CSVHeader: "Item Object String"},
 		},
 		Delimiter: ',',
-		AddHeader: true, // Explicitly add header
 	}
 
 	fmt.Println("Converting JSON to CSV (Inferring Flattening from [*] - Flattening Only)...")
@@ -1,3 +1,26 @@
 module github.com/pradnyoday/go-json2csv
 
 go 1.23.0
+
+require (
+	filippo.io/age v1.2.1
+	github.com/ProtonMail/go-crypto v1.1.6
+	github.com/itchyny/gojq v0.12.17
+	github.com/jmespath/go-jmespath v0.4.0
+	github.com/klauspost/compress v1.18.0
+	github.com/linkedin/goavro/v2 v2.12.0
+	golang.org/x/text v0.16.0
+	google.golang.org/grpc v1.64.1
+	google.golang.org/protobuf v1.33.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/cloudflare/circl v1.3.7 // indirect
+	github.com/golang/snappy v0.0.1 // indirect
+	github.com/itchyny/timefmt-go v0.1.6 // indirect
+	golang.org/x/crypto v0.24.0 // indirect
+	golang.org/x/net v0.26.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240318140521-94a12d6c2237 // indirect
+)
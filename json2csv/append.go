@@ -0,0 +1,140 @@
+// json2csv/append.go
+
+package json2csv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// ErrHeaderMismatch is wrapped by the error of ConvertAppend when the
+// header of the existing file differs from the configured one.
+var ErrHeaderMismatch = errors.New("json2csv: header mismatch")
+
+// ConvertAppend converts r like Convert and appends the rows, without a
+// header, to the CSV file at path, for incremental exports into a rolling
+// file. Unless skipHeaderCheck is set, the header of the file (after any
+// HeaderComment) must equal the header the options produce, cell for cell,
+// or ConvertAppend fails with an error wrapping ErrHeaderMismatch before
+// writing anything. With OmitHeader the file has no header to check. A file
+// that does not exist or is empty is written with a header. Use
+// ConvertAppendEvolving to add columns to an existing file instead.
+//
+// Only FormatCSV is supported, and WrapOutput must not be set.
+func ConvertAppend(path string, r io.Reader, options Options, skipHeaderCheck bool) error {
+	if options.Format != FormatCSV || options.WrapOutput != nil {
+		return errors.New("json2csv: appending requires FormatCSV and no WrapOutput")
+	}
+	c, err := newConversion(nil, options)
+	if err != nil {
+		return err
+	}
+	sources, cleanup, err := c.prepareSources([]Source{{Reader: r}})
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	r = sources[0].Reader
+
+	header, err := readCSVHeader(path, options)
+	if errors.Is(err, os.ErrNotExist) || errors.Is(err, io.EOF) {
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("json2csv: failed to create output file: %w", err)
+		}
+		c.out = NewRowWriter(f, options)
+		return closeFile(f, c.run(func() error { return c.convertSource(Source{Reader: r}) }))
+	}
+	if err != nil {
+		return err
+	}
+	if !skipHeaderCheck && !options.OmitHeader && !slices.Equal(header, c.header) {
+		return fmt.Errorf("%w: %q has the header %q, but the fields produce %q", ErrHeaderMismatch, path, header, c.header)
+	}
+
+	f, err := openForAppend(path)
+	if err != nil {
+		return err
+	}
+	c.options.OmitHeader = true // The file already has one.
+	c.out = NewRowWriter(f, options)
+	return closeFile(f, c.run(func() error { return c.convertSource(Source{Reader: r}) }))
+}
+
+// readCSVHeader returns the first row of the CSV file at path, after the
+// lines of options.HeaderComment, or io.EOF if there is none.
+func readCSVHeader(path string, options Options) ([]string, error) {
+	f, err := openCSVFile(path, options)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	return f.header, nil
+}
+
+// csvFile is an existing CSV file opened by openCSVFile.
+type csvFile struct {
+	*os.File
+	preamble []byte      // The byte order mark and comment lines before the header
+	header   []string    // The header row
+	reader   *csv.Reader // Reads the data rows after the header
+}
+
+// openCSVFile opens the CSV file at path and reads it up to its header row,
+// skipping a UTF-8 byte order mark and the lines of options.HeaderComment. It returns io.EOF if the file
+// has no header.
+func openCSVFile(path string, options Options) (*csvFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("json2csv: failed to open existing file: %w", err)
+	}
+
+	br := bufio.NewReader(f)
+	var preamble []byte
+	if head, _ := br.Peek(len(utf8BOM)); bytes.Equal(head, utf8BOM) {
+		// E.g. saved by a spreadsheet; kept when the file is rewritten.
+		preamble = append(preamble, utf8BOM...)
+		br.Discard(len(utf8BOM))
+	}
+	if options.HeaderComment != "" {
+		prefix := options.CommentPrefix
+		if prefix == "" {
+			prefix = DefaultCommentPrefix
+		}
+		// The comment lines may have changed since the file was written,
+		// so skip every line starting with the prefix.
+		prefix = strings.TrimRight(prefix, " ")
+		for prefix != "" {
+			peek, err := br.Peek(len(prefix))
+			if err != nil || string(peek) != prefix {
+				break
+			}
+			line, err := br.ReadBytes('\n')
+			if err != nil {
+				f.Close()
+				return nil, io.EOF
+			}
+			preamble = append(preamble, line...)
+		}
+	}
+
+	reader := csv.NewReader(br)
+	reader.Comma = options.delimiter()
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		f.Close()
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("json2csv: failed to read existing file header: %w", err)
+	}
+	return &csvFile{File: f, preamble: preamble, header: header, reader: reader}, nil
+}
@@ -0,0 +1,69 @@
+// json2csv/atomic.go
+
+package json2csv
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ConvertToFile converts r like Convert into the file at path. The output
+// is written to a temporary file in the same directory, which replaces path
+// only once the conversion has succeeded, so a failed conversion never
+// leaves a truncated file where a good one used to be. With
+// Options.SyncFiles, the file is synced to stable storage before it is
+// renamed. A replaced file's permissions are kept; new files get 0644.
+func ConvertToFile(path string, r io.Reader, options Options) error {
+	return writeFileAtomic(path, options.SyncFiles, func(f *os.File) error {
+		return Convert(r, f, options)
+	})
+}
+
+// writeFileAtomic calls write with a temporary file next to path and
+// renames it to path if write succeeds, syncing it first if sync is set.
+// The temporary file is removed on failure.
+func writeFileAtomic(path string, sync bool, write func(f *os.File) error) error {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := os.CreateTemp(dir, "."+base+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("json2csv: failed to create temporary file: %w", err)
+	}
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	err = write(f)
+	if err == nil {
+		if err = f.Chmod(mode); err != nil {
+			err = fmt.Errorf("json2csv: failed to set file permissions: %w", err)
+		}
+	}
+	if err == nil && sync {
+		if err = f.Sync(); err != nil {
+			err = fmt.Errorf("json2csv: failed to sync output file: %w", err)
+		}
+	}
+	if err = closeFile(f, err); err == nil {
+		if err = os.Rename(f.Name(), path); err != nil {
+			err = fmt.Errorf("json2csv: failed to replace output file: %w", err)
+		}
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if sync {
+		// Persist the rename; not every platform can sync a directory.
+		if d, err := os.Open(dir); err == nil {
+			d.Sync()
+			d.Close()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,282 @@
+// json2csv/avro/avro.go
+
+// Package avro reads Avro data for json2csv, decoded with
+// github.com/linkedin/goavro/v2: object container files with Records and
+// Convert, and single binary-encoded records, e.g. Kafka messages, with
+// NewDecoder and json2csv.MessageRecords:
+//
+//	decoder, err := avro.NewDecoder(schema)
+//	...
+//	err = json2csv.ConvertRecords(json2csv.MessageRecords(poll, decoder), w, options)
+//
+// Records are converted like JSON objects, so the fields of the options
+// address them as they would the Avro JSON encoding without union
+// wrappers: a union value is the value of its branch, bytes and fixed
+// values are base64 strings as encoding/json writes them, and decimals are
+// json.Number values. Other values keep their goavro types, e.g. int32,
+// float64 or, for timestamps, time.Time.
+//
+// It is a separate package so that programs not reading Avro do not depend
+// on goavro.
+package avro
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/linkedin/goavro/v2"
+
+	"github.com/pradnyoday/go-json2csv/json2csv"
+)
+
+// Convert converts the records of the Avro object container file read from
+// r like json2csv.ConvertRecords.
+func Convert(r io.Reader, w io.Writer, options json2csv.Options) error {
+	records, err := Records(r)
+	if err != nil {
+		return err
+	}
+	return json2csv.ConvertRecords(records, w, options)
+}
+
+// Records reads the header of the Avro object container file in r and
+// returns a RecordSource that decodes its records, one block at a time.
+// The schema of the file must be a record.
+func Records(r io.Reader) (json2csv.RecordSource, error) {
+	ocf, err := goavro.NewOCFReader(bufio.NewReader(r))
+	if err != nil {
+		return nil, fmt.Errorf("json2csv: failed to read avro file: %w", err)
+	}
+	s, err := newSchema(ocf.Codec())
+	if err != nil {
+		return nil, err
+	}
+	index := 0
+	return json2csv.RecordFunc(func() (map[string]interface{}, error) {
+		if !ocf.Scan() {
+			if err := ocf.Err(); err != nil {
+				return nil, fmt.Errorf("json2csv: failed to read avro file: %w", err)
+			}
+			return nil, io.EOF
+		}
+		var record map[string]interface{}
+		datum, err := ocf.Read()
+		if err == nil {
+			record, err = s.record(datum)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("json2csv: failed to decode avro record %d: %w", index, err)
+		}
+		index++
+		return record, nil
+	}), nil
+}
+
+// NewDecoder returns a MessageDecoder for messages holding a single record
+// of schema in the Avro binary encoding, without framing: strip e.g. the
+// schema registry header of Confluent messages first. Its errors are meant
+// to be wrapped by MessageRecords.
+func NewDecoder(schema string) (json2csv.MessageDecoder, error) {
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return nil, fmt.Errorf("json2csv: invalid avro schema: %w", err)
+	}
+	s, err := newSchema(codec)
+	if err != nil {
+		return nil, err
+	}
+	return json2csv.MessageDecoderFunc(func(message []byte) (map[string]interface{}, error) {
+		datum, rest, err := codec.NativeFromBinary(message)
+		if err != nil {
+			return nil, fmt.Errorf("invalid avro record: %w", err)
+		} else if len(rest) > 0 {
+			return nil, fmt.Errorf("%d unexpected bytes after avro record", len(rest))
+		}
+		return s.record(datum)
+	}), nil
+}
+
+// schema is a parsed Avro schema in Parsing Canonical Form, where names
+// are full names and each named type is defined once.
+type schema struct {
+	root  interface{}
+	named map[string]map[string]interface{}
+}
+
+func newSchema(codec *goavro.Codec) (*schema, error) {
+	s := &schema{named: map[string]map[string]interface{}{}}
+	if err := json.Unmarshal([]byte(codec.CanonicalSchema()), &s.root); err != nil {
+		return nil, fmt.Errorf("json2csv: failed to parse avro schema: %w", err)
+	}
+	if typ, _ := s.resolve(s.root); typ["type"] != "record" {
+		return nil, fmt.Errorf("json2csv: avro schema %s is not a record", codec.CanonicalSchema())
+	}
+	return s, nil
+}
+
+// resolve returns the definition of the type node, if it is a complex or
+// named type, registering the named types it defines.
+func (s *schema) resolve(node interface{}) (map[string]interface{}, bool) {
+	switch node := node.(type) {
+	case string:
+		typ, ok := s.named[node]
+		return typ, ok
+	case map[string]interface{}:
+		if name, ok := node["name"].(string); ok {
+			if _, seen := s.named[name]; !seen {
+				s.named[name] = node
+				s.define(node)
+			}
+		}
+		return node, true
+	case []interface{}:
+		for _, member := range node {
+			s.resolve(member)
+		}
+	}
+	return nil, false
+}
+
+// define registers the named types defined within typ, so that they are
+// known wherever they are referenced, even if the data does not reach
+// their definition.
+func (s *schema) define(typ map[string]interface{}) {
+	switch typ["type"] {
+	case "record":
+		fields, _ := typ["fields"].([]interface{})
+		for _, field := range fields {
+			if field, ok := field.(map[string]interface{}); ok {
+				s.resolve(field["type"])
+			}
+		}
+	case "array":
+		s.resolve(typ["items"])
+	case "map":
+		s.resolve(typ["values"])
+	}
+}
+
+// record returns the record for datum, a value of the root schema.
+func (s *schema) record(datum interface{}) (map[string]interface{}, error) {
+	switch v := s.value(s.root, datum).(type) {
+	case map[string]interface{}:
+		return v, nil
+	case nil:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("record decoded as %T", v)
+	}
+}
+
+// value returns the JSON-like equivalent of v, a value of the type node.
+func (s *schema) value(node, v interface{}) interface{} {
+	if union, ok := node.([]interface{}); ok {
+		// goavro wraps non-null values in a map keyed by the branch type.
+		wrapped, ok := v.(map[string]interface{})
+		if !ok || len(wrapped) != 1 {
+			return scalar(v)
+		}
+		for branch, inner := range wrapped {
+			for _, member := range union {
+				if typeName(member) == branch {
+					return s.value(member, inner)
+				}
+			}
+			return scalar(inner) // A logical type, e.g. "long.timestamp-millis"
+		}
+	}
+
+	typ, ok := s.resolve(node)
+	if !ok {
+		return scalar(v)
+	}
+	switch typ["type"] {
+	case "record":
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return v
+		}
+		record := make(map[string]interface{}, len(m))
+		fields, _ := typ["fields"].([]interface{})
+		for _, field := range fields {
+			field, _ := field.(map[string]interface{})
+			name, _ := field["name"].(string)
+			if fv, ok := m[name]; ok {
+				record[name] = s.value(field["type"], fv)
+			}
+		}
+		return record
+	case "array":
+		items, ok := v.([]interface{})
+		if !ok {
+			return v
+		}
+		list := make([]interface{}, len(items))
+		for i, item := range items {
+			list[i] = s.value(typ["items"], item)
+		}
+		return list
+	case "map":
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return v
+		}
+		values := make(map[string]interface{}, len(m))
+		for key, value := range m {
+			values[key] = s.value(typ["values"], value)
+		}
+		return values
+	}
+	return scalar(v) // An enum or fixed
+}
+
+// typeName returns the name goavro gives to values of the union member
+// node: the full name of a named type, otherwise the type itself.
+func typeName(node interface{}) string {
+	switch node := node.(type) {
+	case string:
+		return node
+	case map[string]interface{}:
+		if name, ok := node["name"].(string); ok {
+			return name
+		}
+		typ, _ := node["type"].(string)
+		return typ
+	}
+	return ""
+}
+
+// scalar returns the JSON-like equivalent of the primitive value v.
+func scalar(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	case *big.Rat:
+		return json.Number(v.FloatString(decimalDigits(v)))
+	}
+	return v
+}
+
+// decimalDigits returns the number of fractional digits needed to write r
+// exactly; the denominator of a decimal only has the prime factors 2 and 5.
+func decimalDigits(r *big.Rat) int {
+	denom := new(big.Int).Set(r.Denom())
+	twos, fives := 0, 0
+	two, five, mod := big.NewInt(2), big.NewInt(5), new(big.Int)
+	for denom.Cmp(big.NewInt(1)) > 0 {
+		if mod.Mod(denom, two).Sign() == 0 {
+			denom.Quo(denom, two)
+			twos++
+		} else if mod.Mod(denom, five).Sign() == 0 {
+			denom.Quo(denom, five)
+			fives++
+		} else {
+			return 18 // Not a decimal; round.
+		}
+	}
+	return max(twos, fives)
+}
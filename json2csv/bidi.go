@@ -0,0 +1,87 @@
+// json2csv/bidi.go
+
+package json2csv
+
+import (
+	"strings"
+	"unicode"
+)
+
+// BiDiMode selects how cells containing right-to-left text are written.
+// Spreadsheet tools lay out a row from left to right, and a Hebrew or Arabic
+// cell can visually reorder the punctuation and numbers around it, so values
+// appear scrambled.
+type BiDiMode int
+
+const (
+	// BiDiNone writes cells unchanged (the default).
+	BiDiNone BiDiMode = iota
+
+	// BiDiIsolate wraps every cell containing right-to-left characters in the
+	// Unicode FIRST STRONG ISOLATE (U+2068) and POP DIRECTIONAL ISOLATE
+	// (U+2069) characters, so its direction cannot affect neighbouring text.
+	BiDiIsolate
+
+	// BiDiStrip removes the invisible directional formatting characters
+	// (marks, embeddings, overrides and isolates) from every cell, e.g. for
+	// consumers that compare values byte for byte.
+	BiDiStrip
+)
+
+const (
+	firstStrongIsolate    = '\u2068'
+	popDirectionalIsolate = '\u2069'
+)
+
+// rtlScripts are the scripts written right to left.
+var rtlScripts = []*unicode.RangeTable{
+	unicode.Hebrew, unicode.Arabic, unicode.Syriac, unicode.Thaana,
+	unicode.Nko, unicode.Samaritan, unicode.Mandaic, unicode.Adlam,
+}
+
+// apply returns s formatted according to mode.
+func (mode BiDiMode) apply(s string) string {
+	switch mode {
+	case BiDiIsolate:
+		if containsRTL(s) {
+			return string(firstStrongIsolate) + s + string(popDirectionalIsolate)
+		}
+	case BiDiStrip:
+		if strings.IndexFunc(s, isDirectionalFormat) >= 0 {
+			return strings.Map(func(r rune) rune {
+				if isDirectionalFormat(r) {
+					return -1
+				}
+				return r
+			}, s)
+		}
+	}
+	return s
+}
+
+// containsRTL reports whether s contains a right-to-left letter.
+func containsRTL(s string) bool {
+	for _, r := range s {
+		if r < 0x0590 {
+			continue // Fast path: nothing below Hebrew is right to left.
+		}
+		if unicode.IsOneOf(rtlScripts, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDirectionalFormat reports whether r is an invisible bidirectional
+// formatting character.
+func isDirectionalFormat(r rune) bool {
+	switch {
+	case r == '\u200e', r == '\u200f', r == '\u061c': // LRM, RLM, ALM
+		return true
+	case r >= '\u202a' && r <= '\u202e': // LRE, RLE, PDF, LRO, RLO
+		return true
+	case r >= '\u2066' && r <= '\u2069': // LRI, RLI, FSI, PDI
+		return true
+	}
+	return false
+}
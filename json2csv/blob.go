@@ -0,0 +1,146 @@
+// json2csv/blob.go
+
+package json2csv
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// BlobStore gives access to objects in blob storage such as S3 or GCS, so
+// that ConvertBlobs can read its inputs from and write its output to the
+// store directly. Implementations wrap the storage SDK of choice, which
+// keeps this package free of cloud dependencies.
+type BlobStore interface {
+	// Open returns a reader for the object name.
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+
+	// Create returns a writer that uploads the object name, typically as
+	// a streaming multipart upload, which is completed by Close. If ctx is
+	// canceled before Close, the upload must be aborted instead of being
+	// completed, so that a failed conversion leaves no partial object.
+	Create(ctx context.Context, name string) (io.WriteCloser, error)
+}
+
+// ConvertBlobs converts the objects named inputs in store, in order, into
+// the object output, like ConvertFiles. The output is uploaded as it is
+// produced; if the conversion fails the upload is aborted.
+func ConvertBlobs(ctx context.Context, store BlobStore, inputs []string, output string, options Options) error {
+	uploadCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	w, err := store.Create(uploadCtx, output)
+	if err != nil {
+		return fmt.Errorf("json2csv: failed to create output object %q: %w", output, err)
+	}
+
+	err = convertOpened(inputs, func(name string) (io.ReadCloser, error) {
+		return store.Open(ctx, name)
+	}, w, options)
+	if err != nil {
+		cancel() // Abort the upload.
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("json2csv: failed to upload output object %q: %w", output, err)
+	}
+	return nil
+}
+
+// BlobParts returns a WriterFactory for ConvertSplit that uploads each part
+// to store, named by formatting pattern with the part number. Parts are
+// completed when they are closed, so canceling ctx aborts only the part
+// being written. If the conversion fails, the part being written is
+// aborted as well.
+func BlobParts(ctx context.Context, store BlobStore, pattern string) WriterFactory {
+	return func(part int) (io.WriteCloser, error) {
+		partCtx, cancel := context.WithCancel(ctx)
+		w, err := store.Create(partCtx, fmt.Sprintf(pattern, part))
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		return &blobPart{WriteCloser: w, cancel: cancel}, nil
+	}
+}
+
+// blobPart is a part uploaded by BlobParts, with a context of its own.
+type blobPart struct {
+	io.WriteCloser
+	cancel context.CancelFunc
+}
+
+func (b *blobPart) Close() error {
+	defer b.cancel()
+	return b.WriteCloser.Close()
+}
+
+// abort cancels the upload before closing it, so that it is not completed.
+func (b *blobPart) abort() error {
+	b.cancel()
+	return b.WriteCloser.Close()
+}
+
+// DirBlobStore is a BlobStore backed by a local directory, e.g. for tests
+// or for a mounted bucket. Object names use forward slashes and must stay
+// within the directory. Objects are written to a temporary file that is
+// renamed into place on Close, so readers never see partial output.
+type DirBlobStore string
+
+// Open opens the file for name.
+func (d DirBlobStore) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	path, err := d.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// Create creates a temporary file in the directory of name's file.
+func (d DirBlobStore) Create(ctx context.Context, name string) (io.WriteCloser, error) {
+	path, err := d.path(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".json2csv-*")
+	if err != nil {
+		return nil, err
+	}
+	return &dirBlobWriter{File: f, ctx: ctx, path: path}, nil
+}
+
+func (d DirBlobStore) path(name string) (string, error) {
+	if !filepath.IsLocal(filepath.FromSlash(name)) {
+		return "", &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	return filepath.Join(string(d), filepath.FromSlash(name)), nil
+}
+
+// dirBlobWriter renames its temporary file to path on Close, or removes it
+// if ctx has been canceled.
+type dirBlobWriter struct {
+	*os.File
+	ctx  context.Context
+	path string
+}
+
+func (w *dirBlobWriter) Close() error {
+	err := w.File.Close()
+	if err == nil {
+		err = w.ctx.Err()
+	}
+	if err == nil {
+		err = os.Rename(w.File.Name(), w.path)
+	}
+	if err != nil {
+		os.Remove(w.File.Name())
+	}
+	return err
+}
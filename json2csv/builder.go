@@ -0,0 +1,145 @@
+// json2csv/builder.go
+
+package json2csv
+
+import "slices"
+
+// FieldBuilder builds a Field with chained calls, as an alternative to a
+// struct literal:
+//
+//	json2csv.F("items[*].price").Header("Item Price").Transform(json2csv.RoundTo(2)).Default(0).Required()
+//
+// Each method returns a new FieldBuilder, so a partially configured builder
+// can be reused as a template. A FieldBuilder is passed to Options.Fields
+// with Build, or several at once with Fields.
+type FieldBuilder struct {
+	field   Field
+	onError bool // OnError was called.
+}
+
+// F starts a FieldBuilder for the field at path.
+func F(path string) FieldBuilder {
+	return FieldBuilder{field: Field{JSONPath: path}}
+}
+
+// Const starts a FieldBuilder for a field with the Constant value.
+func Const(value interface{}) FieldBuilder {
+	return FieldBuilder{field: Field{Constant: value}}
+}
+
+// Computed starts a FieldBuilder for a field computed by compute (see
+// Field.Compute).
+func Computed(compute ComputeFunc) FieldBuilder {
+	return FieldBuilder{field: Field{Compute: compute}}
+}
+
+// FromQuery starts a FieldBuilder for a field computed by query (see
+// Field.Query).
+func FromQuery(query Query) FieldBuilder {
+	return FieldBuilder{field: Field{Query: query}}
+}
+
+// Fallback appends FallbackPaths, tried in order when the value is null.
+func (b FieldBuilder) Fallback(paths ...string) FieldBuilder {
+	b.field.FallbackPaths = append(slices.Clip(b.field.FallbackPaths), paths...)
+	return b
+}
+
+// Header sets the CSVHeader.
+func (b FieldBuilder) Header(header string) FieldBuilder {
+	b.field.CSVHeader = header
+	return b
+}
+
+// Transform appends transformers, which run in order.
+func (b FieldBuilder) Transform(transformers ...Transformer) FieldBuilder {
+	b.field.Transformers = append(slices.Clip(b.field.Transformers), transformers...)
+	return b
+}
+
+// ContextTransform sets the ContextTransformer.
+func (b FieldBuilder) ContextTransform(transformer ContextTransformer) FieldBuilder {
+	b.field.ContextTransformer = transformer
+	return b
+}
+
+// Type sets the FieldType.
+func (b FieldBuilder) Type(fieldType FieldType) FieldBuilder {
+	b.field.Type = fieldType
+	return b
+}
+
+// OnError sets the FieldErrorPolicy. It takes precedence over the policy
+// set by Default, whichever is called first.
+func (b FieldBuilder) OnError(policy FieldErrorPolicy) FieldBuilder {
+	b.field.OnError = policy
+	b.onError = true
+	return b
+}
+
+// Default sets the Default value and, unless OnError sets another policy,
+// the FieldErrorUseDefault policy, so that the value replaces errors and,
+// with Required, missing values.
+func (b FieldBuilder) Default(value interface{}) FieldBuilder {
+	b.field.Default = value
+	if !b.onError {
+		b.field.OnError = FieldErrorUseDefault
+	}
+	return b
+}
+
+// Missing sets the MissingValue, used when the path is not present.
+func (b FieldBuilder) Missing(value interface{}) FieldBuilder {
+	b.field.MissingValue = value
+	return b
+}
+
+// KeepWhitespace sets KeepWhitespace, exempting the field from the white
+// space normalization of Options.
+func (b FieldBuilder) KeepWhitespace() FieldBuilder {
+	b.field.KeepWhitespace = true
+	return b
+}
+
+// Locale sets the Locale of the field's numbers.
+func (b FieldBuilder) Locale(locale string) FieldBuilder {
+	b.field.Locale = locale
+	return b
+}
+
+// Required makes null or missing values an error.
+func (b FieldBuilder) Required() FieldBuilder {
+	b.field.Required = true
+	return b
+}
+
+// Expand sets the KeyExpansion of a path ending in ".*".
+func (b FieldBuilder) Expand(expansion KeyExpansion) FieldBuilder {
+	b.field.Expand = &expansion
+	return b
+}
+
+// Pivot spreads the items of the array over maxElements columns (see
+// ArrayPivot).
+func (b FieldBuilder) Pivot(maxElements int) FieldBuilder {
+	b.field.ArrayMode = ArrayPivot
+	b.field.MaxElements = maxElements
+	return b
+}
+
+// Build returns the Field.
+func (b FieldBuilder) Build() Field {
+	field := b.field
+	field.Transformers = slices.Clone(field.Transformers)
+	field.FallbackPaths = slices.Clone(field.FallbackPaths)
+	return field
+}
+
+// Fields returns the Fields of builders, in order.
+func Fields(builders ...FieldBuilder) []Field {
+	fields := make([]Field, len(builders))
+	for i, b := range builders {
+		fields[i] = b.Build()
+	}
+	return fields
+}
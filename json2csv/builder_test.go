@@ -0,0 +1,34 @@
+// json2csv/builder_test.go
+
+package json2csv_test
+
+import (
+	"testing"
+
+	"github.com/pradnyoday/go-json2csv/json2csv"
+)
+
+func TestFieldBuilderDefaultAndOnError(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder json2csv.FieldBuilder
+		want    json2csv.FieldErrorPolicy
+	}{
+		{"default", json2csv.F("a").Default(0), json2csv.FieldErrorUseDefault},
+		{"on error", json2csv.F("a").OnError(json2csv.FieldErrorSkipRow), json2csv.FieldErrorSkipRow},
+		{"on error then default", json2csv.F("a").OnError(json2csv.FieldErrorSkipRow).Default(0), json2csv.FieldErrorSkipRow},
+		{"default then on error", json2csv.F("a").Default(0).OnError(json2csv.FieldErrorEmptyCell), json2csv.FieldErrorEmptyCell},
+		{"explicit propagate", json2csv.F("a").OnError(json2csv.FieldErrorPropagate).Default(0), json2csv.FieldErrorPropagate},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			field := test.builder.Build()
+			if field.OnError != test.want {
+				t.Errorf("OnError = %v, want %v", field.OnError, test.want)
+			}
+			if test.name != "on error" && field.Default != 0 {
+				t.Errorf("Default = %v, want 0", field.Default)
+			}
+		})
+	}
+}
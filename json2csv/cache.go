@@ -0,0 +1,154 @@
+// json2csv/cache.go
+
+package json2csv
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// ConfigHash returns a hex SHA-256 digest identifying config. Configs that
+// encode to the same JSON have the same hash.
+func ConfigHash(config Config) (string, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("json2csv: failed to encode config: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ConverterCache holds Converters built from Configs, keyed by ConfigHash, so
+// a service handling many requests with a handful of configs parses and
+// validates each config only once. It is safe for concurrent use; concurrent
+// requests for the same new config build its Converter only once.
+type ConverterCache struct {
+	mu      sync.Mutex
+	entries *lru[string, *cacheEntry]
+}
+
+type cacheEntry struct {
+	hash      string
+	once      sync.Once
+	converter *Converter
+	err       error
+}
+
+// NewConverterCache returns a cache holding at most maxEntries Converters,
+// evicting the least recently used one when full. Zero means no limit.
+func NewConverterCache(maxEntries int) *ConverterCache {
+	return &ConverterCache{entries: newLRU[string, *cacheEntry](maxEntries)}
+}
+
+// Get returns the Converter for config, building it on first use. Configs
+// that fail to build are not cached.
+func (cc *ConverterCache) Get(config Config) (*Converter, error) {
+	hash, err := ConfigHash(config)
+	if err != nil {
+		return nil, err
+	}
+
+	cc.mu.Lock()
+	entry, ok := cc.entries.get(hash)
+	if !ok {
+		entry = &cacheEntry{hash: hash}
+		cc.entries.add(hash, entry)
+	}
+	cc.mu.Unlock()
+
+	entry.once.Do(func() {
+		var options Options
+		if options, entry.err = config.Options(); entry.err == nil {
+			entry.converter, entry.err = NewConverter(options)
+		}
+	})
+	if entry.err != nil {
+		cc.remove(entry)
+	}
+	return entry.converter, entry.err
+}
+
+// Len returns the number of cached Converters.
+func (cc *ConverterCache) Len() int {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return cc.entries.len()
+}
+
+// remove drops entry unless it was already evicted or replaced.
+func (cc *ConverterCache) remove(entry *cacheEntry) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cached, ok := cc.entries.peek(entry.hash); ok && cached == entry {
+		cc.entries.remove(entry.hash)
+	}
+}
+
+// lru is a map holding at most max entries, evicting the least recently
+// used one when full. Zero means no limit. It is not safe for concurrent
+// use.
+type lru[K comparable, V any] struct {
+	max     int
+	entries map[K]*list.Element // of *lruEntry[K, V]
+	order   list.List           // most recently used first
+}
+
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+func newLRU[K comparable, V any](max int) *lru[K, V] {
+	return &lru[K, V]{max: max, entries: map[K]*list.Element{}}
+}
+
+// get returns the value of key, marking it as recently used.
+func (c *lru[K, V]) get(key K) (V, bool) {
+	element, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*lruEntry[K, V]).value, true
+}
+
+// peek returns the value of key without marking it as used.
+func (c *lru[K, V]) peek(key K) (V, bool) {
+	element, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	return element.Value.(*lruEntry[K, V]).value, true
+}
+
+// add sets the value of key, evicting the least recently used entry if the
+// map is full.
+func (c *lru[K, V]) add(key K, value V) {
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*lruEntry[K, V]).value = value
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry[K, V]{key: key, value: value})
+	if c.max > 0 && c.order.Len() > c.max {
+		c.remove(c.order.Back().Value.(*lruEntry[K, V]).key)
+	}
+}
+
+// remove drops key, if present.
+func (c *lru[K, V]) remove(key K) {
+	if element, ok := c.entries[key]; ok {
+		c.order.Remove(element)
+		delete(c.entries, key)
+	}
+}
+
+func (c *lru[K, V]) len() int {
+	return c.order.Len()
+}
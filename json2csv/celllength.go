@@ -0,0 +1,61 @@
+// json2csv/celllength.go
+
+package json2csv
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"unicode/utf8"
+)
+
+// LongCellPolicy selects how a conversion handles a cell longer than
+// Options.MaxCellLength.
+type LongCellPolicy int
+
+const (
+	// LongCellsTruncate cuts the cell to MaxCellLength bytes, without
+	// splitting a UTF-8 character (the default).
+	LongCellsTruncate LongCellPolicy = iota
+
+	// LongCellsError fails the conversion.
+	LongCellsError
+
+	// LongCellsHash replaces the cell with "sha256:" and the hex SHA-256
+	// digest of its text, so the row can still be matched with the full
+	// value kept elsewhere. MaxCellLength must be at least
+	// len(LongCellHashPrefix)+64.
+	LongCellsHash
+)
+
+// LongCellHashPrefix starts the cells replaced under LongCellsHash.
+const LongCellHashPrefix = "sha256:"
+
+// limitCell applies Options.MaxCellLength to the cell of field i,
+// counting the truncated and hashed cells in the report and statistics.
+func (c *conversion) limitCell(i int, cell string) (string, error) {
+	max := c.options.MaxCellLength
+	if max <= 0 || len(cell) <= max {
+		return cell, nil
+	}
+	switch c.options.LongCells {
+	case LongCellsError:
+		return "", fmt.Errorf("cell of %d bytes exceeds MaxCellLength %d", len(cell), max)
+	case LongCellsHash:
+		sum := sha256.Sum256([]byte(cell))
+		cell = LongCellHashPrefix + hex.EncodeToString(sum[:])
+	default:
+		n := max
+		for n > 0 && !utf8.RuneStart(cell[n]) {
+			n--
+		}
+		cell = cell[:n]
+	}
+	if c.report != nil {
+		c.report.Fields[i].Truncated++
+	}
+	if c.stats != nil {
+		c.stats.columns[i].truncated++
+	}
+	return cell, nil
+}
@@ -0,0 +1,228 @@
+// json2csv/checkpoint.go
+
+package json2csv
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Checkpoint is the progress of a ConvertResumable conversion. Its
+// records are converted and its rows are in the output file.
+type Checkpoint struct {
+	// Records is the number of records converted, across sources.
+	Records int `json:"records"`
+
+	// RecordNumber is the value of the "$recordNumber" pseudo-path of the
+	// last record, which continues from it on resume. It can exceed
+	// Records if records failed and were skipped.
+	RecordNumber int `json:"record_number"`
+
+	// SourceIndex is the index of the source being converted and
+	// SourceRecords the number of its records converted. Source is its
+	// Name and Offset the approximate byte offset of its last converted
+	// record, or -1 if unknown, for information.
+	SourceIndex   int    `json:"source_index"`
+	SourceRecords int    `json:"source_records"`
+	Source        string `json:"source,omitempty"`
+	Offset        int64  `json:"offset"`
+
+	// Rows is the number of data rows written and OutputBytes the size of
+	// the output file after them.
+	Rows        int   `json:"rows"`
+	OutputBytes int64 `json:"output_bytes"`
+
+	// Done is set once the conversion has completed.
+	Done bool `json:"done,omitempty"`
+}
+
+// Checkpointer stores the Checkpoint of a ConvertResumable conversion.
+type Checkpointer interface {
+	// Load returns the last saved checkpoint, or nil if there is none.
+	Load() (*Checkpoint, error)
+
+	// Save stores checkpoint, replacing the previous one.
+	Save(checkpoint Checkpoint) error
+}
+
+// FileCheckpointer is a Checkpointer storing the checkpoint as JSON in the
+// file at the path, e.g. "export.csv.checkpoint". The file is replaced
+// atomically, so a crash while saving leaves the previous checkpoint.
+type FileCheckpointer string
+
+// Load reads the checkpoint file, if it exists.
+func (path FileCheckpointer) Load() (*Checkpoint, error) {
+	data, err := os.ReadFile(string(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("json2csv: failed to read checkpoint: %w", err)
+	}
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("json2csv: invalid checkpoint file %q: %w", string(path), err)
+	}
+	return &checkpoint, nil
+}
+
+// Save writes checkpoint to a temporary file and renames it to the path.
+func (path FileCheckpointer) Save(checkpoint Checkpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return fmt.Errorf("json2csv: failed to encode checkpoint: %w", err)
+	}
+	return writeFileAtomic(string(path), true, func(f *os.File) error {
+		if _, err := f.Write(append(data, '\n')); err != nil {
+			return fmt.Errorf("json2csv: failed to write checkpoint: %w", err)
+		}
+		return nil
+	})
+}
+
+// ResumeOptions configures ConvertResumable.
+type ResumeOptions struct {
+	// Checkpointer stores the progress. It is required.
+	Checkpointer Checkpointer
+
+	// Every is the number of records converted between checkpoints.
+	// Defaults to DefaultCheckpointEvery.
+	Every int
+
+	// Sync syncs the output file to stable storage before each checkpoint,
+	// so that the checkpoint survives a power loss, not only a crash of the
+	// process, at the cost of throughput.
+	Sync bool
+}
+
+// DefaultCheckpointEvery is the default of ResumeOptions.Every.
+const DefaultCheckpointEvery = 10000
+
+// ConvertResumable converts sources into the CSV file at path like
+// ConvertSources, saving a Checkpoint every few records, for multi-hour
+// exports that must survive a crash. Run again with the same sources,
+// options and Checkpointer after a failure, it truncates the file to the
+// rows of the last checkpoint, skips the sources and records converted
+// before it and appends the remaining rows without a header. Sources
+// before the checkpoint's are not read; the records before it in its
+// source are decoded but not converted, and the "$recordNumber" and
+// "$rowNumber" pseudo-paths continue from the checkpoint. Once the
+// conversion has completed the checkpoint is marked Done, and later calls
+// return without reading the sources; remove the checkpoint to convert
+// them again.
+//
+// Only FormatCSV is supported; WrapOutput, GroupBy, SortBy, Offset, Limit,
+// sampling and key expansions without Keys cannot be used. StatsWriter and
+// SchemaWriter only cover the records converted by the last call.
+func ConvertResumable(path string, sources []Source, options Options, resume ResumeOptions) error {
+	if resume.Checkpointer == nil {
+		return errors.New("json2csv: ConvertResumable requires a Checkpointer")
+	}
+	if options.Format != FormatCSV || options.WrapOutput != nil {
+		return errors.New("json2csv: resumable conversions require FormatCSV and no WrapOutput")
+	}
+	if options.GroupBy != nil || len(options.SortBy) > 0 || options.Offset > 0 || options.Limit > 0 || options.SampleEvery > 1 || options.SampleRate > 0 {
+		return errors.New("json2csv: resumable conversions cannot be combined with GroupBy, SortBy, Offset, Limit or sampling")
+	}
+	every := resume.Every
+	if every <= 0 {
+		every = DefaultCheckpointEvery
+	}
+
+	checkpoint, err := resume.Checkpointer.Load()
+	if err != nil {
+		return err
+	}
+	if checkpoint == nil {
+		checkpoint = &Checkpoint{Offset: -1}
+	}
+	if checkpoint.Done {
+		return nil
+	}
+
+	var f *os.File
+	if checkpoint.OutputBytes == 0 {
+		f, err = os.Create(path)
+		if err != nil {
+			return fmt.Errorf("json2csv: failed to create output file: %w", err)
+		}
+	} else {
+		f, err = os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return fmt.Errorf("json2csv: failed to open output file: %w", err)
+		}
+		if info, err := f.Stat(); err != nil || info.Size() < checkpoint.OutputBytes {
+			f.Close()
+			return fmt.Errorf("json2csv: output file %q is shorter than its checkpoint", path)
+		}
+		if err := f.Truncate(checkpoint.OutputBytes); err != nil {
+			f.Close()
+			return fmt.Errorf("json2csv: failed to truncate output file: %w", err)
+		}
+		if _, err := f.Seek(checkpoint.OutputBytes, io.SeekStart); err != nil {
+			f.Close()
+			return fmt.Errorf("json2csv: failed to seek output file: %w", err)
+		}
+		options.OmitHeader = true // Written before the checkpoint.
+	}
+
+	c, err := newConversion(NewRowWriter(f, options), options)
+	if err != nil {
+		return closeFile(f, err)
+	}
+	if c.discoverKeys {
+		return closeFile(f, errors.New("json2csv: resumable conversions require the Keys of key expansions"))
+	}
+	c.rowsWritten = checkpoint.Rows
+	c.recordNumber = checkpoint.RecordNumber
+	progress := *checkpoint
+	save := func(done bool) error {
+		if err := c.out.Flush(); err != nil {
+			return fmt.Errorf("json2csv: failed to flush output: %w", err)
+		}
+		if resume.Sync {
+			if err := f.Sync(); err != nil {
+				return fmt.Errorf("json2csv: failed to sync output file: %w", err)
+			}
+		}
+		info, err := f.Stat()
+		if err != nil {
+			return fmt.Errorf("json2csv: failed to stat output file: %w", err)
+		}
+		progress.Rows, progress.OutputBytes, progress.Done = c.rowsWritten, info.Size(), done
+		progress.RecordNumber = c.recordNumber
+		return resume.Checkpointer.Save(progress)
+	}
+	c.onRecord = func() error {
+		progress.Records++
+		progress.SourceRecords = c.recordIndex
+		progress.Offset = c.recordOffset
+		if progress.Records%every != 0 {
+			return nil
+		}
+		return save(false)
+	}
+
+	err = c.run(func() error {
+		for i := checkpoint.SourceIndex; i < len(sources); i++ {
+			c.skipRecords = 0
+			if i == checkpoint.SourceIndex {
+				c.skipRecords = checkpoint.SourceRecords
+			}
+			if i != progress.SourceIndex {
+				progress.SourceIndex, progress.SourceRecords, progress.Offset = i, 0, -1
+			}
+			progress.Source = sources[i].Name
+			if err := c.convertSource(sources[i]); err != nil && !c.skipSource(sources[i].Name, err) {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		err = save(true)
+	}
+	return closeFile(f, err)
+}
@@ -0,0 +1,129 @@
+// json2csv/checkpoint_test.go
+
+package json2csv_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/pradnyoday/go-json2csv/json2csv"
+)
+
+// memCheckpointer is a Checkpointer keeping the checkpoint in memory.
+type memCheckpointer struct {
+	checkpoint *json2csv.Checkpoint
+	saves      int
+}
+
+func (m *memCheckpointer) Load() (*json2csv.Checkpoint, error) {
+	if m.checkpoint == nil {
+		return nil, nil
+	}
+	checkpoint := *m.checkpoint
+	return &checkpoint, nil
+}
+
+func (m *memCheckpointer) Save(checkpoint json2csv.Checkpoint) error {
+	m.checkpoint = &checkpoint
+	m.saves++
+	return nil
+}
+
+var errCrash = errors.New("crash")
+
+// crashingReader reads r up to n bytes, then fails with errCrash.
+func crashingReader(r string, n int) io.Reader {
+	return io.MultiReader(strings.NewReader(r[:n]), iotest.ErrReader(errCrash))
+}
+
+func TestConvertResumable(t *testing.T) {
+	options := json2csv.Options{
+		Fields: []json2csv.Field{
+			{JSONPath: "id", CSVHeader: "id"},
+			{JSONPath: "items[*].n", CSVHeader: "n"},
+			{JSONPath: json2csv.PathRecordNumber, CSVHeader: "record"},
+			{JSONPath: json2csv.PathRowNumber, CSVHeader: "row"},
+		},
+	}
+	var sources [2]string
+	for s := range sources {
+		var b strings.Builder
+		for i := 0; i < 6; i++ {
+			id := s*10 + i
+			fmt.Fprintf(&b, `{"id":%d,"items":[{"n":"%d.a"},{"n":"%d.b"}]}`+"\n", id, id, id)
+		}
+		sources[s] = b.String()
+	}
+	newSources := func(crashSource, crashAt int) []json2csv.Source {
+		result := make([]json2csv.Source, len(sources))
+		for i, data := range sources {
+			result[i] = json2csv.Source{Name: fmt.Sprint("source", i), Reader: strings.NewReader(data)}
+			if i == crashSource {
+				result[i].Reader = crashingReader(data, crashAt)
+			}
+		}
+		return result
+	}
+
+	var want strings.Builder
+	if err := json2csv.ConvertSources(newSources(-1, 0), &want, options); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		every       int
+		crashSource int
+		crashAt     int // Bytes of the source read before the crash
+	}{
+		{"first record", 2, 0, 10},
+		{"mid source", 2, 0, len(sources[0]) / 2},
+		{"between checkpoints", 4, 0, len(sources[0]) - 5},
+		{"second source", 3, 1, len(sources[1]) / 2},
+		{"every record", 1, 1, len(sources[1]) - 5},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "out.csv")
+			checkpointer := &memCheckpointer{}
+			resume := json2csv.ResumeOptions{Checkpointer: checkpointer, Every: test.every}
+
+			err := json2csv.ConvertResumable(path, newSources(test.crashSource, test.crashAt), options, resume)
+			if !errors.Is(err, errCrash) {
+				t.Fatalf("first run: got %v, want the crash", err)
+			}
+			if checkpointer.checkpoint != nil && checkpointer.checkpoint.Done {
+				t.Fatal("the checkpoint of the crashed run is Done")
+			}
+
+			if err := json2csv.ConvertResumable(path, newSources(-1, 0), options, resume); err != nil {
+				t.Fatalf("resumed run: %v", err)
+			}
+			if !checkpointer.checkpoint.Done {
+				t.Error("the checkpoint of the completed run is not Done")
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != want.String() {
+				t.Errorf("resumed output:\n%s\nwant\n%s", got, want.String())
+			}
+
+			// A completed conversion is not run again: its sources would crash.
+			saves := checkpointer.saves
+			if err := json2csv.ConvertResumable(path, newSources(0, 0), options, resume); err != nil {
+				t.Fatalf("completed run: %v", err)
+			}
+			if checkpointer.saves != saves {
+				t.Error("the completed conversion ran again")
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+// json2csv/columns.go
+
+package json2csv
+
+import (
+	"fmt"
+	"slices"
+)
+
+// selectFields returns options with the Fields chosen by SelectHeaders and
+// DropHeaders, ordered by ColumnOrder. Headers not naming a field's
+// CSVHeader are an error, so a typo does not silently change an export.
+func (options Options) selectFields() (Options, error) {
+	if options.SelectHeaders == nil && len(options.DropHeaders) == 0 && len(options.ColumnOrder) == 0 {
+		return options, nil
+	}
+	headers := make(map[string]bool, len(options.Fields))
+	for _, field := range options.Fields {
+		headers[field.CSVHeader] = true
+	}
+	for _, list := range [][]string{options.SelectHeaders, options.DropHeaders, options.ColumnOrder} {
+		for _, header := range list {
+			if !headers[header] {
+				return options, fmt.Errorf("json2csv: no field has the CSVHeader %q", header)
+			}
+		}
+	}
+
+	var fields []Field
+	for _, field := range options.Fields {
+		if options.SelectHeaders != nil && !slices.Contains(options.SelectHeaders, field.CSVHeader) {
+			continue
+		}
+		if slices.Contains(options.DropHeaders, field.CSVHeader) {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	rank := func(field Field) int {
+		if i := slices.Index(options.ColumnOrder, field.CSVHeader); i >= 0 {
+			return i
+		}
+		return len(options.ColumnOrder)
+	}
+	slices.SortStableFunc(fields, func(a, b Field) int { return rank(a) - rank(b) })
+	options.Fields = fields
+	options.SelectHeaders, options.DropHeaders, options.ColumnOrder = nil, nil, nil
+	return options, nil
+}
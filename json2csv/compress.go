@@ -0,0 +1,100 @@
+// json2csv/compress.go
+
+package json2csv
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CellCodec compresses the contents of a single cell for CompressCell. The
+// compressed bytes are base64 encoded and prefixed with Marker, so consumers
+// can tell compressed cells apart and restore them with DecompressCell.
+type CellCodec struct {
+	// Marker prefixes every compressed cell, e.g. "gz64:".
+	Marker string
+
+	Compress   func(p []byte) ([]byte, error)
+	Decompress func(p []byte) ([]byte, error)
+}
+
+// GzipCodec compresses cells with gzip, marked "gz64:". The json2csv/compress
+// package provides a zstd codec.
+var GzipCodec = CellCodec{
+	Marker: "gz64:",
+	Compress: func(p []byte) ([]byte, error) {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(p); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	},
+	Decompress: func(p []byte) ([]byte, error) {
+		zr, err := gzip.NewReader(bytes.NewReader(p))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		return io.ReadAll(zr)
+	},
+}
+
+// CompressCell returns a Transformer that compresses values of at least
+// minBytes bytes (in their CSV string form) with codec, for huge text
+// columns such as stack traces or HTML bodies. The result is the codec's
+// Marker followed by the base64 encoded compressed data. Shorter values, and
+// values that would not get smaller, are returned unchanged; nil stays nil.
+//
+// A value that happens to start with the marker is always compressed, so
+// DecompressCell never misinterprets an uncompressed cell.
+func CompressCell(codec CellCodec, minBytes int) Transformer {
+	return func(value interface{}, originalRecord map[string]interface{}) (interface{}, error) {
+		s, ok := stringValue(value)
+		if !ok {
+			return nil, nil
+		}
+		marked := strings.HasPrefix(s, codec.Marker)
+		if len(s) < minBytes && !marked {
+			return value, nil
+		}
+
+		compressed, err := codec.Compress([]byte(s))
+		if err != nil {
+			return nil, fmt.Errorf("json2csv: CompressCell: %w", err)
+		}
+		encoded := codec.Marker + base64.StdEncoding.EncodeToString(compressed)
+		if len(encoded) >= len(s) && !marked {
+			return value, nil
+		}
+		return encoded, nil
+	}
+}
+
+// DecompressCell restores a cell written by CompressCell with one of codecs.
+// Cells without a known marker are returned unchanged.
+func DecompressCell(cell string, codecs ...CellCodec) (string, error) {
+	for _, codec := range codecs {
+		encoded, ok := strings.CutPrefix(cell, codec.Marker)
+		if !ok {
+			continue
+		}
+		compressed, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return "", fmt.Errorf("json2csv: DecompressCell: %w", err)
+		}
+		p, err := codec.Decompress(compressed)
+		if err != nil {
+			return "", fmt.Errorf("json2csv: DecompressCell: %w", err)
+		}
+		return string(p), nil
+	}
+	return cell, nil
+}
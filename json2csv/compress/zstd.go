@@ -0,0 +1,33 @@
+// json2csv/compress/zstd.go
+
+// Package compress provides json2csv.CellCodec implementations that need
+// third-party libraries:
+//
+//	{JSONPath: "events[*].stack", CSVHeader: "Stack",
+//		Transformer: json2csv.CompressCell(compress.Zstd, 4096)}
+package compress
+
+import (
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/pradnyoday/go-json2csv/json2csv"
+)
+
+// Zstd compresses cells with Zstandard, marked "zstd64:". It usually
+// compresses text better and faster than json2csv.GzipCodec.
+var Zstd = json2csv.CellCodec{
+	Marker: "zstd64:",
+	Compress: func(p []byte) ([]byte, error) {
+		return encoder.EncodeAll(p, nil), nil
+	},
+	Decompress: func(p []byte) ([]byte, error) {
+		return decoder.DecodeAll(p, nil)
+	},
+}
+
+// The encoder and decoder are safe for concurrent EncodeAll and DecodeAll
+// calls and are shared by all conversions.
+var (
+	encoder, _ = zstd.NewWriter(nil)
+	decoder, _ = zstd.NewReader(nil)
+)
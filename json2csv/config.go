@@ -0,0 +1,202 @@
+// json2csv/config.go
+
+package json2csv
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf8"
+)
+
+// Config is a serializable form of Options, for mappings kept in files or
+// sent to a conversion service. Transformers are referred to by their
+// registered name (see RegisterTransformer). For example:
+//
+//	{
+//	  "fields": [
+//	    {"path": "user_id", "header": "User ID"},
+//	    {"path": "items[*].price", "header": "Price", "transformers": [{"name": "RoundTo", "args": [2]}]},
+//	    {"path": "items[*].tags", "header": "Tags", "transformers": [{"name": "JoinArray", "args": [";"]}]},
+//	    {"path": "user_name", "header": "Name", "transformers": ["Trim", "TitleCase"]}
+//	  ],
+//	  "delimiter": ";"
+//	}
+type Config struct {
+	Fields []FieldConfig `json:"fields"`
+
+	// Delimiter is a single character. Defaults to ",".
+	Delimiter string `json:"delimiter,omitempty"`
+
+	// Header enables the header row. Defaults to true.
+	Header *bool `json:"header,omitempty"`
+
+	// Format is "csv" (the default), "markdown" or "html".
+	Format string `json:"format,omitempty"`
+
+	// Lossless selects the type-preserving CSV profile.
+	Lossless bool `json:"lossless,omitempty"`
+
+	// StrictTypes disables the coercion of values to the field types.
+	StrictTypes bool `json:"strict_types,omitempty"`
+
+	// PreserveNumberStrings writes the numbers of the input unchanged.
+	PreserveNumberStrings bool `json:"preserve_number_strings,omitempty"`
+
+	// TrimSpace and CollapseWhitespace normalize the white space of all
+	// string cells.
+	TrimSpace          bool `json:"trim_space,omitempty"`
+	CollapseWhitespace bool `json:"collapse_whitespace,omitempty"`
+
+	// Locale is a language tag such as "de" for the numbers of all fields.
+	Locale string `json:"locale,omitempty"`
+}
+
+// FieldConfig is the serializable form of a Field.
+type FieldConfig struct {
+	Path         string              `json:"path"`
+	Header       string              `json:"header"`
+	Transformers []TransformerConfig `json:"transformers,omitempty"`
+
+	// Type is a FieldType name such as "int" or "time".
+	Type string `json:"type,omitempty"`
+
+	// OnError is a FieldErrorPolicy name such as "empty" or "skip_row".
+	OnError string `json:"on_error,omitempty"`
+
+	// Default is the value written under the "default" OnError policy.
+	Default interface{} `json:"default,omitempty"`
+
+	// Missing is the value used when Path is not present, as opposed to
+	// present with a null value.
+	Missing interface{} `json:"missing,omitempty"`
+
+	// KeepWhitespace exempts the field from the white space normalization
+	// of the config.
+	KeepWhitespace bool `json:"keep_whitespace,omitempty"`
+
+	// Required makes a null or missing value an error.
+	Required bool `json:"required,omitempty"`
+
+	// Locale overrides the Locale of the config for this field; "-"
+	// disables it.
+	Locale string `json:"locale,omitempty"`
+
+	// Fallbacks are paths tried in order when the value at Path is null.
+	Fallbacks []string `json:"fallbacks,omitempty"`
+
+	// Constant is the value of the field in every row, instead of the
+	// value at Path.
+	Constant interface{} `json:"constant,omitempty"`
+}
+
+// TransformerConfig refers to a registered transformer. In JSON it is either
+// an object {"name": "RoundTo", "args": [2]} or just the name "Trim".
+type TransformerConfig struct {
+	Name string `json:"name"`
+	Args Args   `json:"args,omitempty"`
+}
+
+// UnmarshalJSON accepts a transformer name as well as the object form.
+// Numeric arguments are decoded as json.Number.
+func (tc *TransformerConfig) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*tc = TransformerConfig{Name: name}
+		return nil
+	}
+	type plain TransformerConfig // Avoids recursing into UnmarshalJSON.
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	decoder.DisallowUnknownFields()
+	return decoder.Decode((*plain)(tc))
+}
+
+func (tc TransformerConfig) build() (Transformer, error) {
+	return LookupTransformer(tc.Name, tc.Args)
+}
+
+// ParseConfig reads a Config from JSON. Unknown keys are rejected so that
+// typos do not silently change the output.
+func ParseConfig(r io.Reader) (Config, error) {
+	var config Config
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return Config{}, fmt.Errorf("json2csv: invalid config: %w", err)
+	}
+	return config, nil
+}
+
+// LoadConfig reads a Config from the JSON file filename.
+func LoadConfig(filename string) (Config, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return Config{}, fmt.Errorf("json2csv: failed to open config file: %w", err)
+	}
+	defer f.Close()
+	return ParseConfig(f)
+}
+
+// Options builds the Options described by the config, instantiating its
+// transformers from the registry.
+func (config Config) Options() (Options, error) {
+	options := Options{
+		Delimiter:   DefaultDelimiter,
+		OmitHeader:  config.Header != nil && !*config.Header,
+		Lossless:    config.Lossless,
+		StrictTypes: config.StrictTypes,
+		Locale:      config.Locale,
+
+		PreserveNumberStrings: config.PreserveNumberStrings,
+		TrimSpace:             config.TrimSpace,
+		CollapseWhitespace:    config.CollapseWhitespace,
+	}
+
+	if config.Delimiter != "" {
+		r, size := utf8.DecodeRuneInString(config.Delimiter)
+		if size != len(config.Delimiter) || r == utf8.RuneError {
+			return Options{}, fmt.Errorf("json2csv: config delimiter must be a single character, got %q", config.Delimiter)
+		}
+		options.Delimiter = r
+	}
+
+	switch config.Format {
+	case "", "csv":
+		options.Format = FormatCSV
+	case "markdown":
+		options.Format = FormatMarkdown
+	case "html":
+		options.Format = FormatHTML
+	default:
+		return Options{}, fmt.Errorf("json2csv: unknown config format %q", config.Format)
+	}
+
+	if len(config.Fields) == 0 {
+		return Options{}, errors.New("json2csv: config has no fields")
+	}
+	for i, fc := range config.Fields {
+		fieldType, err := ParseFieldType(fc.Type)
+		if err != nil {
+			return Options{}, fmt.Errorf("json2csv: config field %d (%q): %w", i+1, fc.Path, err)
+		}
+		onError, err := ParseFieldErrorPolicy(fc.OnError)
+		if err != nil {
+			return Options{}, fmt.Errorf("json2csv: config field %d (%q): %w", i+1, fc.Path, err)
+		}
+		field := Field{JSONPath: fc.Path, FallbackPaths: fc.Fallbacks, CSVHeader: fc.Header, Type: fieldType, OnError: onError, Default: fc.Default, MissingValue: fc.Missing, KeepWhitespace: fc.KeepWhitespace, Required: fc.Required, Locale: fc.Locale, Constant: fc.Constant}
+		for _, tc := range fc.Transformers {
+			t, err := tc.build()
+			if err != nil {
+				return Options{}, fmt.Errorf("json2csv: config field %d (%q): %w", i+1, fc.Path, err)
+			}
+			field.Transformers = append(field.Transformers, t)
+		}
+		options.Fields = append(options.Fields, field)
+	}
+	return options, nil
+}
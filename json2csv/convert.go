@@ -1,193 +1,957 @@
 // json2csv/convert.go
+
 package json2csv
 
 import (
-	"encoding/csv"
+	"bufio"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"strings"
+	"time"
+
+	"golang.org/x/text/message"
 )
 
 // Convert reads JSON objects from r, converts them to CSV rows based on options.
 // It requires at least one Field's JSONPath to contain "[*]" to trigger flattening.
 // Extreme streaming and memory optimization are maintained.
 func Convert(r io.Reader, w io.Writer, options Options) error {
-	// Set default delimiter if not provided
-	if options.Delimiter == ',' {
-		options.Delimiter = DefaultDelimiter
+	return ConvertSources([]Source{{Reader: r}}, w, options)
+}
+
+// ConvertSources converts each source in order into a single output with one
+// header row. Each source must hold a JSON array of objects or a stream of
+// objects such as NDJSON (one object per line); empty sources are skipped.
+// The source Name is available to fields through the "$sourceFile"
+// pseudo-path. Sources that fail are handled according to
+// Options.ErrorPolicy.
+func ConvertSources(sources []Source, w io.Writer, options Options) error {
+	return withOutput(w, options, func(w io.Writer) error {
+		return convertSourcesTo(sources, NewRowWriter(w, options), options, nil)
+	})
+}
+
+// ConvertTo is like Convert but writes the rows to rw instead of formatting
+// them with Options.Format (Options.WrapOutput does not apply). rw is closed
+// after the last row of a successful conversion; on error it is only flushed.
+func ConvertTo(r io.Reader, rw RowWriter, options Options) error {
+	return convertSourcesTo([]Source{{Reader: r}}, rw, options, nil)
+}
+
+// ConvertSourcesTo is like ConvertSources but writes the rows to rw, as
+// ConvertTo does.
+func ConvertSourcesTo(sources []Source, rw RowWriter, options Options) error {
+	return convertSourcesTo(sources, rw, options, nil)
+}
+
+// convertSourcesTo converts sources to rw, filling in report if not nil.
+func convertSourcesTo(sources []Source, rw RowWriter, options Options, report *Report) error {
+	c, err := newConversion(rw, options)
+	if err != nil {
+		return err
+	}
+	c.report = report
+	sources, cleanup, err := c.prepareSources(sources)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	return c.run(func() error {
+		for _, source := range sources {
+			if err := c.convertSource(source); err != nil && !c.skipSource(source.Name, err) {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// conversion holds the state of a single conversion run across its sources.
+type conversion struct {
+	options          Options
+	out              RowWriter
+	fields           []Field            // Options.Fields with key expansions applied
+	plans            []fieldPlan        // The compiled paths of fields
+	row              []string           // Reused for every row; see RowWriter.WriteRow
+	transformContext TransformContext   // Reused for every row
+	printers         []*message.Printer // Number formatting of each field; nil entries for none
+	header           []string
+	newlines         *strings.Replacer // nil for NewlinesKeep
+	flattenArrayPath string
+	flattenPath      *compiledPath
+	filters          []valueFilter
+	stats            *statsCollector  // nil unless Options.StatsWriter is set
+	schema           *schemaCollector // nil unless Options.SchemaWriter is set
+	report           *Report          // nil unless requested
+	metrics          Metrics          // Options.Metrics, or NopMetrics
+	rowsWritten      int
+	headerPending    bool // The header waits for the first row; see Options.HeaderOnEmpty.
+	outputFailed     bool // Writing failed; see skipSource.
+
+	// Rows and records counted for Options.Offset and Options.Limit.
+	rowsSeen    int
+	recordsSeen int
+	sampleIndex int // Rows considered for sampling; see sampled.
+
+	// partition resolves Options.PartitionBy into partitionKey for every
+	// row (see ConvertPartitioned).
+	partition    *fieldPlan
+	partitionKey string
+
+	// discoverKeys is set while fields lack the columns of key expansions
+	// that must be discovered from the input (see prepareSources).
+	discoverKeys bool
+
+	// lateKeys holds the key collectors of a discovery stopped by
+	// Options.DiscoveryRecords, indexed like Options.Fields, to report the
+	// keys seen afterwards (see reportLateKeys).
+	lateKeys map[int]*keyCollector
+
+	unmapped *unmappedKeys // nil unless Options.WarnUnmappedKeys is set
+	drift    *driftChecker // nil unless Options.ExpectedSchema is set
+
+	// skipRecords is the number of records of the current source converted
+	// before a resume, and onRecord is called after each record converted
+	// (see ConvertResumable).
+	skipRecords int
+	onRecord    func() error
+
+	// Provenance of the record currently being processed.
+	sourceName   string
+	recordIndex  int
+	recordNumber int       // One-based, across sources
+	startTime    time.Time // See PathNow
+
+	// Position of the record currently being processed (see locate).
+	inRecord     bool
+	recordOffset int64 // -1 if unknown
+	itemIndex    int   // -1 outside of items
+	arrayLength  int   // Elements of the flattened array of the record
+	fieldIndex   int   // -1 outside of fields
+}
+
+// newConversion validates options and applies defaults for a conversion
+// writing to out. Nothing is written to out yet.
+func newConversion(out RowWriter, options Options) (*conversion, error) {
+	options.Delimiter = options.delimiter()
+
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	options, err := options.nativePaths()
+	if err != nil {
+		return nil, err
+	}
+	if options, err = options.selectFields(); err != nil {
+		return nil, err
 	}
 
-	csvWriter := csv.NewWriter(w)
-	csvWriter.Comma = options.Delimiter
-	defer csvWriter.Flush() // Ensure any buffered data is written at the end
+	// Determine the path to the array that will trigger flattening.
+	flattenArrayPath := getFlattenArrayPath(options.Fields)
+	flattenPath, err := compilePath(flattenArrayPath)
+	if err != nil {
+		return nil, err
+	}
 
-	// Handle default for AddHeader. If not explicitly set to false, default to true.
-	addHeader := true
-	if options.AddHeader == false {
-		addHeader = false
+	filters, err := buildFilters(options)
+	if err != nil {
+		return nil, err
 	}
 
-	if addHeader {
-		headerRow := make([]string, len(options.Fields))
-		for i, field := range options.Fields {
-			headerRow[i] = field.CSVHeader
+	c := &conversion{
+		options:          options,
+		out:              out,
+		flattenArrayPath: flattenArrayPath,
+		flattenPath:      flattenPath,
+		filters:          filters,
+		newlines:         options.newlineReplacer(),
+		metrics:          options.Metrics,
+	}
+	if c.metrics == nil {
+		c.metrics = NopMetrics{}
+	}
+	fields, pending, err := expandFields(options.Fields, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.discoverKeys = pending
+	if err := c.setFields(fields); err != nil {
+		return nil, err
+	}
+	if options.WarnUnmappedKeys {
+		if c.unmapped, err = newUnmappedKeys(options.Fields, flattenArrayPath, c.reportUnmappedKey); err != nil {
+			return nil, err
+		}
+	}
+	if options.ExpectedSchema != nil {
+		if c.drift, err = newDriftChecker(*options.ExpectedSchema, flattenArrayPath); err != nil {
+			return nil, err
 		}
-		if err := csvWriter.Write(headerRow); err != nil {
-			return fmt.Errorf("json2csv: failed to write header: %w", err)
+	}
+	if options.PartitionBy != "" {
+		plan, err := compileFieldPlan(options.PartitionBy)
+		if err != nil {
+			return nil, err
 		}
+		c.partition = &plan
+	}
+	return c, nil
+}
+
+// setFields sets the effective columns of the conversion and derives the
+// header from them.
+func (c *conversion) setFields(fields []Field) error {
+	header := make([]string, len(fields))
+	for i, field := range fields {
+		header[i] = field.CSVHeader
+		if header[i] == "" {
+			header[i] = c.options.HeaderNaming.header(field.JSONPath)
+		}
+	}
+	if c.options.HeaderTranslations != nil {
+		var err error
+		if header, err = c.options.HeaderTranslations.translate(header); err != nil {
+			return err
+		}
+	}
+	header, err := c.options.DuplicateHeaders.apply(header)
+	if err != nil {
+		return err
+	}
+	for i := range header {
+		header[i] = c.options.BiDi.apply(c.replaceNewlines(c.options.HeaderCase.apply(header[i])))
 	}
 
-	decoder := json.NewDecoder(r)
-	decoder.UseNumber() // Keep numbers as json.Number for precision
+	plans := make([]fieldPlan, len(fields))
+	printers := make([]*message.Printer, len(fields))
+	for i, field := range fields {
+		if field.Constant != nil || field.Compute != nil || field.Query != nil {
+			plans[i] = fieldPlan{constant: field.Constant, computed: field.Compute != nil || field.Query != nil}
+			continue
+		}
+		if resolver := c.options.PathResolver; resolver != nil {
+			plans[i] = resolverPlan(field.JSONPath, resolver)
+		} else if plans[i], err = compileFieldPlan(field.JSONPath); err != nil {
+			return err
+		}
+		if printers[i], err = localePrinter(field, c.options); err != nil {
+			return err
+		}
+		for _, fallback := range field.FallbackPaths {
+			if resolver := c.options.PathResolver; resolver != nil {
+				plans[i].fallbacks = append(plans[i].fallbacks, resolverPlan(fallback, resolver))
+				continue
+			}
+			plan, err := compileFieldPlan(fallback)
+			if err != nil {
+				return err
+			}
+			plans[i].fallbacks = append(plans[i].fallbacks, plan)
+		}
+	}
 
-	// Expect the input to be a JSON array of objects.
-	token, err := decoder.Token()
-	if err != nil {
-        if err == io.EOF { return nil } // Handle empty input
-		return fmt.Errorf("json2csv: failed to read initial token: %w", err)
+	c.fields = fields
+	c.plans = plans
+	c.printers = printers
+	c.header = header
+	if c.options.StatsWriter != nil {
+		c.stats = newStatsCollector(len(header))
 	}
-	if delim, ok := token.(json.Delim); !ok || delim.String() != "[" {
-		return fmt.Errorf(`json2csv: expected start of json array "[", but got %v (%T)`, token, token)
+	if c.options.SchemaWriter != nil {
+		c.schema = newSchemaCollector(fields)
 	}
+	return nil
+}
 
-	// Determine the path to the array that will trigger flattening.
-	flattenArrayPath := getFlattenArrayPath(options.Fields)
-    if err != nil {
-        return fmt.Errorf("json2csv: failed to determine flattening array path: %w", err)
-    }
-    isFlattening := flattenArrayPath != ""
+// run writes the header, calls convertSources to stream the records and
+// finishes the output.
+func (c *conversion) run(convertSources func() error) (err error) {
+	defer c.out.Flush() // Ensure any buffered data is written at the end
+
+	c.initReport()
+	c.startTime = time.Now()
+	defer func() {
+		if err != nil {
+			c.metrics.IncErrors(1)
+		}
+		c.metrics.ObserveDuration(time.Since(c.startTime))
+	}()
+	c.row = make([]string, len(c.fields))
+	// Rows pass through GroupBy, then SortBy, before reaching the output.
+	header := c.header
+	var grouper *groupRowWriter
+	if c.options.GroupBy != nil {
+		var err error
+		if grouper, err = newGroupRowWriter(nil, c.header, c.options); err != nil {
+			return err
+		}
+		header = grouper.header
+	}
+	if len(c.options.SortBy) > 0 {
+		columns, err := sortColumns(c.options.SortBy, header)
+		if err != nil {
+			return err
+		}
+		sorter := newSortRowWriter(c.out, columns, c.options)
+		defer sorter.remove()
+		c.out = sorter
+	}
+	if grouper != nil {
+		grouper.out = c.out
+		c.out = grouper
+	}
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
 
-    // --- New Check: Require flattening ---
-    if !isFlattening {
-        return errors.New("json2csv: flattening is the only supported mode. At least one Field JSONPath must contain '[*]'")
-    }
-    // --- End New Check ---
+	if err := convertSources(); err != nil && !errors.Is(err, errLimitReached) {
+		return err
+	}
+
+	// Finish the document and flush any remaining buffered output
+	if err := c.out.Close(); err != nil {
+		return fmt.Errorf("json2csv: error flushing output writer: %w", err)
+	}
+
+	// The column profile is only written for complete conversions.
+	if c.stats != nil {
+		delimiter := c.options.Delimiter
+		if c.options.Format != FormatCSV {
+			delimiter = DefaultDelimiter
+		}
+		if err := c.stats.write(c.options.StatsWriter, c.header, delimiter, c.options.MaxCellLength > 0); err != nil {
+			return fmt.Errorf("json2csv: failed to write column statistics: %w", err)
+		}
+	}
+	if c.schema != nil {
+		schema := c.schema.schema(c.header)
+		if err := writeSchema(c.options.SchemaWriter, c.options.SchemaFormat, c.options.SchemaTable, schema); err != nil {
+			return fmt.Errorf("json2csv: failed to write schema: %w", err)
+		}
+	}
 
+	return nil // Success
+}
 
-	// Process each JSON object in the array
-	for decoder.More() {
+// writeHeader writes the header row unless it is disabled.
+func (c *conversion) writeHeader() error {
+	if c.options.OmitHeader {
+		return nil
+	}
+	if c.options.HeaderOnEmpty == HeaderOnEmptyOmit {
+		c.headerPending = true // Written with the first row.
+		return nil
+	}
+	return c.emitHeader()
+}
+
+// emitHeader writes the header row to the output.
+func (c *conversion) emitHeader() error {
+	c.headerPending = false
+	if err := c.out.WriteHeader(c.header); err != nil {
+		return fmt.Errorf("json2csv: failed to write header: %w", err)
+	}
+	if c.report != nil {
+		c.report.HeaderWritten = true
+	}
+	return nil
+}
+
+// writeRow writes a data row and records it in the column statistics.
+func (c *conversion) writeRow(row []string) error {
+	if c.headerPending {
+		if err := c.emitHeader(); err != nil {
+			c.outputFailed = true
+			return err
+		}
+	}
+	if err := c.out.WriteRow(row); err != nil {
+		c.outputFailed = true
+		return fmt.Errorf("json2csv: failed to write csv row: %w", err)
+	}
+	c.rowsWritten++
+	c.metrics.IncRows(1)
+	if c.report != nil {
+		c.report.RowsWritten++
+	}
+	if c.options.FlushEvery > 0 && c.rowsWritten%c.options.FlushEvery == 0 {
+		if err := c.out.Flush(); err != nil {
+			c.outputFailed = true
+			return fmt.Errorf("json2csv: failed to flush output: %w", err)
+		}
+	}
+	if c.schema != nil {
+		c.schema.endRow()
+	}
+	if c.stats != nil {
+		if c.options.Lossless && c.options.Format == FormatCSV {
+			row = decodeLosslessRow(row)
+		}
+		c.stats.addRow(row)
+	}
+	return nil
+}
+
+// convertSource streams the JSON array in source and writes its rows.
+// Errors are located with a ConvertError.
+func (c *conversion) convertSource(source Source) error {
+	c.sourceName = source.Name
+	c.recordIndex = 0
+	c.inRecord = false
+
+	err := c.decodeRecords(source.Reader, func(originalRecord map[string]interface{}, items *itemSpool) error {
+		if c.recordIndex < c.skipRecords {
+			c.endRecord()
+			return nil
+		}
+		if err := c.processRecord(originalRecord, items); err != nil {
+			return err
+		}
+		c.endRecord()
+		if c.onRecord != nil {
+			return c.onRecord()
+		}
+		return nil
+	})
+	return c.locate(err)
+}
+
+// decodeArray streams a JSON array of objects from r, calling fn for each
+// record in order. Empty input is treated as an empty array.
+func decodeArray(r io.Reader, fn func(record map[string]interface{}) error) error {
+	return decodeArrayElements(r, func(decoder *json.Decoder, offset int64) error {
 		var originalRecord map[string]interface{}
 		err := decoder.Decode(&originalRecord)
 		if err != nil {
-			return fmt.Errorf("json2csv: failed to decode json object: %w", err)
+			return decodeError(err)
+		}
+		return fn(originalRecord)
+	})
+}
+
+// decodeArrayElements reads the JSON array in r, calling decodeElement to
+// consume each element from decoder; offset is the approximate byte offset
+// of the element in r. Empty input is treated as an empty array. Input
+// starting with "{" is read as a stream of objects (NDJSON).
+func decodeArrayElements(r io.Reader, decodeElement func(decoder *json.Decoder, offset int64) error) error {
+	return (&jsonStream{r: r}).each(decodeElement)
+}
+
+// jsonStream reads the elements of a JSON array, or the objects of a stream
+// of objects (e.g. NDJSON, one per line), one at a time.
+type jsonStream struct {
+	r       io.Reader
+	br      *bufio.Reader // The reader of decoder
+	decoder *json.Decoder // Set once the stream has started.
+	objects bool          // The input is a stream of objects.
+	done    bool
+	skipped int64 // Input read before the decoder started.
+	indent  int   // Of the current element, -1 if unknown (see lineIndent).
+
+	// multiple continues with the arrays following the first one (see
+	// nextDocument).
+	multiple bool
+
+	// maxElementBytes, if positive, makes the decoding of larger elements
+	// fail with ErrRecordTooLarge (see recordLimiter).
+	maxElementBytes int64
+	limiter         *recordLimiter
+
+	// skipElement, if set, reports whether the element whose decodeElement
+	// failed with err is to be skipped instead (see resync).
+	skipElement func(err error) bool
+}
+
+// each calls decodeElement to consume each element of the stream from
+// decoder; offset is the approximate byte offset of the element in r.
+func (s *jsonStream) each(decodeElement func(decoder *json.Decoder, offset int64) error) error {
+	if s.maxElementBytes > 0 {
+		s.limiter = &recordLimiter{r: s.r, max: s.maxElementBytes, start: -1}
+		s.r = s.limiter
+	}
+	for {
+		if more, err := s.next(); err != nil || !more {
+			return err
+		}
+		offset := s.skipped + s.decoder.InputOffset()
+		if s.limiter != nil {
+			s.limiter.start = offset
+		}
+		err := decodeElement(s.decoder, offset)
+		if s.limiter != nil {
+			s.limiter.start = -1
+		}
+		if err != nil && s.skipElement != nil && s.skipElement(err) {
+			err = s.resync(err)
+		}
+		if err != nil {
+			return err
 		}
+	}
+}
 
-		var itemsToProcess []map[string]interface{} // Will hold the array items
+// next reports whether another element can be decoded from s.decoder. At
+// the end of the input it checks that the array is properly terminated.
+func (s *jsonStream) next() (bool, error) {
+	if s.done {
+		return false, nil
+	}
+	if s.decoder == nil {
+		if err := s.start(); err != nil {
+			s.done = true
+			return false, err
+		} else if s.done {
+			return false, nil
+		}
+	}
+	if s.skipElement != nil {
+		s.indent = lineIndent(s.decoder.Buffered())
+	}
+	if s.decoder.More() {
+		return true, nil
+	}
+	s.done = true
+	if s.objects {
+		if token, err := s.decoder.Token(); err != io.EOF {
+			if err != nil {
+				return false, fmt.Errorf("json2csv: failed to read json object stream: %w", err)
+			}
+			return false, fmt.Errorf("json2csv: unexpected %v after json object", token)
+		}
+		return false, nil
+	}
 
-        // Get the array value from the original record using the determined path
-		arrayValue, getArrErr := getValueByDotPath(originalRecord, flattenArrayPath)
-		if getArrErr != nil {
-			// Error getting the array itself (e.g., path segment not a map)
-			return fmt.Errorf("json2csv: failed to get array for flattening at path %q: %w", flattenArrayPath, getArrErr)
+	// Read the closing bracket ']'
+	token, err := s.decoder.Token()
+	if err != nil {
+		if err == io.EOF {
+			return false, fmt.Errorf("json2csv: unexpected EOF while expecting end of array ']'")
 		}
+		return false, fmt.Errorf("json2csv: failed to read final token: %w", err)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim.String() != "]" {
+		return false, fmt.Errorf(`json2csv: expected end of json array "]", but got %v (%T)`, token, token)
+	}
+	if s.multiple {
+		return s.nextDocument()
+	}
+	return false, nil
+}
+
+// start detects the kind of input and reads the opening "[" of an array.
+func (s *jsonStream) start() error {
+	br := bufio.NewReader(s.r)
+	s.br = br
+	first, skipped, err := peekNonSpace(br)
+	s.skipped += int64(skipped)
+	if err == io.EOF {
+		s.done = true
+		return nil // Handle empty input
+	} else if err != nil {
+		return fmt.Errorf("json2csv: failed to read initial token: %w", err)
+	}
+	s.decoder = json.NewDecoder(br)
+	s.decoder.UseNumber() // Keep numbers as json.Number for precision
+	if first == '{' {
+		s.objects = true
+		return nil
+	}
 
-        // Handle null or non-array values at the flattening path
-		if arrayValue == nil {
-			// Value is null. Treat as empty array, skip this record.
+	// Expect the input to be a JSON array of objects.
+	token, err := s.decoder.Token()
+	if err != nil {
+		return fmt.Errorf("json2csv: failed to read initial token: %w", err)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim.String() != "[" {
+		return fmt.Errorf(`json2csv: expected start of json array "[", but got %v (%T)`, token, token)
+	}
+	return nil
+}
+
+// peekNonSpace skips leading JSON whitespace in br and returns the next byte
+// without consuming it, and the number of bytes skipped.
+func peekNonSpace(br *bufio.Reader) (byte, int, error) {
+	for skipped := 0; ; skipped++ {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, skipped, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
 			continue
 		}
+		return b, skipped, br.UnreadByte()
+	}
+}
+
+// errLimitReached stops a conversion once Options.Limit is reached.
+var errLimitReached = errors.New("json2csv: limit reached")
+
+// processRecord flattens one decoded record and writes a row per array item.
+// items holds the record's array items if they were streamed (see
+// decodeRecords).
+func (c *conversion) processRecord(originalRecord map[string]interface{}, items *itemSpool) error {
+	c.recordNumber++
+	c.metrics.IncRecords(1)
+	if c.report != nil {
+		c.report.RecordsRead++
+	}
+	if c.unmapped != nil {
+		c.unmapped.checkRecord(originalRecord)
+	}
+	if c.drift != nil {
+		if err := c.drift.checkRecord(originalRecord, c.recordNumber, items != nil && items.streamed); err != nil {
+			return err
+		}
+	}
 
-		arr, ok := arrayValue.([]interface{})
-		if !ok {
-			// Value is not an array (and not null). Return error.
-			return fmt.Errorf("json2csv: value at flatten path %q is not an array or null, but %T", flattenArrayPath, arrayValue)
-		}
-
-        // Convert array items to map[string]interface{} slice
-        for i, item := range arr {
-            if itemMap, itemIsMap := item.(map[string]interface{}); itemIsMap {
-                itemsToProcess = append(itemsToProcess, itemMap)
-            } else if item == nil {
-                 // Handle null items within the array by skipping them.
-                 continue
-            } else {
-                // Handle array elements that are not objects. Error out.
-                return fmt.Errorf("json2csv: array element at path %q index %d is not a JSON object, but %T", flattenArrayPath, i, item)
-            }
-        }
-
-        // If after processing, itemsToProcess is empty (original array was empty or contained only null/non-objects)
-        if len(itemsToProcess) == 0 {
-             continue // Skip this record
-        }
-
-
-		// --- Process Items (the flattened array items) ---
-		for _, itemData := range itemsToProcess { // itemData is a flattened array item map
-			csvRow := make([]string, len(options.Fields))
-
-			for i, field := range options.Fields {
-				var value interface{}
-				var getValErr error
-
-                // Determine the data source and effective path based on whether the field has "[*]".
-                starIndex := strings.Index(field.JSONPath, "[*]")
-
-                if starIndex != -1 {
-                    // Field has "[*]". Get value from the current itemData (the array item map).
-                    pathAfterStar := field.JSONPath[starIndex+len("[*]"):]
-                     if strings.HasPrefix(pathAfterStar, ".") {
-                        pathAfterStar = pathAfterStar[1:]
-                     }
-                     // Handle "array[*]" case (path after star is empty) implicitly handled by getValueByDotPath
-
-                     value, getValErr = getValueByDotPath(itemData, pathAfterStar) // Get value from the item map
-                     if getValErr != nil {
-                        return fmt.Errorf("json2csv: failed to get value from array item for field %q (path after [*]: %q): %w", field.JSONPath, pathAfterStar, getValErr)
-                     }
-
-                } else {
-                    // Field does NOT have "[*]". Get value from the original record.
-                     value, getValErr = getValueByDotPath(originalRecord, field.JSONPath) // Get value from original record
-                     if getValErr != nil {
-                        return fmt.Errorf("json2csv: failed to get value from record for field %q: %w", field.JSONPath, getValErr)
-                     }
-                }
-
-                // Note: If getValueByDotPath successfully returns nil, nil, 'value' will be nil, valueToString handles as "".
-
-
-				// Apply transformation if a transformer is provided
-				transformedValue := value
-				var transformErr error
-				if field.Transformer != nil {
-					transformedValue, transformErr = field.Transformer(value, originalRecord) // Pass originalRecord for context
-					if transformErr != nil {
-						// Handle transformation error: propagate it.
-						return fmt.Errorf("json2csv: failed to transform field %q: %w", field.JSONPath, transformErr)
+	if c.options.LimitUnit == LimitRecords {
+		c.recordsSeen++
+		if c.recordsSeen <= c.options.Offset {
+			return nil
+		}
+		if err := c.processItems(originalRecord, items); err != nil {
+			return err
+		}
+		if c.options.Limit > 0 && c.recordsSeen == c.options.Offset+c.options.Limit {
+			return errLimitReached
+		}
+		return nil
+	}
+	return c.processItems(originalRecord, items)
+}
+
+// processItems is processRecord once Offset and Limit in records are
+// applied.
+func (c *conversion) processItems(originalRecord map[string]interface{}, items *itemSpool) error {
+	// Skip records rejected by record-level filters before flattening them.
+	if keep, err := c.keepRow(false, originalRecord, nil); err != nil || !keep {
+		if !keep && err == nil && c.report != nil {
+			c.report.RecordsFiltered++
+		}
+		return err
+	}
+
+	// --- Process Items (the flattened array items) ---
+	writeItem := func(itemData interface{}) error { // itemData is a flattened array item
+		if keep, err := c.keepRow(true, originalRecord, itemData); err != nil {
+			return err
+		} else if !keep {
+			if c.report != nil {
+				c.report.RowsFiltered++
+			}
+			return nil
+		}
+
+		if c.lateKeys != nil && c.report != nil {
+			if err := c.reportLateKeys(originalRecord, itemData); err != nil {
+				return err
+			}
+		}
+
+		csvRow := c.row
+		ctx := &c.transformContext
+		*ctx = TransformContext{
+			Record:      originalRecord,
+			Item:        itemData,
+			ItemIndex:   c.itemIndex,
+			RecordIndex: c.recordIndex,
+			Source:      c.sourceName,
+		}
+		for i, field := range c.fields {
+			c.fieldIndex = i
+			ctx.Field = field
+			// Missing values resolve to nil, which valueToString formats as "".
+			value, missing, err := c.resolveField(i, originalRecord, itemData)
+			ctx.Missing = missing
+			if missing {
+				if field.MissingValue != nil {
+					value = field.MissingValue
+				}
+				if c.report != nil {
+					c.report.Fields[i].Missing++
+				}
+			}
+
+			// Apply the field's transformers and type, if any, handling
+			// failures as selected by the field's OnError.
+			var transformedValue interface{}
+			if err == nil {
+				transformedValue, err = field.value(value, ctx, c.options.StrictTypes, c.options.PreserveNumberStrings)
+			}
+			if err != nil {
+				var skipRow bool
+				if transformedValue, skipRow, err = field.handleError(err); err != nil {
+					return err
+				}
+				c.metrics.IncErrors(1)
+				if c.report != nil {
+					c.report.Fields[i].Errors++
+				}
+				if skipRow {
+					if c.report != nil {
+						c.report.RowsSkipped++
 					}
+					return nil
 				}
+			}
+
+			if c.schema != nil {
+				c.schema.observe(i, transformedValue)
+			}
+			if transformedValue == nil && c.report != nil {
+				c.report.Fields[i].Nulls++
+			}
 
-				// Convert the transformed value to a string for CSV
-				csvRow[i] = valueToString(transformedValue)
+			// Convert the transformed value to a string for CSV
+			cell, err := c.formatCell(i, transformedValue)
+			if err != nil {
+				return fmt.Errorf("json2csv: failed to encode field %q: %w", field.JSONPath, err)
 			}
+			csvRow[i] = cell
+		}
+		c.fieldIndex = -1
+
+		if c.partition != nil {
+			key, _, _ := c.resolvePlan(*c.partition, originalRecord, itemData) // PartitionBy uses no PathResolver.
+			c.partitionKey = valueToString(key)
+		}
 
-			// Write the CSV row
-			if err := csvWriter.Write(csvRow); err != nil {
-				return fmt.Errorf("json2csv: failed to write csv row: %w", err)
+		if (c.options.SampleEvery > 1 || c.options.SampleRate > 0) && !c.sampled() {
+			return nil
+		}
+		if c.options.LimitUnit == LimitRows {
+			c.rowsSeen++
+			if c.rowsSeen <= c.options.Offset {
+				return nil
 			}
 		}
+
+		// Write the CSV row
+		if err := c.writeRow(csvRow); err != nil {
+			return err
+		}
+		if c.options.LimitUnit == LimitRows && c.options.Limit > 0 && c.rowsSeen == c.options.Offset+c.options.Limit {
+			return errLimitReached
+		}
+		return nil
+	}
+	count, err := c.eachItem(originalRecord, items, writeItem)
+	if err != nil {
+		return err
 	}
 
-	// Read the closing bracket ']'
-	token, err = decoder.Token()
+	// If no items were found (the array was missing, empty or contained only
+	// nulls), the record produces no rows unless EmptyArrayBehavior says
+	// otherwise.
+	if count == 0 {
+		switch c.options.EmptyArrayBehavior {
+		case EmptyArrayEmitParent:
+			return writeItem(nil)
+		case EmptyArrayError:
+			return fmt.Errorf("json2csv: no items to flatten at path %q", c.flattenArrayPath)
+		}
+		if c.report != nil {
+			c.report.RecordsSkipped++
+		}
+	}
+	return nil
+}
+
+// eachItem calls fn for each item of the array to flatten in originalRecord,
+// or in items if the array was streamed, and returns their number. Items
+// are objects or, with Options.AllowScalarArrayItems, scalars; other
+// elements are handled as selected by Options.InvalidItems. Null items are
+// skipped.
+func (c *conversion) eachItem(originalRecord map[string]interface{}, items *itemSpool, fn func(item interface{}) error) (int, error) {
+	if c.flattenArrayPath == "" {
+		return 1, fn(nil) // Only pivoted arrays: a row per record.
+	}
+	count := 0
+	c.arrayLength = 0
+	if items == nil || !items.streamed {
+		itemsToProcess, err := c.flattenItems(originalRecord)
+		if err != nil {
+			return 0, err
+		}
+		c.arrayLength = len(itemsToProcess)
+		for index, element := range itemsToProcess {
+			item, err := c.checkItem(index, element)
+			if err != nil {
+				return 0, err
+			} else if item == nil {
+				continue
+			}
+			count++
+			c.itemIndex = index
+			if err := fn(item); err != nil {
+				return 0, err
+			}
+		}
+		c.itemIndex = -1
+		return count, nil
+	}
+
+	c.arrayLength = items.count
+	err := items.each(func(index int, element interface{}) error {
+		if c.unmapped != nil && !c.discoverKeys {
+			c.unmapped.checkItem(element)
+		}
+		if c.drift != nil && !c.discoverKeys {
+			if err := c.drift.checkItem(element); err != nil {
+				return err
+			}
+		}
+		item, err := c.checkItem(index, element)
+		if err != nil || item == nil {
+			return err
+		}
+		count++
+		c.itemIndex = index
+		return fn(item)
+	})
+	if err == nil {
+		c.itemIndex = -1
+	}
+	return count, err
+}
+
+// formatCell converts a transformed value of field i to the text of its
+// cell.
+func (c *conversion) formatCell(i int, value interface{}) (string, error) {
+	if s, ok := value.(string); ok && !c.fields[i].KeepWhitespace {
+		value = c.options.normalizeSpace(s)
+	}
+	if c.options.Lossless && c.options.Format == FormatCSV {
+		if s, ok := value.(string); ok {
+			var err error
+			if value, err = c.limitCell(i, c.replaceNewlines(s)); err != nil {
+				return "", err
+			}
+		}
+		return encodeLosslessCell(value, c.options.BiDi)
+	}
+	cell, ok := "", false
+	if p := c.printers[i]; p != nil {
+		cell, ok = formatLocaleNumber(p, value, c.options.PreserveNumberStrings)
+	}
+	if !ok {
+		cell = valueToString(value)
+	}
+	cell, err := c.limitCell(i, c.replaceNewlines(cell))
 	if err != nil {
-        if err == io.EOF {
-             return fmt.Errorf("json2csv: unexpected EOF while expecting end of array ']'")
-        }
-		return fmt.Errorf("json2csv: failed to read final token: %w", err)
+		return "", err
 	}
-	if delim, ok := token.(json.Delim); !ok || delim.String() != "]" {
-		return fmt.Errorf(`json2csv: expected end of json array "]", but got %v (%T)`, token, token)
+	return c.options.BiDi.apply(cell), nil
+}
+
+// item returns the item for the element at index of the array to flatten
+// (see eachItem), or nil if the element is skipped. invalid reports whether
+// the element was handled by Options.InvalidItems.
+func (c *conversion) item(index int, element interface{}) (item interface{}, invalid bool, err error) {
+	switch element.(type) {
+	case map[string]interface{}, nil:
+		return element, false, nil
+	case []interface{}:
+	default:
+		if c.options.AllowScalarArrayItems {
+			return element, false, nil
+		}
+	}
+	switch c.options.InvalidItems {
+	case InvalidItemsSkip:
+		return nil, true, nil
+	case InvalidItemsStringify:
+		text, err := StringifyJSON(element, nil)
+		if err != nil {
+			return nil, true, err
+		}
+		return valueToString(text), true, nil
+	}
+	return nil, false, fmt.Errorf("json2csv: array element at path %q index %d is not a JSON object, but %T", c.flattenArrayPath, index, element)
+}
+
+// checkItem is item for eachItem, counting invalid elements in the report
+// (but not while discovering keys).
+func (c *conversion) checkItem(index int, element interface{}) (interface{}, error) {
+	item, invalid, err := c.item(index, element)
+	if invalid && err == nil && !c.discoverKeys {
+		c.metrics.IncErrors(1)
+		if c.report != nil {
+			c.report.InvalidItems++
+		}
+	}
+	return item, err
+}
+
+// flattenItems returns the items of the array to flatten in originalRecord
+// (see eachItem), checking all of them first. Null items are kept, so items
+// keep their array index; a null or missing array yields no items.
+func (c *conversion) flattenItems(originalRecord map[string]interface{}) ([]interface{}, error) {
+	flattenArrayPath := c.flattenArrayPath
+
+	// Get the array value from the original record using the determined path
+	arrayValue := c.flattenPath.evaluate(originalRecord)
+	if c.flattenPath.query && arrayValue != nil {
+		// A query matches a list of items or arrays of items; flatten them all.
+		arrayValue = concatArrays(arrayValue.([]interface{}))
 	}
 
-	// Flush any remaining buffered CSV data
-	csvWriter.Flush()
-	if err := csvWriter.Error(); err != nil {
-		return fmt.Errorf("json2csv: error flushing csv writer: %w", err)
+	// Handle null or non-array values at the flattening path
+	if arrayValue == nil {
+		// Value is null. Treat as empty array, skip this record.
+		return nil, nil
 	}
 
-	return nil // Success
-}
\ No newline at end of file
+	arr, ok := arrayValue.([]interface{})
+	if !ok {
+		// Value is not an array (and not null). Return error.
+		return nil, fmt.Errorf("json2csv: value at flatten path %q is not an array or null, but %T", flattenArrayPath, arrayValue)
+	}
+
+	for i, element := range arr {
+		if _, _, err := c.item(i, element); err != nil {
+			return nil, err
+		}
+	}
+	return arr, nil
+}
+
+// resolvePath returns the value at path for the current row. Paths with "[*]"
+// are resolved against item (the current flattened array item), pseudo-paths
+// against the conversion state and all other paths against originalRecord.
+func (c *conversion) resolvePath(path string, originalRecord map[string]interface{}, item interface{}) (interface{}, error) {
+	if isPseudoPath(path) {
+		// Path refers to conversion metadata rather than record data.
+		return c.pseudoPathValue(path), nil
+	}
+
+	// Determine the data source and effective path based on whether the path has "[*]".
+	starIndex := strings.Index(path, "[*]")
+	if starIndex != -1 {
+		if item == nil {
+			return nil, nil // The row of a record without items.
+		}
+		// Path has "[*]". Get value from the current item (usually an object).
+		pathAfterStar := path[starIndex+len("[*]"):]
+		if strings.HasPrefix(pathAfterStar, ".") {
+			pathAfterStar = pathAfterStar[1:]
+		}
+		// Handle "array[*]" case (path after star is empty) implicitly handled by getValueByDotPath
+
+		value, err := getValueByDotPath(item, pathAfterStar) // Get value from the item
+		if err != nil {
+			return nil, fmt.Errorf("json2csv: failed to get value from array item for field %q (path after [*]: %q): %w", path, pathAfterStar, err)
+		}
+		return value, nil
+	}
+
+	// Path does NOT have "[*]". Get value from the original record.
+	value, err := getValueByDotPath(originalRecord, path)
+	if err != nil {
+		return nil, fmt.Errorf("json2csv: failed to get value from record for field %q: %w", path, err)
+	}
+	return value, nil
+}
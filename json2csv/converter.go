@@ -0,0 +1,68 @@
+// json2csv/converter.go
+
+package json2csv
+
+import "io"
+
+// Converter is a conversion configuration that has been validated and
+// prepared once, for services that run many conversions with the same
+// Options: validation, key expansion and the parsing of every JSONPath are
+// done by NewConverter instead of on every call. A Converter is safe for
+// concurrent use.
+type Converter struct {
+	options Options
+	proto   *conversion // Prepared state, copied for every run.
+}
+
+// NewConverter validates options and prepares a Converter for them.
+func NewConverter(options Options) (*Converter, error) {
+	proto, err := newConversion(nil, options)
+	if err != nil {
+		return nil, err
+	}
+	return &Converter{options: options, proto: proto}, nil
+}
+
+// Options returns the options the Converter was built from.
+func (cv *Converter) Options() Options {
+	return cv.options
+}
+
+// Convert converts the JSON array in r like the package-level Convert.
+func (cv *Converter) Convert(r io.Reader, w io.Writer) error {
+	return withOutput(w, cv.options, func(w io.Writer) error {
+		return cv.ConvertTo(r, NewRowWriter(w, cv.options))
+	})
+}
+
+// ConvertTo converts the JSON array in r like the package-level ConvertTo.
+func (cv *Converter) ConvertTo(r io.Reader, rw RowWriter) error {
+	c := cv.newRun(rw)
+	sources, cleanup, err := c.prepareSources([]Source{{Reader: r}})
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	return c.run(func() error {
+		return c.convertSource(sources[0])
+	})
+}
+
+// newRun returns a fresh conversion writing to out.
+func (cv *Converter) newRun(out RowWriter) *conversion {
+	c := *cv.proto
+	c.out = out
+	if c.stats != nil {
+		c.stats = newStatsCollector(len(c.header))
+	}
+	if c.schema != nil {
+		c.schema = newSchemaCollector(c.fields)
+	}
+	if c.unmapped != nil {
+		c.unmapped = c.unmapped.forRun(c.reportUnmappedKey)
+	}
+	if c.drift != nil {
+		c.drift = c.drift.forRun()
+	}
+	return &c
+}
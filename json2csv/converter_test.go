@@ -0,0 +1,96 @@
+// json2csv/converter_test.go
+
+package json2csv_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pradnyoday/go-json2csv/json2csv"
+)
+
+// convertConcurrently runs the conversion of every input at once with cv
+// and returns the errors, in the order of inputs.
+func convertConcurrently(cv *json2csv.Converter, inputs ...string) []error {
+	errs := make([]error, len(inputs))
+	var wg sync.WaitGroup
+	for i, input := range inputs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = cv.Convert(strings.NewReader(input), io.Discard)
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+func TestConverterConcurrentUnmappedKeys(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+	cv, err := json2csv.NewConverter(json2csv.Options{
+		Fields: []json2csv.Field{
+			{JSONPath: "id", CSVHeader: "id"},
+			{JSONPath: "items[*].sku", CSVHeader: "sku"},
+		},
+		WarnUnmappedKeys: true,
+		OnUnmappedKey: func(path string) {
+			mu.Lock()
+			defer mu.Unlock()
+			keys = append(keys, path)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := `{"id":1,"items":[{"sku":"a"}],"extra":true}
+{"id":2,"items":[{"sku":"b"}],"extra":false}
+`
+	for i, err := range convertConcurrently(cv, input, input) {
+		if err != nil {
+			t.Errorf("run %d: %v", i, err)
+		}
+	}
+	// Each run reports the key once.
+	if got := strings.Join(keys, ","); got != "extra,extra" {
+		t.Errorf("unmapped keys = %q, want %q", got, "extra,extra")
+	}
+}
+
+func TestConverterConcurrentSchemaDrift(t *testing.T) {
+	cv, err := json2csv.NewConverter(json2csv.Options{
+		Fields: []json2csv.Field{
+			{JSONPath: "id", CSVHeader: "id"},
+			{JSONPath: "items[*].sku", CSVHeader: "sku"},
+		},
+		ExpectedSchema: &json2csv.ExpectedSchema{
+			Paths: map[string]json2csv.FieldType{
+				"id":           json2csv.TypeInt,
+				"items":        json2csv.TypeAny,
+				"items[*].sku": json2csv.TypeString,
+			},
+			NewKeys: json2csv.DriftError,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	drifting := `{"id":1,"items":[{"sku":"a"}],"extra":true}` + "\n"
+	valid := `{"id":2,"items":[{"sku":"b"}]}` + "\n"
+	errs := convertConcurrently(cv, drifting, valid)
+	if !errors.Is(errs[0], json2csv.ErrSchemaDrift) {
+		t.Errorf("drifting run: got %v, want ErrSchemaDrift", errs[0])
+	}
+	if errs[1] != nil {
+		t.Errorf("valid run: %v", errs[1])
+	}
+	// The error of a run does not stick to the Converter.
+	if err := cv.Convert(strings.NewReader(valid), io.Discard); err != nil {
+		t.Errorf("later run: %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+// json2csv/distinct.go
+
+package json2csv
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// DistinctOptions configures DistinctValues.
+type DistinctOptions struct {
+	// IncludeNull reports null and missing values as a ValueCount with Null set.
+	// By default they are not counted.
+	IncludeNull bool
+
+	// MaxValues caps the number of distinct values tracked, bounding memory on
+	// high-cardinality paths. Once the cap is reached, values not seen before
+	// are ignored and DistinctValues returns ErrTooManyValues along with the
+	// counts collected so far. Zero means no limit.
+	MaxValues int
+
+	// SortByValue orders the result by value instead of by descending count.
+	SortByValue bool
+}
+
+// ValueCount is a distinct value found by DistinctValues and the number of
+// times it occurred.
+type ValueCount struct {
+	// Value is the CSV string form of the value.
+	Value string
+
+	// Null is true for the entry counting null and missing values.
+	Null bool
+
+	// Count is the number of occurrences.
+	Count int
+}
+
+// ErrTooManyValues is returned by DistinctValues when DistinctOptions.MaxValues
+// is exceeded.
+var ErrTooManyValues = errors.New("json2csv: too many distinct values")
+
+// DistinctValues streams the JSON array in r and returns the distinct values
+// found at path with their counts. If path contains "[*]", every item of the
+// array before "[*]" contributes a value, exactly as the item would be
+// flattened by Convert; otherwise each record contributes one value.
+//
+// By default the result is ordered by descending count, ties broken by value.
+func DistinctValues(r io.Reader, path string, opts DistinctOptions) ([]ValueCount, error) {
+	counts := make(map[string]int)
+	nullCount := 0
+	overflow := false
+
+	add := func(value interface{}) {
+		if value == nil {
+			nullCount++
+			return
+		}
+		key := valueToString(value)
+		if _, seen := counts[key]; !seen && opts.MaxValues > 0 && len(counts) >= opts.MaxValues {
+			overflow = true
+			return
+		}
+		counts[key]++
+	}
+
+	err := decodeArray(r, func(record map[string]interface{}) error {
+		values, err := valuesAtPath(record, path)
+		if err != nil {
+			return err
+		}
+		for _, value := range values {
+			add(value)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]ValueCount, 0, len(counts)+1)
+	for value, count := range counts {
+		result = append(result, ValueCount{Value: value, Count: count})
+	}
+	if opts.IncludeNull && nullCount > 0 {
+		result = append(result, ValueCount{Null: true, Count: nullCount})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if !opts.SortByValue && a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.Null != b.Null {
+			return b.Null // Null sorts last
+		}
+		return a.Value < b.Value
+	})
+
+	if overflow {
+		return result, ErrTooManyValues
+	}
+	return result, nil
+}
+
+// valuesAtPath returns the values at path in record. For a path containing
+// "[*]" there is one value per non-null array item; a null or missing array
+// yields no values.
+func valuesAtPath(record map[string]interface{}, path string) ([]interface{}, error) {
+	starIndex := strings.Index(path, "[*]")
+	if starIndex == -1 {
+		value, err := getValueByDotPath(record, path)
+		if err != nil {
+			return nil, fmt.Errorf("json2csv: failed to get value at path %q: %w", path, err)
+		}
+		return []interface{}{value}, nil
+	}
+
+	arrayPath := strings.TrimSuffix(path[:starIndex], ".")
+	itemPath := strings.TrimPrefix(path[starIndex+len("[*]"):], ".")
+
+	arrayValue, err := getValueByDotPath(record, arrayPath)
+	if err != nil {
+		return nil, fmt.Errorf("json2csv: failed to get array at path %q: %w", arrayPath, err)
+	}
+	if arrayValue == nil {
+		return nil, nil
+	}
+	arr, ok := arrayValue.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("json2csv: value at path %q is not an array or null, but %T", arrayPath, arrayValue)
+	}
+
+	values := make([]interface{}, 0, len(arr))
+	for _, item := range arr {
+		switch item := item.(type) {
+		case nil:
+			continue
+		case map[string]interface{}:
+			value, err := getValueByDotPath(item, itemPath)
+			if err != nil {
+				return nil, fmt.Errorf("json2csv: failed to get value at path %q: %w", path, err)
+			}
+			values = append(values, value)
+		default:
+			// Scalar items only have a value for the item itself ("tags[*]").
+			if itemPath == "" {
+				values = append(values, item)
+			} else {
+				values = append(values, nil)
+			}
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,87 @@
+// json2csv/doc.go
+
+// Package json2csv converts streams of JSON objects into CSV (or Markdown or
+// HTML tables), flattening one nested array per record into one row per item.
+//
+// The conversion is configured with Options: an ordered list of Fields, each
+// mapping a JSONPath to a column header and optional Transformers. A path
+// containing "[*]" selects the array to flatten; every other path is resolved
+// against the enclosing record and repeated on each of its rows.
+//
+//	err := json2csv.Convert(r, w, json2csv.Options{
+//		Fields: []json2csv.Field{
+//			{JSONPath: "user_id", CSVHeader: "User ID"},
+//			{JSONPath: "items[*].price", CSVHeader: "Price"},
+//		},
+//	})
+//
+// # Paths
+//
+// Paths are a subset of JSONPath, written without the leading "$":
+//
+//	address.city             child keys
+//	metrics['p99.latency']   quoted key, for keys with dots, brackets or
+//	["user.name"]            spaces; a backslash escapes the next character
+//	items[0], items[-1]      array index, negative from the end
+//	items[1:3], items[::2]   array slice [start:end:step]
+//	address.*, items[*]      all values of an object or array
+//	..sku                    recursive descent: sku at any depth
+//	items[?(@.price>10)]     filter: the items matching an expression
+//
+// Filter expressions compare a path relative to the item ("@", "@.price")
+// with a literal number, 'string' or "string", true, false or null using ==,
+// !=, <, <=, > or >=. A bare path tests that the value exists and is not
+// null, false, 0 or "". Comparisons can be combined with && and ||.
+//
+// An index applied to an object selects the member named by its digits, if
+// the index is not negative: users[123] selects "123" of an object of
+// users. A path made of keys and indexes only resolves to a single value
+// (nil if missing). Any other path is a query and resolves to the list of matched
+// values, or nil if nothing matched. The first "[*]" of a Field still marks
+// the flattened array; the part before it may be a query, whose matches are
+// flattened in order, e.g. "items[?(@.qty>0)][*].sku".
+//
+// With Options.PathSyntax set to PathSyntaxJSONPointer, paths are JSON
+// Pointers instead: "/items/0/price", "/metrics/p99.latency", with "-" for
+// the flattened array: "/items/-/price". As in RFC 6901, a token of digits
+// selects an array element or an object member, depending on the value.
+//
+// # Entry points
+//
+// Convert, ConvertSources and ConvertFiles read JSON arrays or streams of
+// objects; ConvertRecords reads records decoded by a RecordSource, such as
+// those of the yaml and avro subpackages. A Converter validates Options once
+// for services running many conversions. ConvertTo writes the rows to a
+// RowWriter instead of formatting them, and ConvertAppend, ConvertSplit and
+// ConvertPartitioned write to existing files, size-limited parts and one
+// output per key.
+//
+// # Compatibility
+//
+// This package follows semantic versioning starting with v1.0.0 (see Version).
+// Within v1:
+//
+//   - Exported identifiers are not removed or renamed, and function
+//     signatures do not change.
+//   - New fields may be added to Options, Field and other configuration
+//     structs; their zero values keep the previous behavior. Use keyed struct
+//     literals so additions do not break your code.
+//   - Functionality that is superseded is marked with a "Deprecated:"
+//     paragraph in its documentation and keeps working, usually as a thin
+//     wrapper around its replacement, for the rest of v1.
+//   - The exact text of error messages, and the output for inputs that were
+//     previously rejected with an error, may change.
+//
+// The subpackages of json2csv are part of the same module and follow the
+// same rules. Deprecated so far are FormatUnixTimestamp (use
+// FormatUnixTimestampIn) and ItemsSummaryTransformer (use JoinArray).
+//
+// Breaking changes are only made in a new major version with its own import
+// path. Those made before v1 are listed in CHANGELOG.md: the non-flattening
+// conversion mode was removed, and the header row is now written by default.
+// Before v1 a zero Options wrote no header; code relying on that must set
+// Options.OmitHeader, as Options.AddHeader no longer has any effect.
+package json2csv
+
+// Version is the semantic version of this package.
+const Version = "1.0.0"
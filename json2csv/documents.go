@@ -0,0 +1,56 @@
+// json2csv/documents.go
+
+package json2csv
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// nextDocument starts the array following the one just read, with
+// Options.AllowMultipleDocuments, and reports whether it has an element.
+// Anything else after the array is skipped as trailing garbage, up to the
+// next line starting with '['.
+func (s *jsonStream) nextDocument() (bool, error) {
+	rest := bufio.NewReader(io.MultiReader(s.decoder.Buffered(), s.br))
+	offset := s.skipped + s.decoder.InputOffset()
+	n, err := skipToArray(rest)
+	if err == io.EOF {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("json2csv: failed to read next json document: %w", err)
+	}
+	s.r = rest
+	s.decoder = nil
+	s.skipped = offset + n
+	s.done = false
+	return s.next()
+}
+
+// skipToArray reads r up to the next '[' that is the first non-blank
+// character of r or of a line, and returns the number of bytes read.
+func skipToArray(r *bufio.Reader) (int64, error) {
+	var n int64
+	lineStart := true
+	for {
+		peek, err := r.Peek(1)
+		if err != nil {
+			return n, err
+		}
+		switch b := peek[0]; b {
+		case '[':
+			if lineStart {
+				return n, nil
+			}
+			lineStart = false
+		case '\n':
+			lineStart = true
+		case ' ', '\t', '\r':
+		default:
+			lineStart = false
+		}
+		r.ReadByte()
+		n++
+	}
+}
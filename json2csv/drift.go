@@ -0,0 +1,323 @@
+// json2csv/drift.go
+
+package json2csv
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+)
+
+// ExpectedSchema describes the records a conversion expects, as an early
+// warning of changes in an upstream API (see Options.ExpectedSchema).
+type ExpectedSchema struct {
+	// Paths maps the paths of the expected keys, in the syntax of
+	// Options.PathSyntax, to their type, e.g. "id": TypeInt and
+	// "items[*].price": TypeFloat. "[*]" stands for the elements of an array
+	// and "*" for any key of an object; the keys along a path are expected
+	// as well. Values are checked strictly (see Options.StrictTypes), but
+	// null is always accepted. The keys below a listed path are only
+	// checked if some of them are listed too, so that e.g. "meta": TypeAny
+	// accepts any object.
+	Paths map[string]FieldType
+
+	// NewKeys, MissingKeys and TypeChanges select what happens on a key
+	// that is not expected, an expected key absent from its object and a
+	// value of the wrong type. By default they are warnings.
+	NewKeys     DriftAction
+	MissingKeys DriftAction
+	TypeChanges DriftAction
+
+	// OnDrift, if set, is called with the first warning of each kind at
+	// each path.
+	OnDrift func(SchemaDrift)
+}
+
+// DriftAction selects what happens on a kind of SchemaDrift.
+type DriftAction int
+
+const (
+	// DriftWarn reports the drift in Report.SchemaDrift and to
+	// ExpectedSchema.OnDrift (the default).
+	DriftWarn DriftAction = iota
+
+	// DriftError fails the conversion with an error wrapping
+	// ErrSchemaDrift.
+	DriftError
+
+	// DriftIgnore ignores the drift.
+	DriftIgnore
+)
+
+// DriftKind is the kind of a SchemaDrift.
+type DriftKind int
+
+const (
+	// DriftNewKey is a key that the schema does not list.
+	DriftNewKey DriftKind = iota
+
+	// DriftMissingKey is an expected key absent from its object. Only the
+	// outermost absent key is reported.
+	DriftMissingKey
+
+	// DriftTypeChange is a value that is not of its expected type.
+	DriftTypeChange
+)
+
+var driftKindNames = map[DriftKind]string{
+	DriftNewKey:     "new key",
+	DriftMissingKey: "missing key",
+	DriftTypeChange: "type change",
+}
+
+// String returns the name of k, such as "new key".
+func (k DriftKind) String() string {
+	if name, ok := driftKindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("DriftKind(%d)", int(k))
+}
+
+// ErrSchemaDrift is wrapped by the error of a conversion failed by a
+// DriftError action.
+var ErrSchemaDrift = errors.New("json2csv: schema drift")
+
+// SchemaDrift is a difference between the input and an ExpectedSchema.
+type SchemaDrift struct {
+	Kind DriftKind
+
+	// Path is the path of the key in the default syntax, with "[*]" for
+	// array elements, such as "items[*].discount".
+	Path string
+
+	// Expected is the type of the path and Value the first value that is
+	// not of that type, for DriftTypeChange.
+	Expected FieldType
+	Value    interface{}
+
+	// Record is the one-based number of the first record with the drift,
+	// across sources, and Count the number of occurrences. Each key or
+	// value of an array element counts.
+	Record int
+	Count  int
+}
+
+// String describes the drift, e.g. `new key "items[*].discount"`.
+func (d SchemaDrift) String() string {
+	if d.Kind == DriftTypeChange {
+		return fmt.Sprintf("%v at %q: value %s is not of type %s", d.Kind, d.Path, describeValue(d.Value), d.Expected)
+	}
+	return fmt.Sprintf("%v %q", d.Kind, d.Path)
+}
+
+// schemaNode is the part of an ExpectedSchema below one key.
+type schemaNode struct {
+	listed   bool // The path of the node is in ExpectedSchema.Paths.
+	typ      FieldType
+	keys     map[string]*schemaNode
+	order    []string    // The keys, sorted
+	wildcard *schemaNode // Any key, or array elements
+}
+
+func (n *schemaNode) hasChildren() bool {
+	return len(n.keys) > 0 || n.wildcard != nil
+}
+
+// driftChecker compares the records of a conversion with an ExpectedSchema.
+type driftChecker struct {
+	schema ExpectedSchema
+	root   *schemaNode
+	items  *schemaNode      // The items of the flattened array, for Options.StreamItems
+	seen   map[driftKey]int // Index in Report.SchemaDrift, or -1
+
+	// streamedPath is the flattened array left out of streamed records,
+	// which is not missing.
+	streamedPath string
+
+	record int
+	report *Report // nil unless requested
+	err    error   // The first DriftError
+}
+
+// newDriftChecker compiles schema, whose paths are in the default syntax.
+func newDriftChecker(schema ExpectedSchema, flattenArrayPath string) (*driftChecker, error) {
+	d := &driftChecker{schema: schema, root: &schemaNode{}, seen: map[driftKey]int{}}
+	for _, path := range slices.Sorted(maps.Keys(schema.Paths)) {
+		segments, err := schemaSegments(path)
+		if err != nil {
+			return nil, err
+		}
+		n := d.root
+		for _, segment := range segments {
+			if segment.kind == segmentWildcard {
+				if n.wildcard == nil {
+					n.wildcard = &schemaNode{}
+				}
+				n = n.wildcard
+				continue
+			}
+			if n.keys == nil {
+				n.keys = map[string]*schemaNode{}
+			}
+			if n.keys[segment.name] == nil {
+				n.keys[segment.name] = &schemaNode{}
+				n.order = append(n.order, segment.name)
+			}
+			n = n.keys[segment.name]
+		}
+		n.listed, n.typ = true, schema.Paths[path]
+	}
+
+	if cp, err := compilePath(flattenArrayPath); err == nil && flattenArrayPath != "" {
+		d.items = d.root
+		for _, key := range cp.childKeys() {
+			if d.items = d.items.keys[key]; d.items == nil {
+				break
+			}
+		}
+		if d.items != nil {
+			d.items = d.items.wildcard
+		}
+		d.streamedPath = flattenArrayPath
+	}
+	return d, nil
+}
+
+type driftKey struct {
+	kind DriftKind
+	path string
+}
+
+// schemaSegments parses a path of an ExpectedSchema, which may only select
+// keys and all elements.
+func schemaSegments(path string) ([]pathSegment, error) {
+	if path == "" {
+		return nil, errors.New("json2csv: ExpectedSchema: empty path")
+	}
+	cp, err := compilePath(path)
+	if err != nil {
+		return nil, fmt.Errorf("json2csv: ExpectedSchema: %w", err)
+	}
+	for _, segment := range cp.segments {
+		if segment.recursive || segment.kind != segmentChild && segment.kind != segmentWildcard {
+			return nil, fmt.Errorf("json2csv: ExpectedSchema: path %q may only contain keys, \"*\" and \"[*]\"", path)
+		}
+	}
+	return cp.segments, nil
+}
+
+// forRun returns a copy of d for another conversion, with its own seen
+// drift, report and error. The schema nodes are shared: they are not
+// modified once built.
+func (d *driftChecker) forRun() *driftChecker {
+	run := *d
+	run.seen = map[driftKey]int{}
+	run.record, run.report, run.err = 0, nil, nil
+	return &run
+}
+
+// checkRecord compares record number recordNumber with the schema. streamed
+// reports whether its flattened array was left out (see checkItem).
+func (d *driftChecker) checkRecord(record map[string]interface{}, recordNumber int, streamed bool) error {
+	d.record = recordNumber
+	skip := ""
+	if streamed {
+		skip = d.streamedPath
+	}
+	d.walk(record, d.root, "", skip)
+	return d.err
+}
+
+// checkItem compares a streamed item of the flattened array with the
+// schema.
+func (d *driftChecker) checkItem(item interface{}) error {
+	if d.items == nil {
+		d.drift(SchemaDrift{Kind: DriftNewKey, Path: d.streamedPath})
+		return d.err
+	}
+	d.walk(item, d.items, d.streamedPath+"[*]", "")
+	return d.err
+}
+
+// walk compares value, found at path, with n. A key at path skip is never
+// missing.
+func (d *driftChecker) walk(value interface{}, n *schemaNode, path, skip string) {
+	if n.listed && value != nil {
+		if _, err := n.typ.check(value, true); err != nil {
+			d.drift(SchemaDrift{Kind: DriftTypeChange, Path: path, Expected: n.typ, Value: value})
+			return
+		}
+	}
+	if !n.hasChildren() {
+		return
+	}
+	switch value := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys) // Report drift in a stable order.
+		for _, key := range keys {
+			child := n.keys[key]
+			if child == nil {
+				child = n.wildcard
+			}
+			if child == nil {
+				d.drift(SchemaDrift{Kind: DriftNewKey, Path: childPath(path, key)})
+				continue
+			}
+			d.walk(value[key], child, childPath(path, key), skip)
+		}
+		for _, key := range n.order {
+			if _, ok := value[key]; !ok && childPath(path, key) != skip {
+				d.drift(SchemaDrift{Kind: DriftMissingKey, Path: childPath(path, key)})
+			}
+		}
+	case []interface{}:
+		if n.wildcard != nil {
+			for _, element := range value {
+				d.walk(element, n.wildcard, path+"[*]", skip)
+			}
+		}
+	}
+}
+
+// drift handles a difference as selected by the schema.
+func (d *driftChecker) drift(drift SchemaDrift) {
+	action := d.schema.TypeChanges
+	switch drift.Kind {
+	case DriftNewKey:
+		action = d.schema.NewKeys
+	case DriftMissingKey:
+		action = d.schema.MissingKeys
+	}
+	switch {
+	case action == DriftIgnore:
+		return
+	case action == DriftError:
+		if d.err == nil {
+			d.err = fmt.Errorf("%w: %s", ErrSchemaDrift, drift)
+		}
+		return
+	}
+
+	key := driftKey{drift.Kind, drift.Path}
+	if index, seen := d.seen[key]; seen {
+		if index >= 0 {
+			d.report.SchemaDrift[index].Count++
+		}
+		return
+	}
+	drift.Record, drift.Count = d.record, 1
+	if d.schema.OnDrift != nil {
+		d.schema.OnDrift(drift)
+	}
+	d.seen[key] = -1
+	if d.report != nil {
+		d.seen[key] = len(d.report.SchemaDrift)
+		d.report.SchemaDrift = append(d.report.SchemaDrift, drift)
+	}
+}
@@ -0,0 +1,76 @@
+// json2csv/duplicates.go
+
+package json2csv
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DuplicateHeaders selects how a conversion handles header cells that occur
+// more than once, which many loaders (pandas, BigQuery) silently mishandle.
+// Empty headers are never considered duplicates.
+type DuplicateHeaders int
+
+const (
+	// DuplicateHeadersAllow writes duplicate headers unchanged (the default).
+	DuplicateHeadersAllow DuplicateHeaders = iota
+
+	// DuplicateHeadersError rejects the Options with an error listing the
+	// duplicates.
+	DuplicateHeadersError
+
+	// DuplicateHeadersSuffix renames the second and later occurrences by
+	// appending "_2", "_3", etc.: "Name", "Name_2".
+	DuplicateHeadersSuffix
+)
+
+// apply checks header for duplicates, returning the header to write.
+func (mode DuplicateHeaders) apply(header []string) ([]string, error) {
+	if mode == DuplicateHeadersAllow {
+		return header, nil
+	}
+
+	seen := make(map[string]int, len(header))
+	for _, h := range header {
+		if h != "" {
+			seen[h]++
+		}
+	}
+	var duplicates []string
+	for _, h := range header {
+		if seen[h] > 1 {
+			duplicates = append(duplicates, fmt.Sprintf("%q (%d times)", h, seen[h]))
+			seen[h] = 0 // Report each header once.
+		}
+	}
+	if len(duplicates) == 0 {
+		return header, nil
+	}
+	if mode == DuplicateHeadersError {
+		return nil, fmt.Errorf("json2csv: duplicate headers: %s", strings.Join(duplicates, ", "))
+	}
+
+	used := make(map[string]bool, len(header))
+	for _, h := range header {
+		used[h] = true
+	}
+	result := make([]string, len(header))
+	first := make(map[string]bool, len(header))
+	for i, h := range header {
+		if h == "" || !first[h] {
+			first[h] = true
+			result[i] = h
+			continue
+		}
+		for n := 2; ; n++ {
+			candidate := fmt.Sprintf("%s_%d", h, n)
+			if !used[candidate] {
+				used[candidate] = true
+				result[i] = candidate
+				break
+			}
+		}
+	}
+	return result, nil
+}
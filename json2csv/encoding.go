@@ -0,0 +1,70 @@
+// json2csv/encoding.go
+
+package json2csv
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// InputEncoding selects the character encoding of JSON input. A UTF-8 byte
+// order mark is stripped in any case.
+type InputEncoding int
+
+const (
+	// InputEncodingUTF8 reads the input as UTF-8 (the default). Input that
+	// looks like UTF-16, e.g. from Windows tools, fails with an error
+	// suggesting InputEncodingAuto.
+	InputEncodingUTF8 InputEncoding = iota
+
+	// InputEncodingAuto detects UTF-16 input from its byte order mark or,
+	// without one, from the zero bytes of its first character (JSON text
+	// starts with ASCII), and transcodes it to UTF-8. Other input is read
+	// as UTF-8.
+	InputEncodingAuto
+
+	// InputEncodingUTF16LE and InputEncodingUTF16BE transcode UTF-16 input
+	// of the given byte order, unless a byte order mark says otherwise.
+	InputEncodingUTF16LE
+	InputEncodingUTF16BE
+)
+
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+// decodeInput returns r as UTF-8 without a byte order mark, as configured
+// by encoding. Byte offsets in errors then refer to the UTF-8 text.
+func decodeInput(r io.Reader, encoding InputEncoding) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(3)
+	endianness, utf16 := unicode.LittleEndian, true
+	switch {
+	case bytes.HasPrefix(head, utf8BOM):
+		br.Discard(len(utf8BOM))
+		utf16 = false
+	case bytes.HasPrefix(head, []byte{0xff, 0xfe}) || len(head) >= 2 && head[0] != 0 && head[1] == 0:
+	case bytes.HasPrefix(head, []byte{0xfe, 0xff}) || len(head) >= 2 && head[0] == 0 && head[1] != 0:
+		endianness = unicode.BigEndian
+	default:
+		utf16 = false
+	}
+
+	switch encoding {
+	case InputEncodingUTF16LE:
+		endianness, utf16 = unicode.LittleEndian, true
+	case InputEncodingUTF16BE:
+		endianness, utf16 = unicode.BigEndian, true
+	case InputEncodingUTF8:
+		if utf16 {
+			return nil, errors.New("json2csv: input looks UTF-16 encoded; set Options.InputEncoding to InputEncodingAuto to transcode it")
+		}
+	}
+	if !utf16 {
+		return br, nil
+	}
+	return transform.NewReader(br, unicode.UTF16(endianness, unicode.UseBOM).NewDecoder()), nil
+}
@@ -0,0 +1,96 @@
+// json2csv/errors.go
+
+package json2csv
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConvertError locates a conversion failure in the input. Errors occurring
+// while converting a named source or a record, such as invalid JSON or a
+// failing transformer, are returned as a *ConvertError wrapping the cause;
+// use errors.As to retrieve the position.
+type ConvertError struct {
+	// Source is the Name of the Source being converted, if any.
+	Source string
+
+	// Record is the zero-based index of the failing record within the
+	// source, or -1 if the failure is not in a record (e.g. a missing "]").
+	Record int
+
+	// Item is the zero-based index of the item within the flattened array,
+	// or -1 if the failure is not in an item.
+	Item int
+
+	// Field is the JSONPath of the field being converted, or its CSVHeader
+	// for fields without a path, if any.
+	Field string
+
+	// Offset is the approximate byte offset of the record in the source
+	// input, or -1 if unknown (e.g. for records from a RecordSource).
+	Offset int64
+
+	Err error
+}
+
+func (e *ConvertError) Error() string {
+	var where []string
+	if e.Source != "" {
+		where = append(where, fmt.Sprintf("source %q", e.Source))
+	}
+	if e.Record >= 0 {
+		where = append(where, fmt.Sprintf("record %d", e.Record))
+	}
+	if e.Item >= 0 {
+		where = append(where, fmt.Sprintf("item %d", e.Item))
+	}
+	if e.Field != "" {
+		where = append(where, fmt.Sprintf("field %q", e.Field))
+	}
+	if e.Offset >= 0 {
+		where = append(where, fmt.Sprintf("byte offset %d", e.Offset))
+	}
+	if len(where) == 0 {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%v (%s)", e.Err, strings.Join(where, ", "))
+}
+
+func (e *ConvertError) Unwrap() error {
+	return e.Err
+}
+
+// startRecord marks the start of a record at the byte offset of the
+// current source (-1 if unknown) for locate.
+func (c *conversion) startRecord(offset int64) {
+	c.inRecord = true
+	c.recordOffset = offset
+	c.itemIndex = -1
+	c.fieldIndex = -1
+}
+
+// endRecord marks the end of the current record.
+func (c *conversion) endRecord() {
+	c.inRecord = false
+	c.recordIndex++
+}
+
+// locate wraps err, which occurred while converting the current source, in
+// a ConvertError giving its position. Errors outside of records of unnamed
+// sources are returned unchanged.
+func (c *conversion) locate(err error) error {
+	if err == nil || (!c.inRecord && c.sourceName == "") {
+		return err
+	}
+	located := &ConvertError{Source: c.sourceName, Record: -1, Item: -1, Offset: -1, Err: err}
+	if c.inRecord {
+		located.Record = c.recordIndex
+		located.Item = c.itemIndex
+		located.Offset = c.recordOffset
+		if c.fieldIndex >= 0 {
+			located.Field = c.fields[c.fieldIndex].name()
+		}
+	}
+	return located
+}
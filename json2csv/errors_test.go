@@ -0,0 +1,44 @@
+// json2csv/errors_test.go
+
+package json2csv_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pradnyoday/go-json2csv/json2csv"
+)
+
+func TestConvertErrorLocatesField(t *testing.T) {
+	failing := func(value interface{}, record map[string]interface{}) (interface{}, error) {
+		return nil, errors.New("boom")
+	}
+	tests := []struct {
+		name  string
+		field json2csv.Field
+		want  string
+	}{
+		{"path", json2csv.Field{JSONPath: "items[*].sku", CSVHeader: "sku", Transformer: failing}, "items[*].sku"},
+		{"computed", json2csv.Field{CSVHeader: "total", Compute: func(*json2csv.TransformContext) (interface{}, error) {
+			return nil, errors.New("boom")
+		}}, "total"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := json2csv.Options{Fields: []json2csv.Field{{JSONPath: "items[*].id", CSVHeader: "id"}, test.field}}
+			input := `[{"items":[{"id":1,"sku":"a"}]}]`
+			err := json2csv.ConvertSources([]json2csv.Source{{Name: "in.json", Reader: strings.NewReader(input)}}, &strings.Builder{}, options)
+			var convertErr *json2csv.ConvertError
+			if !errors.As(err, &convertErr) {
+				t.Fatalf("got %v, want a ConvertError", err)
+			}
+			if convertErr.Field != test.want {
+				t.Errorf("Field = %q, want %q", convertErr.Field, test.want)
+			}
+			if want := `field "` + test.want + `"`; !strings.Contains(err.Error(), "("+`source "in.json", record 0, item 0, `+want) {
+				t.Errorf("error %q does not locate %s", err, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,376 @@
+// json2csv/evolve.go
+
+package json2csv
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SchemaEvolution selects how ConvertAppendEvolving handles columns that are
+// configured in Options.Fields but missing from the existing file.
+type SchemaEvolution int
+
+const (
+	// EvolveRewrite rewrites the file with the extended header, padding the
+	// existing rows with empty cells for the new columns. The rewrite goes to
+	// a temporary file that replaces the original only on success.
+	EvolveRewrite SchemaEvolution = iota
+
+	// EvolveSidecar leaves the existing rows and header untouched and appends
+	// the new rows with the extended column set. The change is recorded as a
+	// JSON line (a SchemaChange) in the sidecar file path + ".schema.jsonl",
+	// so readers know which header applies from which row on. Later appends
+	// continue with the header of the last recorded change.
+	EvolveSidecar
+)
+
+// SchemaChange describes how the columns of an appended file changed.
+type SchemaChange struct {
+	// Time is when the change was made.
+	Time time.Time `json:"time"`
+
+	// FirstRow is the zero-based index of the first data row written with
+	// NewHeader (the number of data rows already in the file).
+	FirstRow int `json:"first_row"`
+
+	OldHeader []string `json:"old_header"`
+	NewHeader []string `json:"new_header"`
+
+	// Added lists the new columns, appended after the existing ones.
+	Added []string `json:"added"`
+
+	// Missing lists existing columns that are no longer configured; they
+	// are written as empty cells.
+	Missing []string `json:"missing,omitempty"`
+}
+
+// ConvertAppendEvolving converts r like Convert and appends the rows to the
+// CSV file at path, for incremental exports into a file whose schema grows
+// over time. Columns are matched by header name: rows are written in the
+// file's column order, configured columns the file lacks are added after the
+// existing ones as selected by evolution, and existing columns that are no
+// longer configured are left empty. onChange, if not nil, is called before
+// the file is changed; returning an error aborts the conversion.
+//
+// If path does not exist it is created with a header. Only FormatCSV is
+// supported; WrapOutput must not be set, and the lossless profile cannot be
+// combined with EvolveRewrite.
+func ConvertAppendEvolving(path string, r io.Reader, options Options, evolution SchemaEvolution, onChange func(SchemaChange) error) error {
+	if options.Format != FormatCSV || options.WrapOutput != nil {
+		return errors.New("json2csv: appending requires FormatCSV and no WrapOutput")
+	}
+	c, err := newConversion(nil, options)
+	if err != nil {
+		return err
+	}
+	sources, cleanup, err := c.prepareSources([]Source{{Reader: r}})
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	r = sources[0].Reader
+
+	oldHeader, rowCount, err := readCSVShape(path, options)
+	if errors.Is(err, os.ErrNotExist) {
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("json2csv: failed to create output file: %w", err)
+		}
+		c.options.OmitHeader = false
+		c.out = NewRowWriter(f, options)
+		return closeFile(f, c.run(func() error { return c.convertSource(Source{Reader: r}) }))
+	}
+	if err != nil {
+		return err
+	}
+
+	if evolution == EvolveSidecar {
+		// Earlier changes recorded in the sidecar extend the file header.
+		if oldHeader, err = currentSidecarHeader(path+".schema.jsonl", oldHeader); err != nil {
+			return err
+		}
+	}
+
+	newHeader, added, missing := unionHeader(oldHeader, c.header)
+	fill := ""
+	if options.Lossless {
+		fill = LosslessNull
+	}
+	c.options.OmitHeader = true // Existing files already have one.
+
+	if len(added) == 0 {
+		f, err := openForAppend(path)
+		if err != nil {
+			return err
+		}
+		c.out = newReorderRowWriter(NewRowWriter(f, options), c.header, newHeader, fill)
+		return closeFile(f, c.run(func() error { return c.convertSource(Source{Reader: r}) }))
+	}
+
+	change := SchemaChange{
+		Time:      time.Now().UTC(),
+		FirstRow:  rowCount,
+		OldHeader: oldHeader,
+		NewHeader: newHeader,
+		Added:     added,
+		Missing:   missing,
+	}
+	if onChange != nil {
+		if err := onChange(change); err != nil {
+			return err
+		}
+	}
+
+	switch evolution {
+	case EvolveSidecar:
+		if err := appendSchemaChange(path+".schema.jsonl", change); err != nil {
+			return err
+		}
+		f, err := openForAppend(path)
+		if err != nil {
+			return err
+		}
+		c.out = newReorderRowWriter(NewRowWriter(f, options), c.header, newHeader, fill)
+		return closeFile(f, c.run(func() error { return c.convertSource(Source{Reader: r}) }))
+
+	case EvolveRewrite:
+		if options.Lossless {
+			return errors.New("json2csv: EvolveRewrite cannot be used with the lossless profile")
+		}
+		return rewriteExtended(path, newHeader, options, func(w io.Writer) error {
+			c.out = newReorderRowWriter(NewRowWriter(w, options), c.header, newHeader, fill)
+			return c.run(func() error { return c.convertSource(Source{Reader: r}) })
+		})
+	}
+	return fmt.Errorf("json2csv: unknown schema evolution mode %d", evolution)
+}
+
+// readCSVShape returns the header of the CSV file at path, after the lines
+// of options.HeaderComment, and the number of data rows after it.
+func readCSVShape(path string, options Options) ([]string, int, error) {
+	f, err := openCSVFile(path, options)
+	if err == io.EOF {
+		return nil, 0, fmt.Errorf("json2csv: existing file %q has no header", path)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	f.reader.ReuseRecord = true
+	rows := 0
+	for {
+		if _, err := f.reader.Read(); err == io.EOF {
+			return f.header, rows, nil
+		} else if err != nil {
+			return nil, 0, fmt.Errorf("json2csv: failed to read existing file: %w", err)
+		}
+		rows++
+	}
+}
+
+// unionHeader returns oldHeader followed by the columns of header it lacks,
+// along with those added columns and the old columns missing from header.
+func unionHeader(oldHeader, header []string) (union, added, missing []string) {
+	inOld := make(map[string]bool, len(oldHeader))
+	for _, h := range oldHeader {
+		inOld[h] = true
+	}
+	inNew := make(map[string]bool, len(header))
+	union = append([]string(nil), oldHeader...)
+	for _, h := range header {
+		inNew[h] = true
+		if !inOld[h] {
+			inOld[h] = true
+			union = append(union, h)
+			added = append(added, h)
+		}
+	}
+	for _, h := range oldHeader {
+		if !inNew[h] {
+			missing = append(missing, h)
+		}
+	}
+	return union, added, missing
+}
+
+// reorderRowWriter writes rows with columns named by from in the column
+// order of to, filling columns that from lacks with fill.
+type reorderRowWriter struct {
+	RowWriter
+	source []int // source[i] is the index in from of column i of to, or -1
+	fill   string
+	buf    []string
+}
+
+func newReorderRowWriter(out RowWriter, from, to []string, fill string) *reorderRowWriter {
+	index := make(map[string]int, len(from))
+	for i := len(from) - 1; i >= 0; i-- {
+		index[from[i]] = i // The first column wins for duplicate names.
+	}
+	source := make([]int, len(to))
+	for i, h := range to {
+		if j, ok := index[h]; ok {
+			source[i] = j
+		} else {
+			source[i] = -1
+		}
+	}
+	return &reorderRowWriter{RowWriter: out, source: source, fill: fill, buf: make([]string, len(to))}
+}
+
+func (r *reorderRowWriter) WriteHeader(header []string) error {
+	return r.WriteRow(header)
+}
+
+func (r *reorderRowWriter) WriteRow(row []string) error {
+	for i, j := range r.source {
+		if j >= 0 && j < len(row) {
+			r.buf[i] = row[j]
+		} else {
+			r.buf[i] = r.fill
+		}
+	}
+	return r.RowWriter.WriteRow(r.buf)
+}
+
+// openForAppend opens the file at path for appending rows, first terminating
+// its last line if it lacks a newline.
+func openForAppend(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND, 0)
+	if err != nil {
+		return nil, fmt.Errorf("json2csv: failed to open output file: %w", err)
+	}
+	info, err := f.Stat()
+	if err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err = f.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			_, err = f.Write([]byte("\n"))
+		}
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("json2csv: failed to prepare output file for appending: %w", err)
+	}
+	return f, nil
+}
+
+// closeFile closes f and returns err, or the close error if err is nil.
+func closeFile(f *os.File, err error) error {
+	if closeErr := f.Close(); closeErr != nil && err == nil {
+		err = fmt.Errorf("json2csv: failed to close output file: %w", closeErr)
+	}
+	return err
+}
+
+// appendSchemaChange records change as a JSON line in the sidecar file.
+func appendSchemaChange(sidecar string, change SchemaChange) error {
+	line, err := json.Marshal(change)
+	if err != nil {
+		return fmt.Errorf("json2csv: failed to encode schema change: %w", err)
+	}
+	f, err := os.OpenFile(sidecar, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("json2csv: failed to open schema sidecar: %w", err)
+	}
+	_, err = f.Write(append(line, '\n'))
+	if err != nil {
+		err = fmt.Errorf("json2csv: failed to write schema sidecar: %w", err)
+	}
+	return closeFile(f, err)
+}
+
+// currentSidecarHeader returns the NewHeader of the last change recorded in
+// sidecar, or header if there is none.
+func currentSidecarHeader(sidecar string, header []string) ([]string, error) {
+	f, err := os.Open(sidecar)
+	if errors.Is(err, os.ErrNotExist) {
+		return header, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("json2csv: failed to open schema sidecar: %w", err)
+	}
+	defer f.Close()
+
+	decoder := json.NewDecoder(f)
+	for {
+		var change SchemaChange
+		if err := decoder.Decode(&change); err == io.EOF {
+			return header, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("json2csv: failed to read schema sidecar: %w", err)
+		}
+		header = change.NewHeader
+	}
+}
+
+// rewriteExtended copies the CSV file at path to a temporary file with
+// header as its header row, padding the existing rows to its width, calls
+// appendRows to write the new rows and replaces path with the result.
+func rewriteExtended(path string, header []string, options Options, appendRows func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("json2csv: failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op after a successful rename.
+
+	err = copyPadded(path, tmp, header, options)
+	if err == nil {
+		err = appendRows(tmp)
+	}
+	if err = closeFile(tmp, err); err != nil {
+		return err
+	}
+	if info, err := os.Stat(path); err == nil {
+		os.Chmod(tmp.Name(), info.Mode().Perm())
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("json2csv: failed to replace output file: %w", err)
+	}
+	return nil
+}
+
+// copyPadded writes the comment lines, header and the data rows of the CSV
+// file at path to w, padding every row with empty cells to the width of
+// header.
+func copyPadded(path string, w io.Writer, header []string, options Options) error {
+	f, err := openCSVFile(path, options)
+	if err == io.EOF {
+		return fmt.Errorf("json2csv: existing file %q has no header", path)
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := w.Write(f.preamble); err != nil {
+		return fmt.Errorf("json2csv: failed to write header comment: %w", err)
+	}
+	writer := options.newCSVWriter(w)
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("json2csv: failed to write header: %w", err)
+	}
+	for {
+		row, err := f.reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("json2csv: failed to read existing file: %w", err)
+		}
+		for len(row) < len(header) {
+			row = append(row, "")
+		}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("json2csv: failed to write csv row: %w", err)
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,381 @@
+// json2csv/expand.go
+
+package json2csv
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// KeyExpansion turns a Field whose JSONPath ends in ".*" into one column per
+// key of the object at the path before ".*", for payloads with semi-dynamic
+// attribute maps. For example, with
+//
+//	{JSONPath: "metrics.*", CSVHeader: "metric_", Expand: &KeyExpansion{}}
+//
+// the object {"metrics": {"cpu": 0.5, "mem": 12}} yields the columns
+// "metric_cpu" and "metric_mem": each header is the Field's CSVHeader
+// followed by the key, and the Field's transformers apply to every column.
+// Records lacking a key have an empty cell in its column.
+//
+// Unless Keys is set, the keys are discovered by reading the whole input
+// before the header is written: every source is read twice (Convert and
+// ConvertSources spool their input to a temporary file for that, while
+// ConvertFiles opens each file twice). Options.DiscoveryRecords bounds the
+// discovery to the first records instead, at the cost of missing columns
+// for keys that only appear later. Discovered keys can be filtered with
+// Options.IncludePaths and Options.ExcludePaths.
+type KeyExpansion struct {
+	// Keys, if set, are the keys to create columns for, in order. The input
+	// is then only read once and other keys are ignored.
+	Keys []string
+
+	// Order is the order of discovered keys. Defaults to KeysSorted.
+	Order KeyOrder
+
+	// First lists discovered keys whose columns come before the others, in
+	// this order, e.g. []string{"id"}. Keys that are not discovered are
+	// ignored.
+	First []string
+
+	// MaxKeys, if positive, makes the conversion fail when more keys are
+	// discovered, guarding against maps with unbounded keys such as IDs.
+	MaxKeys int
+}
+
+// KeyOrder is the column order of discovered keys.
+type KeyOrder int
+
+const (
+	// KeysSorted orders the columns by key.
+	KeysSorted KeyOrder = iota
+
+	// KeysFirstSeen orders the columns by first occurrence in the input.
+	KeysFirstSeen
+)
+
+// expandFields returns fields with each ArrayPivot field replaced by its
+// columns and each key expansion replaced by a Field per key, using discovered[i] as the keys of fields[i] unless it lists its
+// Keys. pending reports whether keys still need to be discovered; those
+// expansions produce no columns yet.
+func expandFields(fields []Field, discovered map[int][]string) (expanded []Field, pending bool, err error) {
+	for i, field := range fields {
+		if field.ArrayMode == ArrayPivot {
+			if strings.Count(field.JSONPath, "[*]") != 1 {
+				return nil, false, fmt.Errorf("json2csv: field %q: ArrayPivot requires a path with exactly one \"[*]\"", field.JSONPath)
+			}
+			expanded = append(expanded, pivotColumns(field)...)
+			continue
+		}
+		if field.Expand == nil {
+			expanded = append(expanded, field)
+			continue
+		}
+		base, ok := strings.CutSuffix(field.JSONPath, ".*")
+		if !ok || base == "" {
+			return nil, false, fmt.Errorf("json2csv: field %q: key expansion requires a path ending in \".*\"", field.JSONPath)
+		}
+
+		keys := field.Expand.Keys
+		if len(keys) == 0 {
+			if discovered == nil {
+				pending = true
+				continue
+			}
+			keys = discovered[i]
+		}
+		for _, key := range keys {
+			column := field
+			column.JSONPath = base + "[" + quoteKey(key) + "]"
+			column.CSVHeader = field.CSVHeader + key
+			column.Expand = nil
+			expanded = append(expanded, column)
+		}
+	}
+	return expanded, pending, nil
+}
+
+// quoteKey quotes key for use in a bracket path segment.
+func quoteKey(key string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(key) + "'"
+}
+
+// keyCollector gathers the keys of one expansion during discovery.
+type keyCollector struct {
+	base      string // JSONPath without ".*"
+	expansion *KeyExpansion
+	include   []string // Options.IncludePaths
+	exclude   []string // Options.ExcludePaths
+	seen      map[string]bool
+	keys      []string
+}
+
+func (kc *keyCollector) add(value interface{}, field string) error {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil // Null, missing or not an object: no keys.
+	}
+	var fresh []string
+	for key := range m {
+		if !kc.seen[key] {
+			kc.seen[key] = true
+			if matchPaths(strings.ReplaceAll(kc.base, "[*]", "")+"."+key, kc.include, kc.exclude) {
+				fresh = append(fresh, key)
+			}
+		}
+	}
+	sort.Strings(fresh) // Keys first seen in the same object are sorted.
+	kc.keys = append(kc.keys, fresh...)
+	if max := kc.expansion.MaxKeys; max > 0 && len(kc.keys) > max {
+		return fmt.Errorf("json2csv: field %q: more than %d keys to expand", field, max)
+	}
+	return nil
+}
+
+// ordered returns the discovered keys in the order of the expansion.
+func (kc *keyCollector) ordered() []string {
+	if kc.expansion.Order == KeysSorted {
+		sort.Strings(kc.keys)
+	}
+	if len(kc.expansion.First) == 0 {
+		return kc.keys
+	}
+	keys := make([]string, 0, len(kc.keys))
+	first := map[string]bool{}
+	for _, key := range kc.expansion.First {
+		if !first[key] && slices.Contains(kc.keys, key) {
+			keys = append(keys, key)
+			first[key] = true
+		}
+	}
+	for _, key := range kc.keys {
+		if !first[key] {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// matchPaths reports whether the discovered column path, such as
+// "items.metrics.cpu", matches one of the include patterns (if any) and
+// none of the exclude patterns. Patterns are path.Match patterns.
+func matchPaths(columnPath string, include, exclude []string) bool {
+	for _, pattern := range exclude {
+		if matched, _ := path.Match(pattern, columnPath); matched {
+			return false
+		}
+	}
+	if len(include) == 0 {
+		return true
+	}
+	for _, pattern := range include {
+		if matched, _ := path.Match(pattern, columnPath); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// errDiscoveryDone stops the discovery of keys after
+// Options.DiscoveryRecords records.
+var errDiscoveryDone = errors.New("json2csv: key discovery done")
+
+// collectKeys discovers the keys of all pending key expansions by reading
+// every source passed to fn by forEachSource, and completes the columns.
+// If limit is positive, fn returns errDiscoveryDone once limit records have
+// been read, and the collectors are kept to report later keys.
+func (c *conversion) collectKeys(forEachSource func(fn func(Source) error) error, limit int) error {
+	collectors := map[int]*keyCollector{}
+	for i, field := range c.options.Fields {
+		if field.Expand != nil && len(field.Expand.Keys) == 0 {
+			collectors[i] = &keyCollector{
+				base:      strings.TrimSuffix(field.JSONPath, ".*"),
+				expansion: field.Expand,
+				include:   c.options.IncludePaths,
+				exclude:   c.options.ExcludePaths,
+				seen:      map[string]bool{},
+			}
+		}
+	}
+
+	records, stopped := 0, false
+	err := forEachSource(func(source Source) error {
+		if limit > 0 && records >= limit {
+			stopped = true
+			return errDiscoveryDone
+		}
+		c.sourceName = source.Name
+		c.recordIndex = 0
+		c.inRecord = false
+		err := c.decodeRecords(source.Reader, func(record map[string]interface{}, items *itemSpool) error {
+			if limit > 0 && records >= limit {
+				stopped = true
+				return errDiscoveryDone
+			}
+			records++
+			if keep, err := c.keepRow(false, record, nil); err != nil || !keep {
+				if err == nil {
+					c.endRecord()
+				}
+				return err
+			}
+			collectItem := func(item interface{}) error {
+				if keep, err := c.keepRow(true, record, item); err != nil || !keep {
+					return err
+				}
+				for i, kc := range collectors {
+					value, err := c.resolvePath(kc.base, record, item)
+					if err != nil {
+						return err
+					}
+					if err := kc.add(value, c.options.Fields[i].JSONPath); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+			count, err := c.eachItem(record, items, collectItem)
+			if err == nil && count == 0 && c.options.EmptyArrayBehavior == EmptyArrayEmitParent {
+				err = collectItem(nil)
+			}
+			if err == nil {
+				c.endRecord()
+			}
+			return err
+		})
+		if errors.Is(err, errDiscoveryDone) {
+			return err // Not located: the record was not read.
+		}
+		return c.locate(err)
+	})
+	if err != nil && !errors.Is(err, errDiscoveryDone) {
+		return err
+	}
+	if stopped {
+		c.lateKeys = collectors
+	}
+
+	discovered := map[int][]string{}
+	for i, kc := range collectors {
+		discovered[i] = kc.ordered()
+	}
+	fields, _, err := expandFields(c.options.Fields, discovered)
+	if err != nil {
+		return err
+	}
+	c.discoverKeys = false
+	return c.setFields(fields)
+}
+
+// reportLateKeys adds the keys of key expansions in the row that discovery
+// did not see to Report.LateKeys.
+func (c *conversion) reportLateKeys(originalRecord map[string]interface{}, item interface{}) error {
+	for i := range c.options.Fields {
+		kc, ok := c.lateKeys[i]
+		if !ok {
+			continue
+		}
+		value, err := c.resolvePath(kc.base, originalRecord, item)
+		if err != nil {
+			return err
+		}
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var fresh []string
+		for key := range m {
+			if kc.seen[key] {
+				continue
+			}
+			kc.seen[key] = true
+			if columnPath := strings.ReplaceAll(kc.base, "[*]", "") + "." + key; matchPaths(columnPath, kc.include, kc.exclude) {
+				fresh = append(fresh, columnPath)
+			}
+		}
+		sort.Strings(fresh) // Keys first seen in the same object are sorted.
+		c.report.LateKeys = append(c.report.LateKeys, fresh...)
+	}
+	return nil
+}
+
+// prepareSources returns sources ready to be converted. If keys of
+// expansions must be discovered, every source is read once to collect them
+// while being spooled to a temporary file, and the returned sources read
+// those files instead; cleanup removes them.
+func (c *conversion) prepareSources(sources []Source) (prepared []Source, cleanup func(), err error) {
+	if !c.discoverKeys {
+		return sources, func() {}, nil
+	}
+	return c.spoolSources(func(fn func(Source) error) error {
+		for _, source := range sources {
+			if err := fn(source); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, c.options.DiscoveryRecords)
+}
+
+// spoolSources is prepareSources for the sources passed to fn by
+// forEachSource. Unless limit is positive (see collectKeys), each source
+// must have been read when fn returns; otherwise the sources after the
+// first limit records are returned as they are, to be read later.
+func (c *conversion) spoolSources(forEachSource func(fn func(Source) error) error, limit int) (prepared []Source, cleanup func(), err error) {
+	var files []*os.File
+	cleanup = func() {
+		for _, f := range files {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}
+	done := false
+	err = c.collectKeys(func(fn func(Source) error) error {
+		return forEachSource(func(source Source) error {
+			if done {
+				prepared = append(prepared, source)
+				return nil
+			}
+			f, err := os.CreateTemp("", "json2csv-*.json")
+			if err != nil {
+				return fmt.Errorf("json2csv: failed to create temporary file: %w", err)
+			}
+			files = append(files, f)
+			// Failing sources are skipped silently here and reported when
+			// they fail again during the conversion.
+			err = fn(Source{Name: source.Name, Reader: io.TeeReader(source.Reader, f)})
+			if errors.Is(err, errDiscoveryDone) {
+				// Replay what discovery read, then stream the rest.
+				done = true
+				if _, err := f.Seek(0, io.SeekStart); err != nil {
+					return fmt.Errorf("json2csv: failed to spool input: %w", err)
+				}
+				prepared = append(prepared, Source{Name: source.Name, Reader: io.MultiReader(f, source.Reader)})
+				return nil
+			}
+			if err != nil && c.options.ErrorPolicy != ErrorPolicySkipSource {
+				return err
+			}
+			// Keep anything after the array, so the second pass sees the same input.
+			if _, err := io.Copy(f, source.Reader); err != nil {
+				return fmt.Errorf("json2csv: failed to spool input: %w", err)
+			}
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return fmt.Errorf("json2csv: failed to spool input: %w", err)
+			}
+			prepared = append(prepared, Source{Name: source.Name, Reader: f})
+			return nil
+		})
+	}, limit)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return prepared, cleanup, nil
+}
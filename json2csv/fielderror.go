@@ -0,0 +1,117 @@
+// json2csv/fielderror.go
+
+package json2csv
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// FieldErrorPolicy selects how a conversion handles a value that a field's
+// transformers fail on, or that does not fit the field's Type (see
+// Field.OnError).
+type FieldErrorPolicy int
+
+const (
+	// FieldErrorPropagate fails the conversion.
+	FieldErrorPropagate FieldErrorPolicy = iota
+
+	// FieldErrorEmptyCell writes the cell as null (an empty cell).
+	FieldErrorEmptyCell
+
+	// FieldErrorUseDefault writes Field.Default instead of the value.
+	FieldErrorUseDefault
+
+	// FieldErrorSkipRow drops the row.
+	FieldErrorSkipRow
+)
+
+var fieldErrorPolicyNames = map[FieldErrorPolicy]string{
+	FieldErrorPropagate:  "propagate",
+	FieldErrorEmptyCell:  "empty",
+	FieldErrorUseDefault: "default",
+	FieldErrorSkipRow:    "skip_row",
+}
+
+// String returns the name of p, as accepted by ParseFieldErrorPolicy.
+func (p FieldErrorPolicy) String() string {
+	if name, ok := fieldErrorPolicyNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("FieldErrorPolicy(%d)", int(p))
+}
+
+// ParseFieldErrorPolicy returns the FieldErrorPolicy named name
+// ("propagate", "empty", "default" or "skip_row"). The empty string is
+// FieldErrorPropagate.
+func ParseFieldErrorPolicy(name string) (FieldErrorPolicy, error) {
+	if name == "" {
+		return FieldErrorPropagate, nil
+	}
+	for p, policyName := range fieldErrorPolicyNames {
+		if policyName == name {
+			return p, nil
+		}
+	}
+	return FieldErrorPropagate, fmt.Errorf("json2csv: unknown field error policy %q", name)
+}
+
+// value returns value after the field's transformers, type check and
+// Required check. With preserveNumbers, json.Number values that pass the
+// type check are kept as they are.
+func (f Field) value(value interface{}, ctx *TransformContext, strict, preserveNumbers bool) (interface{}, error) {
+	if f.Compute != nil {
+		var err error
+		if value, err = f.Compute(ctx); err != nil {
+			return nil, fmt.Errorf("json2csv: failed to compute field %q: %w", f.name(), err)
+		}
+	}
+	if f.Query != nil {
+		var err error
+		if value, err = f.Query.Evaluate(ctx.Record); err != nil {
+			return nil, fmt.Errorf("json2csv: failed to evaluate query of field %q: %w", f.name(), err)
+		}
+	}
+	result, err := f.transform(value, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("json2csv: failed to transform field %q: %w", f.name(), err)
+	}
+	if result == nil && f.Required {
+		return nil, fmt.Errorf("json2csv: field %q: required value is missing", f.name())
+	}
+	if f.Type != TypeAny {
+		checked, err := f.Type.check(result, strict)
+		if err != nil {
+			return nil, fmt.Errorf("json2csv: field %q: %w", f.name(), err)
+		}
+		if _, isNumber := result.(json.Number); !isNumber || !preserveNumbers {
+			result = checked
+		}
+	}
+	return result, nil
+}
+
+// name identifies the field in errors: its JSONPath, or its CSVHeader for
+// constant, computed and query fields.
+func (f Field) name() string {
+	if f.JSONPath == "" {
+		return f.CSVHeader
+	}
+	return f.JSONPath
+}
+
+// handleError applies the field's OnError policy to err, a failure of value.
+// It returns the value to write instead, or whether to drop the row, or err
+// for FieldErrorPropagate.
+func (f Field) handleError(err error) (value interface{}, skipRow bool, _ error) {
+	switch f.OnError {
+	case FieldErrorEmptyCell:
+		return nil, false, nil
+	case FieldErrorUseDefault:
+		return f.Default, false, nil
+	case FieldErrorSkipRow:
+		return nil, true, nil
+	default:
+		return nil, false, err
+	}
+}
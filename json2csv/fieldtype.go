@@ -0,0 +1,219 @@
+// json2csv/fieldtype.go
+
+package json2csv
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldType declares the type of a column's values (see Field.Type). Values
+// are checked after the field's transformers; null is always accepted.
+type FieldType int
+
+const (
+	// TypeAny accepts any value and leaves it unchanged.
+	TypeAny FieldType = iota
+
+	// TypeString accepts strings. Other scalars are coerced to their text.
+	TypeString
+
+	// TypeInt accepts integral numbers. Numeric strings and numbers with a
+	// zero fraction, such as 3.0, are coerced.
+	TypeInt
+
+	// TypeFloat accepts numbers. Numeric strings are coerced.
+	TypeFloat
+
+	// TypeBool accepts booleans. The strings accepted by strconv.ParseBool,
+	// such as "true" and "0", are coerced.
+	TypeBool
+
+	// TypeTime accepts time.Time values and RFC 3339 strings. Unix epoch
+	// seconds or milliseconds (see EpochAuto) are coerced. Times are written
+	// in RFC 3339 format.
+	TypeTime
+)
+
+var fieldTypeNames = map[FieldType]string{
+	TypeAny:    "any",
+	TypeString: "string",
+	TypeInt:    "int",
+	TypeFloat:  "float",
+	TypeBool:   "bool",
+	TypeTime:   "time",
+}
+
+// String returns the name of t, as accepted by ParseFieldType.
+func (t FieldType) String() string {
+	if name, ok := fieldTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("FieldType(%d)", int(t))
+}
+
+// ParseFieldType returns the FieldType named name ("string", "int", ...).
+// The empty string is TypeAny.
+func ParseFieldType(name string) (FieldType, error) {
+	if name == "" {
+		return TypeAny, nil
+	}
+	for t, typeName := range fieldTypeNames {
+		if typeName == name {
+			return t, nil
+		}
+	}
+	return TypeAny, fmt.Errorf("json2csv: unknown field type %q", name)
+}
+
+// check returns value as a value of type t. Unless strict, values of other
+// types are coerced where possible; otherwise they are an error.
+func (t FieldType) check(value interface{}, strict bool) (interface{}, error) {
+	if value == nil || t == TypeAny {
+		return value, nil
+	}
+	var (
+		result interface{}
+		ok     bool
+	)
+	switch t {
+	case TypeString:
+		result, ok = checkString(value, strict)
+	case TypeInt:
+		result, ok = checkInt(value, strict)
+	case TypeFloat:
+		result, ok = checkFloat(value, strict)
+	case TypeBool:
+		result, ok = checkBool(value, strict)
+	case TypeTime:
+		result, ok = checkTime(value, strict)
+	}
+	if !ok {
+		return nil, fmt.Errorf("value %s is not of type %s", describeValue(value), t)
+	}
+	return result, nil
+}
+
+// describeValue renders value for a type error.
+func describeValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return strconv.Quote(v)
+	case map[string]interface{}:
+		return "(object)"
+	case []interface{}:
+		return "(array)"
+	default:
+		return fmt.Sprintf("%v (%T)", v, v)
+	}
+}
+
+func checkString(value interface{}, strict bool) (interface{}, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case map[string]interface{}, []interface{}:
+		return nil, false
+	}
+	if strict {
+		return nil, false
+	}
+	return valueToString(value), true
+}
+
+func checkInt(value interface{}, strict bool) (interface{}, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		if isIntegerLiteral(string(v)) {
+			return v, true // Also beyond int64, without rounding through float64.
+		}
+		return integral(v.Float64())
+	case float64:
+		return integral(v, nil)
+	case float32:
+		return integral(float64(v), nil)
+	case int, int8, int16, int32, int64:
+		return json.Number(strconv.FormatInt(reflect.ValueOf(v).Int(), 10)), true
+	case uint, uint8, uint16, uint32, uint64:
+		return json.Number(strconv.FormatUint(reflect.ValueOf(v).Uint(), 10)), true
+	case string:
+		if strict {
+			return nil, false
+		}
+		s := strings.TrimSpace(v)
+		if isIntegerLiteral(s) {
+			return json.Number(s), true
+		}
+		return integral(strconv.ParseFloat(s, 64))
+	}
+	return nil, false
+}
+
+// isIntegerLiteral reports whether s is an integer in decimal notation, of
+// any size, such as "-42" or "18446744073709551615".
+func isIntegerLiteral(s string) bool {
+	digits := strings.TrimPrefix(s, "-")
+	if digits == "" {
+		return false
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// integral returns f as an integer json.Number if it has no fraction.
+func integral(f float64, err error) (interface{}, bool) {
+	if err != nil || f != math.Trunc(f) || math.IsInf(f, 0) {
+		return nil, false
+	}
+	return formatNumber(f), true
+}
+
+func checkFloat(value interface{}, strict bool) (interface{}, bool) {
+	if _, isString := value.(string); isString && strict {
+		return nil, false
+	}
+	if number, isNumber := value.(json.Number); isNumber {
+		_, err := number.Float64()
+		return number, err == nil
+	}
+	f, ok, err := numberValue(value)
+	if err != nil || !ok {
+		return nil, false
+	}
+	return formatNumber(f), true
+}
+
+func checkBool(value interface{}, strict bool) (interface{}, bool) {
+	switch v := value.(type) {
+	case bool:
+		return v, true
+	case string:
+		if strict {
+			return nil, false
+		}
+		b, err := strconv.ParseBool(strings.TrimSpace(v))
+		return b, err == nil
+	}
+	return nil, false
+}
+
+func checkTime(value interface{}, strict bool) (interface{}, bool) {
+	switch value.(type) {
+	case time.Time, string:
+	default:
+		if strict {
+			return nil, false
+		}
+	}
+	t, _, err := parseTimeValue(value, time.RFC3339, EpochAuto)
+	return t, err == nil
+}
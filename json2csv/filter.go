@@ -0,0 +1,174 @@
+// json2csv/filter.go
+
+package json2csv
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// TimeWindow restricts a conversion to records whose timestamp falls within
+// [From, To). Records with a null or missing timestamp are skipped.
+//
+// If Path contains "[*]" the window applies to each flattened array item
+// instead; otherwise whole records are skipped before they are flattened.
+type TimeWindow struct {
+	// Path is the JSONPath of the timestamp, e.g. "created_at".
+	Path string
+
+	// Layout is the time.Parse layout used for string timestamps.
+	// Defaults to time.RFC3339.
+	Layout string
+
+	// Epoch is the unit of numeric timestamps. Defaults to EpochSeconds.
+	Epoch EpochUnit
+
+	// From is the inclusive start of the window. The zero value means no lower bound.
+	From time.Time
+
+	// To is the exclusive end of the window. The zero value means no upper bound.
+	To time.Time
+}
+
+// contains reports whether t falls inside the window.
+func (tw *TimeWindow) contains(t time.Time) bool {
+	if !tw.From.IsZero() && t.Before(tw.From) {
+		return false
+	}
+	if !tw.To.IsZero() && !t.Before(tw.To) {
+		return false
+	}
+	return true
+}
+
+// keep is the valueFilter predicate for the window.
+func (tw *TimeWindow) keep(value interface{}) (bool, error) {
+	t, ok, err := parseTimeValue(value, tw.Layout, tw.Epoch)
+	if err != nil {
+		return false, fmt.Errorf("json2csv: time window: %w", err)
+	}
+	return ok && tw.contains(t), nil
+}
+
+// KeyFilter restricts a conversion to records whose value at Path is listed
+// in Keys (an allow list), or with Deny set, is not listed (a deny list).
+// Values are compared using their CSV string form, so the number 42 matches
+// the key "42". A null or missing value never matches a key.
+//
+// If Path contains "[*]" the filter applies to each flattened array item
+// instead of whole records.
+type KeyFilter struct {
+	// Path is the JSONPath of the key, e.g. "customer.id".
+	Path string
+
+	// Keys is the set of values to allow (or deny).
+	Keys map[string]struct{}
+
+	// Deny turns Keys into a deny list.
+	Deny bool
+}
+
+// NewKeyFilter returns a KeyFilter for path built from a list of keys.
+func NewKeyFilter(path string, keys []string, deny bool) *KeyFilter {
+	set := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		set[key] = struct{}{}
+	}
+	return &KeyFilter{Path: path, Keys: set, Deny: deny}
+}
+
+// LoadKeyFilter returns a KeyFilter for path with the keys read from the
+// file at filename (see ReadKeys for the file format).
+func LoadKeyFilter(path, filename string, deny bool) (*KeyFilter, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("json2csv: failed to open key file: %w", err)
+	}
+	defer f.Close()
+
+	keys, err := ReadKeys(f)
+	if err != nil {
+		return nil, fmt.Errorf("json2csv: failed to read key file %q: %w", filename, err)
+	}
+	return &KeyFilter{Path: path, Keys: keys, Deny: deny}, nil
+}
+
+// ReadKeys reads a set of keys from r, one per line. Surrounding whitespace
+// is trimmed; blank lines and lines starting with "#" are ignored.
+func ReadKeys(r io.Reader) (map[string]struct{}, error) {
+	keys := make(map[string]struct{})
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		keys[line] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// keep is the valueFilter predicate for the key filter.
+func (kf *KeyFilter) keep(value interface{}) (bool, error) {
+	listed := false
+	if value != nil {
+		_, listed = kf.Keys[valueToString(value)]
+	}
+	return listed != kf.Deny, nil
+}
+
+// valueFilter drops records (or flattened items, if path contains "[*]")
+// for which keep returns false.
+type valueFilter struct {
+	path string
+	keep func(value interface{}) (bool, error)
+}
+
+// isItemFilter reports whether the filter must be evaluated per array item.
+func (f valueFilter) isItemFilter() bool {
+	return strings.Contains(f.path, "[*]") || f.path == PathItemIndex || f.path == PathArrayLength
+}
+
+// buildFilters collects the filters configured in options.
+func buildFilters(options Options) ([]valueFilter, error) {
+	var filters []valueFilter
+	if tw := options.TimeWindow; tw != nil {
+		if tw.Path == "" {
+			return nil, fmt.Errorf("json2csv: TimeWindow.Path must not be empty")
+		}
+		filters = append(filters, valueFilter{path: tw.Path, keep: tw.keep})
+	}
+	if kf := options.KeyFilter; kf != nil {
+		if kf.Path == "" {
+			return nil, fmt.Errorf("json2csv: KeyFilter.Path must not be empty")
+		}
+		filters = append(filters, valueFilter{path: kf.Path, keep: kf.keep})
+	}
+	return filters, nil
+}
+
+// keepRow runs the filters of the requested level (record or item) and
+// reports whether the record or item should be converted.
+func (c *conversion) keepRow(itemLevel bool, originalRecord map[string]interface{}, item interface{}) (bool, error) {
+	for _, f := range c.filters {
+		if f.isItemFilter() != itemLevel {
+			continue
+		}
+		value, err := c.resolvePath(f.path, originalRecord, item)
+		if err != nil {
+			return false, err
+		}
+		keep, err := f.keep(value)
+		if err != nil || !keep {
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,280 @@
+// json2csv/group.go
+
+package json2csv
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// GroupBy aggregates the rows that share the values of the key columns into
+// one output row (see Options.GroupBy). For example, one row per user with
+// the number of items and their total price:
+//
+//	&GroupBy{
+//		Keys: []string{"user_id"},
+//		Aggregates: []Aggregate{
+//			{Func: AggregateCount, Header: "items"},
+//			{Column: "price", Func: AggregateSum, Header: "total"},
+//		},
+//	}
+//
+// The output columns are the key columns followed by the aggregates. Groups
+// are written in the order of their first row, after the whole input has
+// been read; they are held in memory.
+type GroupBy struct {
+	// Keys are the headers of the columns to group by.
+	Keys []string
+
+	// Aggregates are the columns computed for each group.
+	Aggregates []Aggregate
+}
+
+// Aggregate is a column computed from the rows of a group.
+type Aggregate struct {
+	// Column is the header of the aggregated column. It may be empty for
+	// AggregateCount, which then counts the rows.
+	Column string
+
+	Func AggregateFunc
+
+	// Header is the output header. Defaults to the name of Func, followed
+	// by "_" and Column if set, e.g. "sum_price".
+	Header string
+}
+
+// AggregateFunc computes an Aggregate. Empty cells are ignored by all
+// functions but AggregateFirst.
+type AggregateFunc int
+
+const (
+	// AggregateCount counts the rows, or the non-empty cells of Column.
+	AggregateCount AggregateFunc = iota
+
+	// AggregateSum adds up the cells, which must be numbers.
+	AggregateSum
+
+	// AggregateMin and AggregateMax select the smallest or largest cell,
+	// comparing numbers numerically and other cells as strings after them
+	// (see SortKey.Numeric).
+	AggregateMin
+	AggregateMax
+
+	// AggregateFirst selects the cell of the group's first row.
+	AggregateFirst
+)
+
+var aggregateFuncNames = map[AggregateFunc]string{
+	AggregateCount: "count",
+	AggregateSum:   "sum",
+	AggregateMin:   "min",
+	AggregateMax:   "max",
+	AggregateFirst: "first",
+}
+
+// String returns the name of f, such as "sum".
+func (f AggregateFunc) String() string {
+	if name, ok := aggregateFuncNames[f]; ok {
+		return name
+	}
+	return fmt.Sprintf("AggregateFunc(%d)", int(f))
+}
+
+// header returns the output header of a.
+func (a Aggregate) header() string {
+	switch {
+	case a.Header != "":
+		return a.Header
+	case a.Column != "":
+		return a.Func.String() + "_" + a.Column
+	default:
+		return a.Func.String()
+	}
+}
+
+// groupRowWriter is a RowWriter that aggregates rows into groups, writing
+// them to out when it is closed.
+type groupRowWriter struct {
+	out        RowWriter
+	header     []string
+	keys       []int // Column indexes of GroupBy.Keys
+	aggregates []Aggregate
+	columns    []int // Column indexes of the aggregates, -1 for none
+	lossless   bool  // Cells are encoded with encodeLosslessCell.
+
+	groups map[string]*group
+	order  []*group
+}
+
+// group is the state of one group.
+type group struct {
+	keys   []string
+	states []aggregateState
+}
+
+// aggregateState is the running value of one aggregate.
+type aggregateState struct {
+	count   int
+	sum     float64
+	intSum  int64
+	isFloat bool   // sum holds the sum; otherwise intSum does.
+	cell    string // For min, max and first.
+	set     bool
+}
+
+// newGroupRowWriter returns a groupRowWriter for rows with the columns of
+// header.
+func newGroupRowWriter(out RowWriter, header []string, options Options) (*groupRowWriter, error) {
+	g := &groupRowWriter{
+		out:        out,
+		aggregates: options.GroupBy.Aggregates,
+		lossless:   options.Lossless && options.Format == FormatCSV,
+		groups:     map[string]*group{},
+	}
+	for _, key := range options.GroupBy.Keys {
+		index := slices.Index(header, key)
+		if index < 0 {
+			return nil, fmt.Errorf("json2csv: GroupBy: no column %q", key)
+		}
+		g.keys = append(g.keys, index)
+		g.header = append(g.header, key)
+	}
+	for _, aggregate := range g.aggregates {
+		index := -1
+		if aggregate.Column != "" {
+			if index = slices.Index(header, aggregate.Column); index < 0 {
+				return nil, fmt.Errorf("json2csv: GroupBy: no column %q", aggregate.Column)
+			}
+		}
+		g.columns = append(g.columns, index)
+		g.header = append(g.header, aggregate.header())
+	}
+	return g, nil
+}
+
+// WriteHeader writes the header of the grouped rows instead of header.
+func (g *groupRowWriter) WriteHeader(header []string) error {
+	return g.out.WriteHeader(g.header)
+}
+
+func (g *groupRowWriter) WriteRow(row []string) error {
+	keys := make([]string, len(g.keys))
+	for i, index := range g.keys {
+		keys[i] = row[index]
+	}
+	id := strings.Join(keys, "\x00")
+	grp, ok := g.groups[id]
+	if !ok {
+		grp = &group{keys: keys, states: make([]aggregateState, len(g.aggregates))}
+		g.groups[id] = grp
+		g.order = append(g.order, grp)
+	}
+	for i, aggregate := range g.aggregates {
+		if err := g.add(&grp.states[i], aggregate, g.columns[i], row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add adds the cell of row in column to state.
+func (g *groupRowWriter) add(state *aggregateState, aggregate Aggregate, column int, row []string) error {
+	if column < 0 {
+		state.count++
+		return nil
+	}
+	cell, plain := row[column], row[column]
+	if g.lossless {
+		plain = decodeLosslessCell(cell)
+	}
+	if aggregate.Func == AggregateFirst {
+		if !state.set {
+			state.cell, state.set = cell, true
+		}
+		return nil
+	}
+	if plain == "" {
+		return nil
+	}
+	state.count++
+	switch aggregate.Func {
+	case AggregateSum:
+		return state.addNumber(aggregate, strings.TrimSpace(plain))
+	case AggregateMin, AggregateMax:
+		if !state.set {
+			state.cell, state.set = cell, true
+			return nil
+		}
+		current := state.cell
+		if g.lossless {
+			current = decodeLosslessCell(current)
+		}
+		order := compareNumeric(plain, current)
+		if (aggregate.Func == AggregateMin && order < 0) || (aggregate.Func == AggregateMax && order > 0) {
+			state.cell = cell
+		}
+	}
+	return nil
+}
+
+// addNumber adds the number s to the sum, which is kept as an integer while
+// all numbers are integers and fit.
+func (s *aggregateState) addNumber(aggregate Aggregate, number string) error {
+	if !s.isFloat {
+		if n, err := strconv.ParseInt(number, 10, 64); err == nil {
+			if sum := s.intSum + n; (n >= 0) == (sum >= s.intSum) {
+				s.intSum = sum
+				return nil
+			}
+		}
+		s.isFloat = true
+		s.sum = float64(s.intSum)
+	}
+	f, err := strconv.ParseFloat(number, 64)
+	if err != nil || math.IsNaN(f) {
+		return fmt.Errorf("json2csv: GroupBy: %s of %q: %q is not a number", aggregate.Func, aggregate.Column, number)
+	}
+	s.sum += f
+	return nil
+}
+
+// value returns the cell of the aggregate.
+func (s *aggregateState) value(f AggregateFunc) string {
+	switch f {
+	case AggregateCount:
+		return strconv.Itoa(s.count)
+	case AggregateSum:
+		if s.isFloat {
+			return formatFloat(s.sum, 64)
+		}
+		return strconv.FormatInt(s.intSum, 10)
+	default:
+		return s.cell
+	}
+}
+
+// Flush does nothing: no group is complete before all rows have been seen.
+func (g *groupRowWriter) Flush() error { return nil }
+
+// Close writes the groups to out and closes it.
+func (g *groupRowWriter) Close() error {
+	row := make([]string, len(g.header))
+	for _, grp := range g.order {
+		copy(row, grp.keys)
+		for i, aggregate := range g.aggregates {
+			cell := grp.states[i].value(aggregate.Func)
+			if g.lossless && cell == "" && aggregate.Func != AggregateCount && aggregate.Func != AggregateSum {
+				cell = LosslessNull // No cell was seen.
+			}
+			row[len(grp.keys)+i] = cell
+		}
+		if err := g.out.WriteRow(row); err != nil {
+			return err
+		}
+	}
+	g.groups, g.order = nil, nil
+	return g.out.Close()
+}
@@ -0,0 +1,118 @@
+// json2csv/http.go
+
+package json2csv
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HTTPOptions configures ConvertFromURL.
+type HTTPOptions struct {
+	// Client sends the requests. Defaults to http.DefaultClient, whose
+	// transport requests gzip compression and decompresses transparently.
+	Client *http.Client
+
+	// Header is added to every request, e.g. an Authorization header.
+	Header http.Header
+
+	// MaxRetries is the number of times a request is retried after a
+	// network error or a 429 or 5xx response. Zero means no retries.
+	MaxRetries int
+
+	// RetryBackoff is the wait before the first retry; it doubles for each
+	// further retry. Defaults to one second.
+	RetryBackoff time.Duration
+}
+
+// ConvertFromURL fetches url with a GET request and converts the JSON in
+// the response body like ConvertSources, with url as the Source Name. The
+// body is converted as it arrives and never held in memory. Failed requests
+// are retried according to httpOptions, but only until the response is
+// accepted: once rows have been written, an error ends the conversion.
+func ConvertFromURL(ctx context.Context, url string, w io.Writer, options Options, httpOptions HTTPOptions) error {
+	body, err := httpOptions.get(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	return ConvertSources([]Source{{Name: url, Reader: body}}, w, options)
+}
+
+// get requests url, retrying as configured, and returns the response body,
+// decompressed if the server sent it gzip-encoded.
+func (h HTTPOptions) get(ctx context.Context, url string) (io.ReadCloser, error) {
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	backoff := h.RetryBackoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("json2csv: invalid request for %q: %w", url, err)
+		}
+		for key, values := range h.Header {
+			for _, value := range values {
+				req.Header.Add(key, value)
+			}
+		}
+
+		resp, err := client.Do(req)
+		retryable := err != nil
+		if err == nil {
+			if resp.StatusCode == http.StatusOK {
+				return responseBody(resp)
+			}
+			resp.Body.Close()
+			retryable = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+			err = fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		if !retryable || attempt >= h.MaxRetries || ctx.Err() != nil {
+			return nil, fmt.Errorf("json2csv: failed to fetch %q: %w", url, err)
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("json2csv: failed to fetch %q: %w", url, ctx.Err())
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// responseBody returns the body of resp, decompressing it if the transport
+// left it gzip-encoded.
+func responseBody(resp *http.Response) (io.ReadCloser, error) {
+	if resp.Uncompressed || !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return resp.Body, nil
+	}
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		resp.Body.Close()
+		return nil, fmt.Errorf("json2csv: failed to decompress response: %w", err)
+	}
+	return &gzipBody{Reader: gz, body: resp.Body}, nil
+}
+
+// gzipBody closes both the gzip reader and the response body.
+type gzipBody struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (g *gzipBody) Close() error {
+	g.Reader.Close()
+	return g.body.Close()
+}
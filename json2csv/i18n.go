@@ -0,0 +1,77 @@
+// json2csv/i18n.go
+
+package json2csv
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HeaderTranslations localizes the header row, so one field mapping can
+// produce reports with column names in the recipient's language. Headers are
+// translated by their Field.CSVHeader; headers without a translation are
+// written unchanged.
+type HeaderTranslations struct {
+	// Locale selects the translation for this run, e.g. "de" or "pt-BR".
+	// If there is no translation for a regional locale, its base language
+	// ("pt") is tried. An empty Locale leaves the headers untranslated.
+	Locale string
+
+	// Catalog maps locales to translations from CSVHeader to the localized
+	// header, e.g. {"de": {"Order ID": "Bestellnummer"}}.
+	Catalog map[string]map[string]string
+
+	// Load, if set, is called for locales not found in Catalog, e.g. to read
+	// translations from a file. It returns nil if it has no translation
+	// for locale.
+	Load func(locale string) (map[string]string, error)
+}
+
+// translations returns the header translations for ht.Locale, or nil if the
+// locale is empty. It is an error if no translation exists for the locale.
+func (ht *HeaderTranslations) translations() (map[string]string, error) {
+	if ht.Locale == "" {
+		return nil, nil
+	}
+	for _, locale := range localeCandidates(ht.Locale) {
+		if m, ok := ht.Catalog[locale]; ok {
+			return m, nil
+		}
+		if ht.Load != nil {
+			m, err := ht.Load(locale)
+			if err != nil {
+				return nil, fmt.Errorf("json2csv: failed to load header translations for locale %q: %w", locale, err)
+			}
+			if m != nil {
+				return m, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("json2csv: no header translations for locale %q", ht.Locale)
+}
+
+// translate returns header with each entry replaced by its translation.
+func (ht *HeaderTranslations) translate(header []string) ([]string, error) {
+	m, err := ht.translations()
+	if err != nil || m == nil {
+		return header, err
+	}
+	translated := make([]string, len(header))
+	for i, h := range header {
+		if t, ok := m[h]; ok {
+			translated[i] = t
+		} else {
+			translated[i] = h
+		}
+	}
+	return translated, nil
+}
+
+// localeCandidates returns locale followed by its base language, if it has
+// a region: "pt-BR" (or "pt_BR") yields "pt-BR", "pt".
+func localeCandidates(locale string) []string {
+	if i := strings.IndexAny(locale, "-_"); i > 0 {
+		return []string{locale, locale[:i]}
+	}
+	return []string{locale}
+}
@@ -0,0 +1,80 @@
+// json2csv/jmespath/jmespath.go
+
+// Package jmespath provides json2csv.Query values for JMESPath expressions,
+// evaluated with github.com/jmespath/go-jmespath:
+//
+//	{CSVHeader: "Expensive SKUs",
+//		Query: jmespath.MustCompile("join(',', items[?price > `10`].sku)")}
+//
+// It is a separate package so that programs not using JMESPath do not
+// depend on go-jmespath.
+package jmespath
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jmespath/go-jmespath"
+
+	"github.com/pradnyoday/go-json2csv/json2csv"
+)
+
+// query is a compiled JMESPath expression.
+type query struct {
+	expression *jmespath.JMESPath
+}
+
+// Compile compiles the JMESPath expression src, which is evaluated against
+// the record. Numbers of the record are seen as float64 values, which can
+// lose the precision of integers beyond 2^53.
+func Compile(src string) (json2csv.Query, error) {
+	expression, err := jmespath.Compile(src)
+	if err != nil {
+		return nil, fmt.Errorf("json2csv: invalid JMESPath expression %q: %w", src, err)
+	}
+	return query{expression: expression}, nil
+}
+
+// MustCompile is like Compile but panics if src is invalid, like
+// regexp.MustCompile. It is meant for expressions written in the program,
+// as in Field literals; use Compile for expressions read from configuration
+// or user input.
+func MustCompile(src string) json2csv.Query {
+	q, err := Compile(src)
+	if err != nil {
+		panic(err.Error())
+	}
+	return q
+}
+
+// Evaluate evaluates the expression against record.
+func (q query) Evaluate(record map[string]interface{}) (interface{}, error) {
+	return q.expression.Search(floatNumbers(record))
+}
+
+// floatNumbers returns a copy of v with json.Number values converted to
+// float64, the only number type go-jmespath compares and computes with.
+func floatNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[key] = floatNumbers(value)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, value := range v {
+			s[i] = floatNumbers(value)
+		}
+		return s
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return v
+		}
+		return f
+	default:
+		return v
+	}
+}
@@ -0,0 +1,39 @@
+// json2csv/jmespath/jmespath_test.go
+
+package jmespath_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pradnyoday/go-json2csv/json2csv/jmespath"
+)
+
+func TestCompile(t *testing.T) {
+	q, err := jmespath.Compile("join(',', items[].sku)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := q.Evaluate(map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"sku": "a"},
+			map[string]interface{}{"sku": "b"},
+		},
+	})
+	if err != nil || got != "a,b" {
+		t.Errorf("Evaluate = %v, %v; want a,b", got, err)
+	}
+
+	if _, err := jmespath.Compile("items[?"); err == nil || !strings.HasPrefix(err.Error(), "json2csv: invalid JMESPath expression") {
+		t.Errorf("Compile = %v, want an invalid expression error", err)
+	}
+}
+
+func TestMustCompilePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("MustCompile did not panic on an invalid expression")
+		}
+	}()
+	jmespath.MustCompile("items[?")
+}
@@ -0,0 +1,96 @@
+// json2csv/jq/jq.go
+
+// Package jq provides json2csv.Query values for jq expressions, evaluated
+// with github.com/itchyny/gojq:
+//
+//	{CSVHeader: "Expensive SKUs",
+//		Query: jq.MustCompile(`[.items[] | select(.price > 10) | .sku] | join(",")`)}
+//
+// It is a separate package so that programs not using jq do not depend on
+// gojq.
+package jq
+
+import (
+	"fmt"
+
+	"github.com/itchyny/gojq"
+
+	"github.com/pradnyoday/go-json2csv/json2csv"
+)
+
+// query is a compiled jq expression.
+type query struct {
+	code *gojq.Code
+}
+
+// Compile parses and compiles the jq expression src, which runs with the
+// record as its input ".". An expression yielding no value results in
+// null, one yielding several values in a list of them.
+func Compile(src string) (json2csv.Query, error) {
+	parsed, err := gojq.Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("json2csv: invalid jq expression %q: %w", src, err)
+	}
+	code, err := gojq.Compile(parsed)
+	if err != nil {
+		return nil, fmt.Errorf("json2csv: invalid jq expression %q: %w", src, err)
+	}
+	return query{code: code}, nil
+}
+
+// MustCompile is like Compile but panics if src is invalid, like
+// regexp.MustCompile. It is meant for expressions written in the program,
+// as in Field literals; use Compile for expressions read from configuration
+// or user input.
+func MustCompile(src string) json2csv.Query {
+	q, err := Compile(src)
+	if err != nil {
+		panic(err.Error())
+	}
+	return q
+}
+
+// Evaluate runs the expression with record as its input.
+func (q query) Evaluate(record map[string]interface{}) (interface{}, error) {
+	var results []interface{}
+	iter := q.code.Run(copyValue(record))
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, ok := v.(error); ok {
+			return nil, err
+		}
+		results = append(results, v)
+	}
+	switch len(results) {
+	case 0:
+		return nil, nil
+	case 1:
+		return results[0], nil
+	default:
+		return results, nil
+	}
+}
+
+// copyValue returns a deep copy of v, as gojq rewrites the numbers of its
+// input in place.
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[key] = copyValue(value)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, value := range v {
+			s[i] = copyValue(value)
+		}
+		return s
+	default:
+		return v
+	}
+}
@@ -0,0 +1,41 @@
+// json2csv/jq/jq_test.go
+
+package jq_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pradnyoday/go-json2csv/json2csv/jq"
+)
+
+func TestCompile(t *testing.T) {
+	q, err := jq.Compile(`[.items[] | .sku] | join(",")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := q.Evaluate(map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"sku": "a"},
+			map[string]interface{}{"sku": "b"},
+		},
+	})
+	if err != nil || got != "a,b" {
+		t.Errorf("Evaluate = %v, %v; want a,b", got, err)
+	}
+
+	for _, src := range []string{`.items[`, `undefined_function(.)`} {
+		if _, err := jq.Compile(src); err == nil || !strings.HasPrefix(err.Error(), "json2csv: invalid jq expression") {
+			t.Errorf("Compile(%q) = %v, want an invalid expression error", src, err)
+		}
+	}
+}
+
+func TestMustCompilePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("MustCompile did not panic on an invalid expression")
+		}
+	}()
+	jq.MustCompile(`.items[`)
+}
@@ -0,0 +1,216 @@
+// json2csv/json2csvtest/json2csvtest.go
+
+// Package json2csvtest provides fault injection for testing pipelines built
+// on json2csv: readers and writers that return short reads, fail part-way
+// through or are slow, and helpers that check a conversion handles those
+// faults correctly.
+//
+//	func TestExportRobustness(t *testing.T) {
+//		input, _ := os.ReadFile("testdata/orders.json")
+//		json2csvtest.CheckRobustness(t, func(r io.Reader, w io.Writer) error {
+//			return json2csv.Convert(r, w, exportOptions)
+//		}, input)
+//	}
+package json2csvtest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+)
+
+// ErrInjected is the error returned by injected failures unless Faults.Err
+// says otherwise.
+var ErrInjected = errors.New("json2csvtest: injected fault")
+
+// Faults configures the faults injected by Faults.Reader and Faults.Writer.
+// The zero value injects nothing.
+type Faults struct {
+	// ChunkSize limits every Read to return at most this many bytes, and
+	// splits every Write into writes of at most this many bytes. Zero means
+	// no limit.
+	ChunkSize int
+
+	// Err, if set, is returned once FailAfter bytes have been transferred;
+	// the call that crosses the limit transfers the bytes up to it first.
+	Err error
+
+	// FailAfter is the number of bytes transferred before Err is returned.
+	FailAfter int64
+
+	// Delay is slept before every Read or Write.
+	Delay time.Duration
+}
+
+// Fail returns Faults that fail with ErrInjected after n bytes.
+func Fail(n int64) Faults {
+	return Faults{Err: ErrInjected, FailAfter: n}
+}
+
+// String describes the faults, for test names and messages.
+func (f Faults) String() string {
+	s := fmt.Sprintf("chunk=%d delay=%s", f.ChunkSize, f.Delay)
+	if f.Err != nil {
+		s += fmt.Sprintf(" fail after %d bytes (%v)", f.FailAfter, f.Err)
+	}
+	return s
+}
+
+// Reader wraps r with the faults.
+func (f Faults) Reader(r io.Reader) io.Reader {
+	return &faultReader{r: r, faults: f}
+}
+
+// Writer wraps w with the faults.
+func (f Faults) Writer(w io.Writer) io.Writer {
+	return &faultWriter{w: w, faults: f}
+}
+
+// limit returns the number of bytes of a len(p)-byte call that may be
+// transferred after done bytes, and the error to return after them.
+func (f Faults) limit(n int, done int64) (int, error) {
+	if f.ChunkSize > 0 && n > f.ChunkSize {
+		n = f.ChunkSize
+	}
+	if f.Err != nil && done+int64(n) >= f.FailAfter {
+		return int(max(0, f.FailAfter-done)), f.Err
+	}
+	return n, nil
+}
+
+type faultReader struct {
+	r      io.Reader
+	faults Faults
+	done   int64
+}
+
+func (fr *faultReader) Read(p []byte) (int, error) {
+	time.Sleep(fr.faults.Delay)
+	n, injected := fr.faults.limit(len(p), fr.done)
+	if n == 0 && injected != nil {
+		return 0, injected
+	}
+	n, err := fr.r.Read(p[:n])
+	fr.done += int64(n)
+	if err == nil && injected != nil && fr.done >= fr.faults.FailAfter {
+		err = injected
+	}
+	return n, err
+}
+
+type faultWriter struct {
+	w      io.Writer
+	faults Faults
+	done   int64
+}
+
+func (fw *faultWriter) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		time.Sleep(fw.faults.Delay)
+		n, injected := fw.faults.limit(len(p)-written, fw.done)
+		n, err := fw.w.Write(p[written : written+n])
+		written += n
+		fw.done += int64(n)
+		if err != nil {
+			return written, err
+		}
+		if injected != nil {
+			return written, injected
+		}
+	}
+	return written, nil
+}
+
+// ConvertFunc runs a conversion from r to w, typically a closure calling
+// json2csv.Convert with fixed Options.
+type ConvertFunc func(r io.Reader, w io.Writer) error
+
+// CheckRobustness runs CheckShortIO, CheckReadFailures and
+// CheckWriteFailures.
+func CheckRobustness(t testing.TB, convert ConvertFunc, input []byte) {
+	t.Helper()
+	CheckShortIO(t, convert, input)
+	CheckReadFailures(t, convert, input)
+	CheckWriteFailures(t, convert, input)
+}
+
+// CheckShortIO checks that reading the input and writing the output in
+// small chunks produces exactly the same output as unhindered I/O.
+func CheckShortIO(t testing.TB, convert ConvertFunc, input []byte) {
+	t.Helper()
+	want := reference(t, convert, input)
+	for _, chunk := range []int{1, 2, 7, 64} {
+		var got bytes.Buffer
+		faults := Faults{ChunkSize: chunk}
+		if err := convert(faults.Reader(bytes.NewReader(input)), faults.Writer(&got)); err != nil {
+			t.Errorf("chunk size %d: conversion failed: %v", chunk, err)
+			continue
+		}
+		if !bytes.Equal(got.Bytes(), want) {
+			t.Errorf("chunk size %d: output differs from unchunked output:\ngot:\n%s\nwant:\n%s", chunk, got.Bytes(), want)
+		}
+	}
+}
+
+// CheckReadFailures injects a read error at offsets spread over the input
+// and checks that the conversion returns it (as checked by errors.Is) and
+// that the output written until then is a prefix of the complete output.
+// Failures are injected before the last byte that is not white space, as a
+// conversion need not read the white space after the end of the input.
+func CheckReadFailures(t testing.TB, convert ConvertFunc, input []byte) {
+	t.Helper()
+	want := reference(t, convert, input)
+	for _, offset := range offsets(len(bytes.TrimRight(input, " \t\r\n"))) {
+		var got bytes.Buffer
+		err := convert(Fail(int64(offset)).Reader(bytes.NewReader(input)), &got)
+		if !errors.Is(err, ErrInjected) {
+			t.Errorf("read failure at byte %d: got error %v, want the injected error", offset, err)
+		}
+		if !bytes.HasPrefix(want, got.Bytes()) {
+			t.Errorf("read failure at byte %d: partial output is not a prefix of the complete output:\n%s", offset, got.Bytes())
+		}
+	}
+}
+
+// CheckWriteFailures injects a write error at offsets spread over the
+// output and checks that the conversion returns it (as checked by
+// errors.Is).
+func CheckWriteFailures(t testing.TB, convert ConvertFunc, input []byte) {
+	t.Helper()
+	want := reference(t, convert, input)
+	for _, offset := range offsets(len(want)) {
+		err := convert(bytes.NewReader(input), Fail(int64(offset)).Writer(io.Discard))
+		if !errors.Is(err, ErrInjected) {
+			t.Errorf("write failure at byte %d: got error %v, want the injected error", offset, err)
+		}
+	}
+}
+
+// reference returns the fault-free output, failing the test if there is none.
+func reference(t testing.TB, convert ConvertFunc, input []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := convert(bytes.NewReader(input), &buf); err != nil {
+		t.Fatalf("conversion without faults failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// offsets returns up to 17 byte offsets in [0, n) spread evenly.
+func offsets(n int) []int {
+	const steps = 16
+	var result []int
+	seen := map[int]bool{}
+	for i := 0; i <= steps; i++ {
+		offset := i * (n - 1) / steps
+		if n > 0 && !seen[offset] {
+			seen[offset] = true
+			result = append(result, offset)
+		}
+	}
+	return result
+}
@@ -0,0 +1,45 @@
+// json2csv/json2csvtest/json2csvtest_test.go
+
+package json2csvtest_test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/pradnyoday/go-json2csv/json2csv"
+	"github.com/pradnyoday/go-json2csv/json2csv/json2csvtest"
+)
+
+func TestCheckRobustnessConvert(t *testing.T) {
+	options := json2csv.Options{
+		Fields: []json2csv.Field{
+			{JSONPath: "orders[*].id", CSVHeader: "id"},
+			{JSONPath: "orders[*].total", CSVHeader: "total"},
+			{JSONPath: "customer", CSVHeader: "customer"},
+		},
+	}
+	convert := func(r io.Reader, w io.Writer) error {
+		return json2csv.Convert(r, w, options)
+	}
+
+	for name, input := range map[string]string{
+		"array":            `[{"customer":"ada","orders":[{"id":1,"total":9.5},{"id":2,"total":12}]}]`,
+		"trailing newline": `[{"customer":"ada","orders":[{"id":1,"total":9.5},{"id":2,"total":12}]}]` + "\n",
+		"objects":          `{"customer":"ada","orders":[{"id":1,"total":9.5}]}` + "\n" + `{"customer":"bob","orders":[{"id":2,"total":12}]}` + "\n",
+		"indented": `[
+  {
+    "customer": "ada",
+    "orders": [
+      {"id": 1, "total": 9.5},
+      {"id": 2, "total": 12}
+    ]
+  }
+]
+
+`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			json2csvtest.CheckRobustness(t, convert, []byte(input))
+		})
+	}
+}
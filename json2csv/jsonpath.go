@@ -0,0 +1,675 @@
+// json2csv/jsonpath.go
+
+package json2csv
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// This file implements the path syntax described in the package documentation.
+
+type segmentKind int
+
+const (
+	segmentChild segmentKind = iota
+	segmentIndex
+	segmentSlice
+	segmentWildcard
+	segmentFilter
+)
+
+// pathSegment is one step of a compiledPath.
+type pathSegment struct {
+	kind      segmentKind
+	recursive bool // Applies to the node and all its descendants ("..").
+
+	name  string // segmentChild
+	index int    // segmentIndex
+
+	// segmentSlice; nil bounds are omitted.
+	start, end *int
+	step       int
+
+	filter filterExpr // segmentFilter
+}
+
+// compiledPath is a parsed path.
+type compiledPath struct {
+	segments []pathSegment
+	query    bool // Resolves to a list of matches.
+}
+
+// pathCacheSize bounds the paths kept by compilePath, so that programs
+// evaluating many distinct paths, such as paths built from the data, do not
+// grow the cache without limit.
+const pathCacheSize = 4096
+
+// pathCache holds the most recently used compiled paths.
+var pathCache = struct {
+	sync.Mutex
+	paths *lru[string, *compiledPath]
+}{paths: newLRU[string, *compiledPath](pathCacheSize)}
+
+// compilePath parses path, caching the result.
+func compilePath(path string) (*compiledPath, error) {
+	pathCache.Lock()
+	compiled, ok := pathCache.paths.get(path)
+	pathCache.Unlock()
+	if ok {
+		return compiled, nil
+	}
+	p := &pathParser{path: path}
+	compiled, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("json2csv: invalid path %q: %w", path, err)
+	}
+	pathCache.Lock()
+	pathCache.paths.add(path, compiled)
+	pathCache.Unlock()
+	return compiled, nil
+}
+
+// childKeys returns the keys of a path made only of child segments, such as
+// "data.items", or nil for any other path.
+func (cp *compiledPath) childKeys() []string {
+	keys := make([]string, 0, len(cp.segments))
+	for _, segment := range cp.segments {
+		if segment.kind != segmentChild || segment.recursive {
+			return nil
+		}
+		keys = append(keys, segment.name)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return keys
+}
+
+// evaluate resolves the path against data.
+func (cp *compiledPath) evaluate(data interface{}) interface{} {
+	value, _ := cp.lookup(data)
+	return value
+}
+
+// lookup resolves the path against data like evaluate. found is false if
+// the path is not present in data, as opposed to present with a null
+// value: a key is absent, an index out of range, a parent not an object or
+// array, or a query matched nothing.
+func (cp *compiledPath) lookup(data interface{}) (value interface{}, found bool) {
+	if !cp.query {
+		current := data
+		for _, segment := range cp.segments {
+			switch segment.kind {
+			case segmentChild:
+				m, ok := current.(map[string]interface{})
+				if !ok {
+					return nil, false
+				}
+				if current, ok = m[segment.name]; !ok {
+					return nil, false
+				}
+			case segmentIndex:
+				var ok bool
+				if current, ok = segment.element(current); !ok {
+					return nil, false
+				}
+			}
+		}
+		return current, true
+	}
+
+	nodes := []interface{}{data}
+	for _, segment := range cp.segments {
+		var next []interface{}
+		for _, node := range nodes {
+			if segment.recursive {
+				walkDescendants(node, func(n interface{}) {
+					next = segment.apply(n, next)
+				})
+			} else {
+				next = segment.apply(node, next)
+			}
+		}
+		nodes = next
+		if len(nodes) == 0 {
+			return nil, false
+		}
+	}
+	return nodes, true
+}
+
+// apply appends the values selected by the segment from node to matches.
+func (s *pathSegment) apply(node interface{}, matches []interface{}) []interface{} {
+	switch s.kind {
+	case segmentChild:
+		if m, ok := node.(map[string]interface{}); ok {
+			if value, exists := m[s.name]; exists {
+				matches = append(matches, value)
+			}
+		}
+	case segmentIndex:
+		if value, ok := s.element(node); ok {
+			matches = append(matches, value)
+		}
+	case segmentSlice:
+		if arr, ok := node.([]interface{}); ok {
+			matches = append(matches, s.slice(arr)...)
+		}
+	case segmentWildcard:
+		matches = append(matches, children(node)...)
+	case segmentFilter:
+		for _, child := range children(node) {
+			if s.filter.match(child) {
+				matches = append(matches, child)
+			}
+		}
+	}
+	return matches
+}
+
+// element returns the value an index segment selects from node: an
+// element of an array or, for a non-negative index, the member of an
+// object named by its digits, as a JSON Pointer token such as the "123" of
+// "/users/123/name" selects either.
+func (s *pathSegment) element(node interface{}) (interface{}, bool) {
+	switch node := node.(type) {
+	case []interface{}:
+		if i, ok := arrayIndex(s.index, len(node)); ok {
+			return node[i], true
+		}
+	case map[string]interface{}:
+		if s.index >= 0 {
+			value, ok := node[strconv.Itoa(s.index)]
+			return value, ok
+		}
+	}
+	return nil, false
+}
+
+// slice returns the elements of arr selected by a slice segment, with
+// Python semantics for negative bounds and steps.
+func (s *pathSegment) slice(arr []interface{}) []interface{} {
+	n := len(arr)
+	bound := func(b *int, def int) int {
+		if b == nil {
+			return def
+		}
+		i := *b
+		if i < 0 {
+			i += n
+		}
+		if s.step > 0 {
+			return max(0, min(i, n))
+		}
+		return max(-1, min(i, n-1))
+	}
+	var result []interface{}
+	if s.step > 0 {
+		for i := bound(s.start, 0); i < bound(s.end, n); i += s.step {
+			result = append(result, arr[i])
+		}
+	} else {
+		for i := bound(s.start, n-1); i > bound(s.end, -1); i += s.step {
+			result = append(result, arr[i])
+		}
+	}
+	return result
+}
+
+// arrayIndex converts a possibly negative index into an index into an array
+// of length n.
+func arrayIndex(i, n int) (int, bool) {
+	if i < 0 {
+		i += n
+	}
+	return i, i >= 0 && i < n
+}
+
+// children returns the elements of an array or the values of an object in
+// key order.
+func children(node interface{}) []interface{} {
+	switch v := node.(type) {
+	case []interface{}:
+		return v
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		values := make([]interface{}, len(keys))
+		for i, key := range keys {
+			values[i] = v[key]
+		}
+		return values
+	}
+	return nil
+}
+
+// walkDescendants calls fn for node and all nodes nested in it, depth first.
+func walkDescendants(node interface{}, fn func(interface{})) {
+	fn(node)
+	for _, child := range children(node) {
+		walkDescendants(child, fn)
+	}
+}
+
+// validatePath reports syntax errors in a Field JSONPath, checking the parts
+// before and after the flattening "[*]" separately.
+func validatePath(path string) error {
+	if starIndex := strings.Index(path, "[*]"); starIndex != -1 {
+		if _, err := compilePath(strings.TrimSuffix(path[:starIndex], ".")); err != nil {
+			return err
+		}
+		path = strings.TrimPrefix(path[starIndex+len("[*]"):], ".")
+	}
+	_, err := compilePath(path)
+	return err
+}
+
+// concatArrays returns the matches of a query with arrays replaced by their
+// elements.
+func concatArrays(matches []interface{}) []interface{} {
+	var items []interface{}
+	for _, match := range matches {
+		if arr, ok := match.([]interface{}); ok {
+			items = append(items, arr...)
+		} else {
+			items = append(items, match)
+		}
+	}
+	return items
+}
+
+// --- Parsing ---
+
+type pathParser struct {
+	path string
+	pos  int
+}
+
+func (p *pathParser) parse() (*compiledPath, error) {
+	cp := &compiledPath{}
+	if p.path == "" {
+		return cp, nil // The data itself, e.g. for "items[*]".
+	}
+
+	expectKey := true // At the start of the path or after a ".".
+	for p.pos < len(p.path) {
+		recursive := false
+		switch {
+		case strings.HasPrefix(p.path[p.pos:], ".."):
+			p.pos += 2
+			recursive = true
+			expectKey = true
+		case p.path[p.pos] == '.':
+			if expectKey {
+				return nil, fmt.Errorf("empty key at offset %d", p.pos)
+			}
+			p.pos++
+			expectKey = true
+		}
+
+		var segment pathSegment
+		switch {
+		case p.pos < len(p.path) && p.path[p.pos] == '[':
+			var err error
+			if segment, err = p.parseBracket(); err != nil {
+				return nil, err
+			}
+		case p.pos < len(p.path) && p.path[p.pos] == '*':
+			p.pos++
+			segment = pathSegment{kind: segmentWildcard}
+		default:
+			start := p.pos
+			for p.pos < len(p.path) && p.path[p.pos] != '.' && p.path[p.pos] != '[' {
+				p.pos++
+			}
+			if p.pos == start {
+				return nil, fmt.Errorf("empty key at offset %d", start)
+			}
+			segment = pathSegment{kind: segmentChild, name: p.path[start:p.pos]}
+		}
+		segment.recursive = recursive
+		expectKey = false
+
+		if segment.kind != segmentChild && segment.kind != segmentIndex || recursive {
+			cp.query = true
+		}
+		cp.segments = append(cp.segments, segment)
+	}
+	if expectKey {
+		return nil, fmt.Errorf("path ends with %q", ".")
+	}
+	return cp, nil
+}
+
+// parseBracket parses a "[...]" selector at p.pos.
+func (p *pathParser) parseBracket() (pathSegment, error) {
+	open := p.pos
+	p.pos++ // "["
+
+	if strings.HasPrefix(p.path[p.pos:], "?(") {
+		p.pos += 2
+		fp := &filterParser{s: p.path, pos: p.pos}
+		expr, err := fp.parseOr()
+		if err != nil {
+			return pathSegment{}, err
+		}
+		fp.skipSpace()
+		if !strings.HasPrefix(p.path[fp.pos:], ")]") {
+			return pathSegment{}, fmt.Errorf("unterminated filter at offset %d", open)
+		}
+		p.pos = fp.pos + 2
+		return pathSegment{kind: segmentFilter, filter: expr}, nil
+	}
+
+	for p.pos < len(p.path) && p.path[p.pos] == ' ' {
+		p.pos++
+	}
+	if p.pos < len(p.path) && (p.path[p.pos] == '\'' || p.path[p.pos] == '"') {
+		// A quoted key, which may contain dots and brackets: ['p99.latency'].
+		name, end, err := scanQuoted(p.path, p.pos)
+		if err != nil {
+			return pathSegment{}, err
+		}
+		p.pos = end
+		for p.pos < len(p.path) && p.path[p.pos] == ' ' {
+			p.pos++
+		}
+		if p.pos >= len(p.path) || p.path[p.pos] != ']' {
+			return pathSegment{}, fmt.Errorf("unterminated %q at offset %d", "[", open)
+		}
+		p.pos++
+		return pathSegment{kind: segmentChild, name: name}, nil
+	}
+
+	end := strings.IndexByte(p.path[p.pos:], ']')
+	if end < 0 {
+		return pathSegment{}, fmt.Errorf("unterminated %q at offset %d", "[", open)
+	}
+	content := strings.TrimSpace(p.path[p.pos : p.pos+end])
+	p.pos += end + 1
+
+	if content == "*" {
+		return pathSegment{kind: segmentWildcard}, nil
+	}
+	if !strings.Contains(content, ":") {
+		i, err := strconv.Atoi(content)
+		if err != nil {
+			return pathSegment{}, fmt.Errorf("invalid array index %q", content)
+		}
+		return pathSegment{kind: segmentIndex, index: i}, nil
+	}
+
+	parts := strings.Split(content, ":")
+	if len(parts) > 3 {
+		return pathSegment{}, fmt.Errorf("invalid slice %q", content)
+	}
+	segment := pathSegment{kind: segmentSlice, step: 1}
+	bounds := []**int{&segment.start, &segment.end}
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return pathSegment{}, fmt.Errorf("invalid slice %q", content)
+		}
+		if i < 2 {
+			*bounds[i] = &n
+		} else if n == 0 {
+			return pathSegment{}, fmt.Errorf("slice step cannot be zero in %q", content)
+		} else {
+			segment.step = n
+		}
+	}
+	return segment, nil
+}
+
+// scanQuoted reads the string quoted with ' or " starting at s[pos]. A
+// backslash escapes the next character, e.g. 'it\'s'. It returns the
+// unquoted string and the offset after the closing quote.
+func scanQuoted(s string, pos int) (string, int, error) {
+	quote := s[pos]
+	var b strings.Builder
+	for i := pos + 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s):
+			i++
+			b.WriteByte(s[i])
+		case c == quote:
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string at offset %d", pos)
+}
+
+// --- Filter expressions ---
+
+// filterExpr is a predicate on an array item or object value.
+type filterExpr interface {
+	match(item interface{}) bool
+}
+
+type filterAnd struct{ left, right filterExpr }
+
+func (f filterAnd) match(item interface{}) bool { return f.left.match(item) && f.right.match(item) }
+
+type filterOr struct{ left, right filterExpr }
+
+func (f filterOr) match(item interface{}) bool { return f.left.match(item) || f.right.match(item) }
+
+// filterExists tests that the value at path is present and truthy.
+type filterExists struct{ path *compiledPath }
+
+func (f filterExists) match(item interface{}) bool {
+	return truthy(f.path.evaluate(item))
+}
+
+// filterCompare compares the value at path with a literal.
+type filterCompare struct {
+	path    *compiledPath
+	op      string
+	literal interface{} // string, float64, bool or nil
+}
+
+func (f filterCompare) match(item interface{}) bool {
+	value := f.path.evaluate(item)
+
+	if lit, ok := f.literal.(float64); ok {
+		if _, isString := value.(string); !isString {
+			if n, ok, err := numberValue(value); err == nil && ok {
+				return compareOrdered(n, lit, f.op)
+			}
+		}
+		return f.op == "!="
+	}
+	if lit, ok := f.literal.(string); ok {
+		if s, isString := value.(string); isString {
+			return compareOrdered(s, lit, f.op)
+		}
+		return f.op == "!="
+	}
+
+	// true, false and null only support equality.
+	equal := value == f.literal
+	switch f.op {
+	case "==":
+		return equal
+	case "!=":
+		return !equal
+	}
+	return false
+}
+
+func compareOrdered[T float64 | string](a, b T, op string) bool {
+	switch op {
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
+
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case []interface{}:
+		return len(v) > 0
+	}
+	if n, ok, err := numberValue(value); err == nil && ok {
+		return n != 0
+	}
+	return true
+}
+
+type filterParser struct {
+	s   string
+	pos int
+}
+
+func (p *filterParser) skipSpace() {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *filterParser) consume(token string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.s[p.pos:], token) {
+		p.pos += len(token)
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) parseOr() (filterExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.consume("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = filterOr{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterExpr, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.consume("&&") {
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = filterAnd{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseComparison() (filterExpr, error) {
+	if p.consume("(") {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(")") {
+			return nil, fmt.Errorf("missing %q in filter at offset %d", ")", p.pos)
+		}
+		return expr, nil
+	}
+
+	if !p.consume("@") {
+		return nil, fmt.Errorf("filter must start with %q at offset %d", "@", p.pos)
+	}
+	start := p.pos
+	for p.pos < len(p.s) && !strings.ContainsRune(" =!<>&|)", rune(p.s[p.pos])) {
+		if c := p.s[p.pos]; c == '\'' || c == '"' {
+			_, end, err := scanQuoted(p.s, p.pos) // A quoted key: @['a b']
+			if err != nil {
+				return nil, err
+			}
+			p.pos = end
+			continue
+		}
+		p.pos++
+	}
+	relative := strings.TrimPrefix(p.s[start:p.pos], ".")
+	path, err := (&pathParser{path: relative}).parse()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.consume(op) {
+			literal, err := p.parseLiteral()
+			if err != nil {
+				return nil, err
+			}
+			return filterCompare{path: path, op: op, literal: literal}, nil
+		}
+	}
+	return filterExists{path: path}, nil
+}
+
+func (p *filterParser) parseLiteral() (interface{}, error) {
+	p.skipSpace()
+	if p.pos >= len(p.s) {
+		return nil, fmt.Errorf("missing literal in filter")
+	}
+	if quote := p.s[p.pos]; quote == '\'' || quote == '"' {
+		literal, end, err := scanQuoted(p.s, p.pos)
+		if err != nil {
+			return nil, err
+		}
+		p.pos = end
+		return literal, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.s) && !strings.ContainsRune(" &|)", rune(p.s[p.pos])) {
+		p.pos++
+	}
+	word := p.s[start:p.pos]
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(word, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid literal %q in filter", word)
+	}
+	return f, nil
+}
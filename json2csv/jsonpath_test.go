@@ -0,0 +1,128 @@
+// json2csv/jsonpath_test.go
+
+package json2csv
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// decodeTestJSON decodes s as the conversion does, with json.Number values.
+func decodeTestJSON(t *testing.T, s string) interface{} {
+	t.Helper()
+	decoder := json.NewDecoder(strings.NewReader(s))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		t.Fatalf("decoding %s: %v", s, err)
+	}
+	return v
+}
+
+func TestJSONPath(t *testing.T) {
+	data := decodeTestJSON(t, `{
+		"store": {
+			"name": "corner",
+			"address": {"city": "Oslo", "zip": "0150"},
+			"metrics": {"p99.latency": 12, "with space": true}
+		},
+		"users": {"123": {"name": "ann"}, "-1": {"name": "neg"}},
+		"items": [
+			{"sku": "a", "price": 5, "tags": ["x", "y"]},
+			{"sku": "b", "price": 15, "discount": 0},
+			{"sku": "c", "price": 25, "discount": 3, "meta": {"sku": "c-meta"}},
+			{"sku": "d", "price": null}
+		]
+	}`)
+
+	tests := []struct {
+		path string
+		want string // JSON
+	}{
+		// Keys and indexes resolve to a single value.
+		{"store.name", `"corner"`},
+		{"store.address.city", `"Oslo"`},
+		{"store.missing", `null`},
+		{"store.name.first", `null`},
+		{"store.metrics['p99.latency']", `12`},
+		{`store.metrics["with space"]`, `true`},
+		{"items[0].sku", `"a"`},
+		{"items[-1].sku", `"d"`},
+		{"items[4].sku", `null`},
+		{"items[0].tags[1]", `"y"`},
+		{"users[123].name", `"ann"`},
+		{"users[124].name", `null`},
+		{"users[-1].name", `null`},
+		{"users[*].name", `["neg","ann"]`},
+
+		// Slices.
+		{"items[1:3].sku", `["b","c"]`},
+		{"items[::2].sku", `["a","c"]`},
+		{"items[-2:].sku", `["c","d"]`},
+		{"items[::-1].sku", `["d","c","b","a"]`},
+		{"items[5:].sku", `null`},
+
+		// Wildcards.
+		{"items[*].sku", `["a","b","c","d"]`},
+		{"store.address.*", `["Oslo","0150"]`},
+		{"items[*].discount", `[0,3]`},
+
+		// Recursive descent.
+		{"..city", `["Oslo"]`},
+		{"items..sku", `["a","b","c","c-meta","d"]`},
+
+		// Filters.
+		{"items[?(@.price>10)].sku", `["b","c"]`},
+		{"items[?(@.price<=5)].sku", `["a"]`},
+		{"items[?(@.sku=='c')].price", `[25]`},
+		{`items[?(@.sku!="a" && @.price>=15)].sku`, `["b","c"]`},
+		{"items[?(@.price<10 || @.price>20)].sku", `["a","c"]`},
+		{"items[?(@.discount)].sku", `["c"]`},
+		{"items[?(@.price==null)].sku", `["d"]`},
+		{"items[?(@.price>100)].sku", `null`},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			got, err := getValueByDotPath(data, test.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := decodeTestJSON(t, test.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %s, want %s", fmt.Sprint(got), test.want)
+			}
+		})
+	}
+}
+
+func TestJSONPathInvalid(t *testing.T) {
+	for _, path := range []string{
+		"items[",
+		"items[?(@.price>)]",
+		"items[1:2:0]",
+		"store['name",
+		"items[a]",
+	} {
+		if _, err := compilePath(path); err == nil {
+			t.Errorf("compilePath(%q) succeeded, want an error", path)
+		}
+	}
+}
+
+func TestPathCacheBounded(t *testing.T) {
+	for i := 0; i < pathCacheSize+100; i++ {
+		if _, err := compilePath(fmt.Sprintf("bounded.key%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pathCache.Lock()
+	defer pathCache.Unlock()
+	if n := pathCache.paths.len(); n > pathCacheSize {
+		t.Errorf("path cache holds %d paths, want at most %d", n, pathCacheSize)
+	}
+	if _, ok := pathCache.paths.peek("bounded.key0"); ok {
+		t.Error("the least recently used path was not evicted")
+	}
+}
@@ -0,0 +1,149 @@
+// json2csv/lenient.go
+
+package json2csv
+
+import (
+	"bufio"
+	"io"
+)
+
+// lenientReader filters JSON with comments and trailing commas (see
+// Options.LenientJSON) into strict JSON as it is read. Comments and
+// trailing commas are replaced with spaces, keeping their line breaks, so
+// byte offsets and lines in the filtered input match the original.
+type lenientReader struct {
+	r     *bufio.Reader
+	state lenientState
+	err   error
+
+	// pending holds a comma and the blanks after it until the next token
+	// shows whether the comma is trailing.
+	pending []byte
+	out     []byte // Filtered, not yet returned
+	buf     []byte
+}
+
+type lenientState int
+
+const (
+	lenientCode lenientState = iota
+	lenientString
+	lenientEscape
+	lenientLineComment
+	lenientBlockComment
+)
+
+// lenientChunk is the number of input bytes filtered per fill.
+const lenientChunk = 4096
+
+func newLenientReader(r io.Reader) *lenientReader {
+	return &lenientReader{r: bufio.NewReader(r)}
+}
+
+func (l *lenientReader) Read(p []byte) (int, error) {
+	for len(l.out) == 0 && l.err == nil {
+		l.fill()
+	}
+	if len(l.out) == 0 {
+		return 0, l.err
+	}
+	n := copy(p, l.out)
+	l.out = l.out[n:]
+	return n, nil
+}
+
+// fill filters the next chunk of input into out, which must be empty.
+func (l *lenientReader) fill() {
+	l.out = l.buf[:0]
+	for i := 0; i < lenientChunk; i++ {
+		b, err := l.r.ReadByte()
+		if err != nil {
+			l.flush()
+			l.err = err
+			break
+		}
+		switch l.state {
+		case lenientString:
+			if b == '\\' {
+				l.state = lenientEscape
+			} else if b == '"' {
+				l.state = lenientCode
+			}
+			l.out = append(l.out, b)
+		case lenientEscape:
+			l.state = lenientString
+			l.out = append(l.out, b)
+		case lenientLineComment:
+			if b == '\n' {
+				l.state = lenientCode
+			}
+			l.blank(b)
+		case lenientBlockComment:
+			if next, err := l.r.Peek(1); b == '*' && err == nil && next[0] == '/' {
+				l.r.ReadByte()
+				l.blank(b)
+				b = '/'
+				l.state = lenientCode
+			}
+			l.blank(b)
+		default:
+			l.code(b)
+		}
+	}
+	l.buf = l.out[:0]
+}
+
+// code filters b outside of strings and comments.
+func (l *lenientReader) code(b byte) {
+	switch b {
+	case ' ', '\t', '\r', '\n':
+		l.blank(b)
+	case '/':
+		if next, err := l.r.Peek(1); err == nil && (next[0] == '/' || next[0] == '*') {
+			l.r.ReadByte()
+			if next[0] == '/' {
+				l.state = lenientLineComment
+			} else {
+				l.state = lenientBlockComment
+			}
+			l.blank(b)
+			l.blank(b)
+			return
+		}
+		l.flush()
+		l.out = append(l.out, b)
+	case ',':
+		l.flush()
+		l.pending = append(l.pending, b)
+	case '}', ']':
+		if len(l.pending) > 0 {
+			l.pending[0] = ' ' // A trailing comma
+		}
+		l.flush()
+		l.out = append(l.out, b)
+	case '"':
+		l.state = lenientString
+		fallthrough
+	default:
+		l.flush()
+		l.out = append(l.out, b)
+	}
+}
+
+// blank writes white space in place of b, keeping line breaks.
+func (l *lenientReader) blank(b byte) {
+	if b != '\n' && b != '\r' && b != '\t' {
+		b = ' '
+	}
+	if len(l.pending) > 0 {
+		l.pending = append(l.pending, b)
+	} else {
+		l.out = append(l.out, b)
+	}
+}
+
+// flush writes the pending comma and blanks as they are.
+func (l *lenientReader) flush() {
+	l.out = append(l.out, l.pending...)
+	l.pending = l.pending[:0]
+}
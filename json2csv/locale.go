@@ -0,0 +1,201 @@
+// json2csv/locale.go
+
+package json2csv
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// LocaleNone as a Field.Locale writes the field's numbers unlocalized even
+// if Options.Locale is set, e.g. for ID columns.
+const LocaleNone = "-"
+
+// localePrinter returns the printer for the numbers of field, or nil if
+// they are not localized.
+func localePrinter(field Field, options Options) (*message.Printer, error) {
+	locale := field.Locale
+	if locale == "" {
+		locale = options.Locale
+	}
+	if locale == "" || locale == LocaleNone {
+		return nil, nil
+	}
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return nil, fmt.Errorf("json2csv: field %q: invalid locale %q: %w", field.name(), locale, err)
+	}
+	return message.NewPrinter(tag), nil
+}
+
+// formatLocaleNumber formats value with the decimal and grouping separators
+// of p if it is a number, keeping its fraction digits: with "de",
+// 1234.5 becomes "1.234,5". ok is false for other values, and with exact
+// for json.Number values that float64 cannot represent exactly.
+func formatLocaleNumber(p *message.Printer, value interface{}, exact bool) (cell string, ok bool) {
+	var x interface{}
+	var digits int
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			x = n
+			break
+		}
+		if n, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			x = n
+			break
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return "", false
+		}
+		x = f
+		if s := v.String(); !strings.ContainsAny(s, "eE") {
+			if i := strings.IndexByte(s, '.'); i >= 0 {
+				digits = len(s) - i - 1 // Keep trailing zeros, as in "1.50".
+			}
+			if exact && strconv.FormatFloat(f, 'f', digits, 64) != s {
+				return "", false
+			}
+		} else if exact {
+			return "", false
+		} else {
+			digits = fractionDigits(f)
+		}
+	case float64:
+		x, digits = v, fractionDigits(v)
+	case float32:
+		x, digits = v, fractionDigits(float64(v))
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		x = v
+	default:
+		return "", false
+	}
+	return p.Sprint(number.Decimal(x, number.MinFractionDigits(digits), number.MaxFractionDigits(digits))), true
+}
+
+// fractionDigits returns the number of digits after the decimal point of
+// the shortest representation of f.
+func fractionDigits(f float64) int {
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		return len(s) - i - 1
+	}
+	return 0
+}
+
+// localTimeNames are the month and weekday names of a language, full and
+// abbreviated, starting with January and Sunday.
+type localTimeNames struct {
+	months, shortMonths [12]string
+	days, shortDays     [7]string
+}
+
+var timeNames = map[string]localTimeNames{
+	"de": {
+		months:      [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		shortMonths: [12]string{"Jan", "Feb", "Mär", "Apr", "Mai", "Jun", "Jul", "Aug", "Sep", "Okt", "Nov", "Dez"},
+		days:        [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		shortDays:   [7]string{"So", "Mo", "Di", "Mi", "Do", "Fr", "Sa"},
+	},
+	"es": {
+		months:      [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		shortMonths: [12]string{"ene", "feb", "mar", "abr", "may", "jun", "jul", "ago", "sept", "oct", "nov", "dic"},
+		days:        [7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+		shortDays:   [7]string{"dom", "lun", "mar", "mié", "jue", "vie", "sáb"},
+	},
+	"fr": {
+		months:      [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		shortMonths: [12]string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+		days:        [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+		shortDays:   [7]string{"dim.", "lun.", "mar.", "mer.", "jeu.", "ven.", "sam."},
+	},
+	"it": {
+		months:      [12]string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"},
+		shortMonths: [12]string{"gen", "feb", "mar", "apr", "mag", "giu", "lug", "ago", "set", "ott", "nov", "dic"},
+		days:        [7]string{"domenica", "lunedì", "martedì", "mercoledì", "giovedì", "venerdì", "sabato"},
+		shortDays:   [7]string{"dom", "lun", "mar", "mer", "gio", "ven", "sab"},
+	},
+	"nl": {
+		months:      [12]string{"januari", "februari", "maart", "april", "mei", "juni", "juli", "augustus", "september", "oktober", "november", "december"},
+		shortMonths: [12]string{"jan", "feb", "mrt", "apr", "mei", "jun", "jul", "aug", "sep", "okt", "nov", "dec"},
+		days:        [7]string{"zondag", "maandag", "dinsdag", "woensdag", "donderdag", "vrijdag", "zaterdag"},
+		shortDays:   [7]string{"zo", "ma", "di", "wo", "do", "vr", "za"},
+	},
+	"pt": {
+		months:      [12]string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+		shortMonths: [12]string{"jan", "fev", "mar", "abr", "mai", "jun", "jul", "ago", "set", "out", "nov", "dez"},
+		days:        [7]string{"domingo", "segunda-feira", "terça-feira", "quarta-feira", "quinta-feira", "sexta-feira", "sábado"},
+		shortDays:   [7]string{"dom", "seg", "ter", "qua", "qui", "sex", "sáb"},
+	},
+}
+
+// Placeholders for the names in a layout, from the Unicode private use
+// area; time.Format copies them as is.
+const (
+	placeholderMonth      = "\uE000"
+	placeholderShortMonth = "\uE001"
+	placeholderDay        = "\uE002"
+	placeholderShortDay   = "\uE003"
+)
+
+// layoutNames replaces the month and weekday names of a layout with
+// placeholders, longest first.
+var layoutNames = strings.NewReplacer(
+	"January", placeholderMonth,
+	"Jan", placeholderShortMonth,
+	"Monday", placeholderDay,
+	"Mon", placeholderShortDay,
+)
+
+// FormatTimeLocale is FormatTime with the month and weekday names of the
+// layout ("January", "Jan", "Monday", "Mon") in the language of locale,
+// e.g. FormatTimeLocale("2. January 2006", nil, "de") renders
+// "2024-03-01T12:00:00Z" as "1. März 2024". Languages without built-in
+// names (currently other than de, es, fr, it, nl and pt) keep the English
+// names. An invalid locale fails every value.
+func FormatTimeLocale(layout string, loc *time.Location, locale string) Transformer {
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	tag, err := language.Parse(locale)
+	if err != nil {
+		err = fmt.Errorf("json2csv: FormatTimeLocale: invalid locale %q: %w", locale, err)
+		return func(interface{}, map[string]interface{}) (interface{}, error) { return nil, err }
+	}
+	base, _ := tag.Base()
+	names, localized := timeNames[base.String()]
+	if localized {
+		layout = layoutNames.Replace(layout)
+	}
+	return func(value interface{}, originalRecord map[string]interface{}) (interface{}, error) {
+		t, ok, err := parseTimeValue(value, time.RFC3339, EpochAuto)
+		if err != nil {
+			return nil, fmt.Errorf("json2csv: FormatTimeLocale: %w", err)
+		}
+		if !ok {
+			return nil, nil
+		}
+		t = t.In(loc)
+		s := t.Format(layout)
+		if localized {
+			s = strings.NewReplacer(
+				placeholderMonth, names.months[t.Month()-1],
+				placeholderShortMonth, names.shortMonths[t.Month()-1],
+				placeholderDay, names.days[t.Weekday()],
+				placeholderShortDay, names.shortDays[t.Weekday()],
+			).Replace(s)
+		}
+		return s, nil
+	}
+}
@@ -0,0 +1,114 @@
+// json2csv/lossless.go
+
+package json2csv
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// LosslessNull is the unquoted cell that represents null with Options.Lossless.
+const LosslessNull = `\N`
+
+// The lossless profile (Options.Lossless) writes CSV whose quoting encodes the
+// JSON type of every cell, so the original values can be reconstructed:
+//
+//   - strings are always quoted, including empty strings (""),
+//   - numbers and booleans are never quoted (42, 1.5, true),
+//   - null and missing values are written as LosslessNull (\N).
+//
+// The header row is quoted like strings. Objects and arrays have no lossless
+// representation and return an error; convert them with StringifyJSON to
+// store them as JSON text. time.Time values are written as RFC 3339 strings.
+
+// encodeLosslessCell returns the CSV text of value for the lossless profile.
+// Strings are passed through bidi before quoting.
+func encodeLosslessCell(value interface{}, bidi BiDiMode) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return LosslessNull, nil
+	case string:
+		return quoteLossless(bidi.apply(v)), nil
+	case time.Time:
+		return quoteLossless(v.Format(time.RFC3339Nano)), nil
+	case map[string]interface{}, []interface{}:
+		return "", fmt.Errorf("%T values cannot be written losslessly, use StringifyJSON", value)
+	default:
+		// Numbers and booleans, whose string form needs no quoting.
+		return valueToString(value), nil
+	}
+}
+
+func quoteLossless(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// decodeLosslessRow returns the plain cell values of an encoded row, with
+// null as the empty string, for the column statistics.
+func decodeLosslessRow(row []string) []string {
+	decoded := make([]string, len(row))
+	for i, cell := range row {
+		decoded[i] = decodeLosslessCell(cell)
+	}
+	return decoded
+}
+
+// decodeLosslessCell returns the plain value of an encoded cell, with null
+// as the empty string.
+func decodeLosslessCell(cell string) string {
+	switch {
+	case cell == LosslessNull:
+		return ""
+	case strings.HasPrefix(cell, `"`):
+		return strings.ReplaceAll(cell[1:len(cell)-1], `""`, `"`)
+	default:
+		return cell
+	}
+}
+
+// losslessRowWriter writes rows of cells already encoded by
+// encodeLosslessCell, so it never adds quotes itself.
+type losslessRowWriter struct {
+	w       *bufio.Writer
+	comma   rune
+	comment string // See headerComment
+}
+
+func newLosslessRowWriter(w io.Writer, comma rune, bufferSize int) *losslessRowWriter {
+	return &losslessRowWriter{w: bufio.NewWriterSize(w, bufferSize), comma: comma}
+}
+
+func (l *losslessRowWriter) WriteHeader(header []string) error {
+	quoted := make([]string, len(header))
+	for i, h := range header {
+		quoted[i] = quoteLossless(h)
+	}
+	if _, err := l.w.WriteString(l.comment); err != nil {
+		return err
+	}
+	return l.WriteRow(quoted)
+}
+
+func (l *losslessRowWriter) WriteRow(row []string) error {
+	if l.comma == 0 || l.comma == '"' || l.comma == '\r' || l.comma == '\n' || l.comma == '\\' ||
+		!utf8.ValidRune(l.comma) || l.comma == utf8.RuneError {
+		return errors.New("invalid field delimiter")
+	}
+	for i, cell := range row {
+		if i > 0 {
+			l.w.WriteRune(l.comma)
+		}
+		l.w.WriteString(cell)
+	}
+	_, err := l.w.WriteString("\n")
+	return err
+}
+
+func (l *losslessRowWriter) Flush() error { return l.w.Flush() }
+
+func (l *losslessRowWriter) Close() error { return l.w.Flush() }
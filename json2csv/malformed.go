@@ -0,0 +1,224 @@
+// json2csv/malformed.go
+
+package json2csv
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrMalformedRecord is wrapped by the error of a conversion that read a
+// syntactically broken JSON record.
+var ErrMalformedRecord = errors.New("json2csv: malformed json record")
+
+// MalformedRecordPolicy selects what happens to syntactically broken
+// records.
+type MalformedRecordPolicy int
+
+const (
+	// MalformedRecordsError fails the conversion, or skips the rest of the
+	// source under ErrorPolicySkipSource (the default).
+	MalformedRecordsError MalformedRecordPolicy = iota
+
+	// MalformedRecordsSkip skips the record and continues at the next
+	// record, counting it in Report.RecordsMalformed. The end of the broken
+	// record is found by following its brackets and strings, so records
+	// after it on the same line are kept, as in minified arrays. If these
+	// are broken too, such as a string that is not closed, the record ends
+	// at the next line starting a record, indented like the broken one if
+	// that is known. If the input of an array ends before the broken
+	// record does, the conversion fails.
+	MalformedRecordsSkip
+)
+
+// malformedRecordError marks the error of decoding a syntactically broken
+// record, keeping its message.
+type malformedRecordError struct{ err error }
+
+func (e malformedRecordError) Error() string        { return e.err.Error() }
+func (e malformedRecordError) Unwrap() error        { return e.err }
+func (e malformedRecordError) Is(target error) bool { return target == ErrMalformedRecord }
+
+// decodeError wraps the error of decoding a record, marking syntax errors
+// and truncated records as malformed.
+func decodeError(err error) error {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) || err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+		err = malformedRecordError{err}
+	}
+	return fmt.Errorf("json2csv: failed to decode json object: %w", err)
+}
+
+// skipMalformed reads the rest of a broken element from r up to the next
+// record boundary and returns the number of bytes read. array reports
+// whether the elements are those of an array, and indent is the indentation
+// of the broken element, or negative if unknown.
+//
+// The boundary is found by following the brackets and strings of the
+// element: it ends when its brackets are balanced, and the next element
+// starts after the following comma in an array, or at the following '{' in
+// a stream of objects; the closing ']' of an array is left unread. A line
+// break inside a string, or inside unbalanced brackets where the next line
+// starts a record at indentation indent or closes the array (see
+// atBoundary), shows that the element itself is broken: it ends at that
+// line, leaving the line break unread. If the element does not end before
+// the input does, skipMalformed returns io.EOF for a stream of objects,
+// whose last record was truncated, and io.ErrUnexpectedEOF for an array,
+// since any records after the broken one would be lost.
+func skipMalformed(r *bufio.Reader, indent int, array bool) (int64, error) {
+	var (
+		n        int64
+		depth    int
+		inString bool
+		escaped  bool
+		broken   bool // The brackets and strings can no longer be followed.
+		started  bool
+	)
+	eof := func(err error) (int64, error) {
+		if err == io.EOF && array {
+			err = io.ErrUnexpectedEOF
+		}
+		return n, err
+	}
+	for { // The start of the element
+		peek, err := r.Peek(1)
+		if err != nil {
+			return eof(err)
+		}
+		if b := peek[0]; b != ' ' && b != '\t' && b != '\r' && b != '\n' && b != ',' {
+			break
+		}
+		r.ReadByte()
+		n++
+	}
+	for {
+		peek, err := r.Peek(1)
+		if err == io.EOF && array && started && depth == 0 && !inString && !broken {
+			return n, nil // The decoder reports the missing ']'.
+		} else if err != nil {
+			return eof(err)
+		}
+		b := peek[0]
+		switch {
+		case b == '\n' && (inString || broken || depth > 0 && (!array || indent >= 0)):
+			if atBoundary(r, indent, array) {
+				return n, nil
+			}
+			if inString {
+				broken = true
+			}
+		case broken:
+		case inString:
+			switch {
+			case escaped:
+				escaped = false
+			case b == '\\':
+				escaped = true
+			case b == '"':
+				inString = false
+			}
+		case b == '"':
+			inString = true
+		case b == '{' || b == '[':
+			if depth == 0 && started && !array {
+				return n, nil
+			}
+			depth++
+		case b == '}' || b == ']':
+			if depth == 0 && b == ']' && array {
+				return n, nil
+			}
+			if depth > 0 {
+				depth--
+			}
+		case b == ',' && depth == 0 && array:
+			r.ReadByte()
+			return n + 1, nil
+		}
+		started = true
+		r.ReadByte()
+		n++
+	}
+}
+
+// atBoundary reports whether the line after the line break at the start of
+// r starts a record at indentation indent, or closes an array.
+func atBoundary(r *bufio.Reader, indent int, array bool) bool {
+	for column := 0; ; column++ {
+		peek, err := r.Peek(column + 2)
+		if err != nil {
+			return false
+		}
+		switch peek[column+1] {
+		case ' ', '\t':
+			continue
+		case '{':
+			return indent < 0 || column == indent
+		case ']':
+			return array && (indent < 0 || column < indent)
+		}
+		return false
+	}
+}
+
+// lineIndent returns the indentation of the element following the white
+// space and array comma at the start of r, or -1 if r does not include the
+// start of its line.
+func lineIndent(r io.Reader) int {
+	var (
+		buf    [1]byte
+		column int
+		indent = -1
+	)
+	for {
+		if _, err := r.Read(buf[:]); err != nil {
+			return -1
+		}
+		switch buf[0] {
+		case '\n':
+			indent, column = 0, 0
+		case ' ', '\t':
+			column++
+		case '\r', ',':
+		default:
+			if indent < 0 {
+				return -1
+			}
+			return column
+		}
+		if indent >= 0 {
+			indent = column
+		}
+	}
+}
+
+// skipRecord is the skipElement function of the records of a conversion:
+// it reports whether the record that failed with err is to be skipped,
+// counting it.
+func (c *conversion) skipRecord(err error) bool {
+	switch {
+	case errors.Is(err, ErrRecordTooLarge) && c.options.OversizedRecords == OversizedRecordsSkip:
+		if !c.discoverKeys && c.report != nil {
+			c.report.RecordsOversized++
+		}
+	case errors.Is(err, ErrMalformedRecord) && c.options.MalformedRecords == MalformedRecordsSkip:
+		if !c.discoverKeys {
+			if c.report != nil {
+				c.report.RecordsMalformed++
+			}
+			if c.options.OnMalformedRecord != nil {
+				c.options.OnMalformedRecord(c.locate(err))
+			}
+		}
+	default:
+		return false
+	}
+	if !c.discoverKeys {
+		c.metrics.IncErrors(1)
+	}
+	c.endRecord()
+	return true
+}
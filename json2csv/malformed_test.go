@@ -0,0 +1,184 @@
+// json2csv/malformed_test.go
+
+package json2csv_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pradnyoday/go-json2csv/json2csv"
+)
+
+func TestMalformedRecordsSkip(t *testing.T) {
+	options := json2csv.Options{
+		Fields: []json2csv.Field{
+			{JSONPath: "id", CSVHeader: "id"},
+			{JSONPath: "tags[*].tag", CSVHeader: "tag"},
+		},
+		MalformedRecords: json2csv.MalformedRecordsSkip,
+	}
+
+	tests := []struct {
+		name      string
+		input     string
+		want      string // The ids written
+		malformed int
+		fail      bool
+	}{
+		{
+			name:      "minified",
+			input:     `[{"id":1,"tags":[{}]},{"id":2 bad},{"id":3,"tags":[{}]},{"id":4,"tags":[{}]}]`,
+			want:      "1,3,4",
+			malformed: 1,
+		},
+		{
+			name:      "minified with nested brackets",
+			input:     `[{"id":1,"tags":[{}]},{"id":2,"tags":["]",x],"m":{"}":1}},{"id":3,"tags":[{}]}]`,
+			want:      "1,3",
+			malformed: 1,
+		},
+		{
+			name:      "minified garbage element",
+			input:     `[{"id":1,"tags":[{}]},nope,{"id":3,"tags":[{}]}]`,
+			want:      "1,3",
+			malformed: 1,
+		},
+		{
+			name:      "minified last element",
+			input:     `[{"id":1,"tags":[{}]},{"id":2 bad}]`,
+			want:      "1",
+			malformed: 1,
+		},
+		{
+			name:      "minified unclosed string",
+			input:     `[{"id":1,"tags":[{}]},{"id":2,"tags":["b},{"id":3,"tags":[{}]}]`,
+			want:      "1",
+			malformed: 1,
+			fail:      true,
+		},
+		{
+			name:      "minified truncated",
+			input:     `[{"id":1,"tags":[{}]},{"id":2,"tags":[`,
+			want:      "1",
+			malformed: 1,
+			fail:      true,
+		},
+		{
+			name: "pretty-printed",
+			input: `[
+  {
+    "id": 1,
+    "tags": [{}]
+  },
+  {
+    "id": 2 bad,
+    "tags": [{}]
+  },
+  {
+    "id": 3,
+    "tags": [{}]
+  }
+]
+`,
+			want:      "1,3",
+			malformed: 1,
+		},
+		{
+			name: "pretty-printed unclosed brackets",
+			input: `[
+  {
+    "id": 1,
+    "tags": [{}]
+  },
+  {
+    "id": 2,
+    "tags": ["b",
+  {
+    "id": 3,
+    "tags": [{}]
+  }
+]
+`,
+			want:      "1,3",
+			malformed: 1,
+		},
+		{
+			name: "pretty-printed unclosed string",
+			input: `[
+  {
+    "id": 1,
+    "tags": [{}]
+  },
+  {
+    "id": 2,
+    "tags": ["b]
+  },
+  {
+    "id": 3,
+    "tags": [{}]
+  }
+]
+`,
+			want:      "1,3",
+			malformed: 1,
+		},
+		{
+			name: "ndjson",
+			input: `{"id":1,"tags":[{}]}
+{"id":2 bad}
+{"id":3,"tags":[{}]}
+`,
+			want:      "1,3",
+			malformed: 1,
+		},
+		{
+			name: "ndjson unclosed string",
+			input: `{"id":1,"tags":[{}]}
+{"id":2,"tags":["b]}
+{"id":3,"tags":[{}]}
+`,
+			want:      "1,3",
+			malformed: 1,
+		},
+		{
+			name: "ndjson unclosed brackets",
+			input: `{"id":1,"tags":[{}]}
+{"id":2,"tags":["b"
+{"id":3,"tags":[{}]}
+`,
+			want:      "1,3",
+			malformed: 1,
+		},
+		{
+			name: "ndjson truncated",
+			input: `{"id":1,"tags":[{}]}
+{"id":2,"tags":["b"`,
+			want:      "1",
+			malformed: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out strings.Builder
+			report, err := json2csv.ConvertWithReport(strings.NewReader(test.input), &out, options)
+			if test.fail {
+				if !errors.Is(err, json2csv.ErrMalformedRecord) {
+					t.Errorf("got error %v, want ErrMalformedRecord", err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n")[1:] {
+				ids = append(ids, strings.Split(line, ",")[0])
+			}
+			if got := strings.Join(ids, ","); got != test.want {
+				t.Errorf("ids = %q, want %q", got, test.want)
+			}
+			if report.RecordsMalformed != test.malformed {
+				t.Errorf("RecordsMalformed = %d, want %d", report.RecordsMalformed, test.malformed)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+// json2csv/metrics.go
+
+package json2csv
+
+import "time"
+
+// Metrics receives the progress of conversions as they run (see
+// Options.Metrics), so that production pipelines can monitor throughput
+// and error rates without wrapping the input and output. Its methods are
+// called from the converting goroutine, once per record or row, so they
+// should be cheap; a Metrics shared by concurrent conversions must be safe
+// for concurrent use.
+//
+// An adapter for the Prometheus client library could read:
+//
+//	type promMetrics struct {
+//		records, rows, errors prometheus.Counter
+//		duration              prometheus.Observer
+//	}
+//
+//	func (m promMetrics) IncRecords(n int)                { m.records.Add(float64(n)) }
+//	func (m promMetrics) IncRows(n int)                   { m.rows.Add(float64(n)) }
+//	func (m promMetrics) IncErrors(n int)                 { m.errors.Add(float64(n)) }
+//	func (m promMetrics) ObserveDuration(d time.Duration) { m.duration.Observe(d.Seconds()) }
+//
+// with the counters and a histogram created by promauto.
+type Metrics interface {
+	// IncRecords counts input records read.
+	IncRecords(n int)
+
+	// IncRows counts data rows written.
+	IncRows(n int)
+
+	// IncErrors counts errors: field errors handled by a Field.OnError
+	// policy, invalid items handled by Options.InvalidItems, oversized and
+	// malformed records skipped, sources skipped under
+	// ErrorPolicySkipSource and failed conversions.
+	IncErrors(n int)
+
+	// ObserveDuration receives the wall-clock time of each conversion,
+	// whether it succeeded or not.
+	ObserveDuration(d time.Duration)
+}
+
+// NopMetrics is a Metrics that discards everything, the default.
+type NopMetrics struct{}
+
+func (NopMetrics) IncRecords(n int)                {}
+func (NopMetrics) IncRows(n int)                   {}
+func (NopMetrics) IncErrors(n int)                 {}
+func (NopMetrics) ObserveDuration(d time.Duration) {}
@@ -0,0 +1,144 @@
+// json2csv/naming.go
+
+package json2csv
+
+import (
+	"strings"
+	"unicode"
+)
+
+// HeaderNaming derives the header of a Field without a CSVHeader from its
+// JSONPath. Array selectors such as "[*]" are ignored.
+type HeaderNaming int
+
+const (
+	// HeaderNamingNone leaves headers empty (the default).
+	HeaderNamingNone HeaderNaming = iota
+
+	// HeaderNamingPath uses the dot path: "items[*].unitPrice" becomes
+	// "items.unitPrice".
+	HeaderNamingPath
+
+	// HeaderNamingLastSegment uses the last key: "unitPrice".
+	HeaderNamingLastSegment
+
+	// HeaderNamingSnakeCase joins the words of all keys with underscores:
+	// "items_unit_price".
+	HeaderNamingSnakeCase
+
+	// HeaderNamingCamelCase joins the words of all keys in camel case:
+	// "itemsUnitPrice".
+	HeaderNamingCamelCase
+)
+
+// header returns the header derived from path.
+func (naming HeaderNaming) header(path string) string {
+	if naming == HeaderNamingNone {
+		return ""
+	}
+	keys := pathKeys(path)
+	if len(keys) == 0 {
+		return ""
+	}
+
+	switch naming {
+	case HeaderNamingPath:
+		return strings.Join(keys, ".")
+	case HeaderNamingLastSegment:
+		return keys[len(keys)-1]
+	}
+
+	var words []string
+	for _, key := range keys {
+		words = append(words, splitWords(key)...)
+	}
+	for i, word := range words {
+		word = strings.ToLower(word)
+		if naming == HeaderNamingCamelCase && i > 0 {
+			runes := []rune(word)
+			runes[0] = unicode.ToUpper(runes[0])
+			word = string(runes)
+		}
+		words[i] = word
+	}
+	if naming == HeaderNamingCamelCase {
+		return strings.Join(words, "")
+	}
+	return strings.Join(words, "_")
+}
+
+// HeaderCase converts the case of the header cells, for ingest systems that
+// expect e.g. upper-case column names.
+type HeaderCase int
+
+const (
+	// HeaderCaseAsIs leaves the header cells unchanged (the default).
+	HeaderCaseAsIs HeaderCase = iota
+
+	// HeaderCaseUpper converts the header cells to upper case.
+	HeaderCaseUpper
+
+	// HeaderCaseLower converts the header cells to lower case.
+	HeaderCaseLower
+)
+
+// apply returns header converted to the case.
+func (hc HeaderCase) apply(header string) string {
+	switch hc {
+	case HeaderCaseUpper:
+		return strings.ToUpper(header)
+	case HeaderCaseLower:
+		return strings.ToLower(header)
+	default:
+		return header
+	}
+}
+
+// pathKeys returns the object keys named in path, in order.
+func pathKeys(path string) []string {
+	if isPseudoPath(path) {
+		return []string{strings.TrimPrefix(path, "$")}
+	}
+	compiled, err := compilePath(path)
+	if err != nil {
+		return nil
+	}
+	var keys []string
+	for _, segment := range compiled.segments {
+		if segment.kind == segmentChild {
+			keys = append(keys, segment.name)
+		}
+	}
+	return keys
+}
+
+// splitWords splits a key such as "unitPrice", "unit_price" or "HTTPStatus"
+// into its words.
+func splitWords(key string) []string {
+	var words []string
+	var current []rune
+	runes := []rune(key)
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, string(current))
+			current = nil
+		}
+	}
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && len(current) > 0:
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			// Split "unitPrice" before "P" and "HTTPStatus" before "S".
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+	return words
+}
@@ -0,0 +1,46 @@
+// json2csv/newlines.go
+
+package json2csv
+
+import "strings"
+
+// NewlinePolicy selects how line breaks within cells are written (see
+// Options.Newlines), for loaders that cannot read quoted multi-line CSV
+// fields.
+type NewlinePolicy int
+
+const (
+	// NewlinesKeep writes line breaks as they are, quoting the cell (the
+	// default).
+	NewlinesKeep NewlinePolicy = iota
+
+	// NewlinesReplace replaces each line break ("\r\n", "\n" or "\r") with
+	// Options.NewlineReplacement, e.g. " " or " | ".
+	NewlinesReplace
+
+	// NewlinesEscape writes line breaks as the two characters `\n` and
+	// `\r`, and backslashes as `\\`, so the original text can be restored.
+	NewlinesEscape
+)
+
+// newlineReplacer returns the replacer applying options.Newlines, or nil
+// for NewlinesKeep.
+func (options Options) newlineReplacer() *strings.Replacer {
+	switch options.Newlines {
+	case NewlinesReplace:
+		r := options.NewlineReplacement
+		return strings.NewReplacer("\r\n", r, "\n", r, "\r", r)
+	case NewlinesEscape:
+		return strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`)
+	default:
+		return nil
+	}
+}
+
+// replaceNewlines applies Options.Newlines to cell.
+func (c *conversion) replaceNewlines(cell string) string {
+	if c.newlines == nil {
+		return cell
+	}
+	return c.newlines.Replace(cell)
+}
@@ -0,0 +1,315 @@
+// json2csv/output.go
+
+package json2csv
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// RowWriter receives the header and data rows produced by a conversion, with
+// every cell already converted to a string. Convert writes to the RowWriter
+// for Options.Format (see NewRowWriter); ConvertTo and ConvertSourcesTo
+// accept any implementation, such as a spreadsheet, Parquet or database
+// bulk-insert sink, a TeeSink or a RowBuffer.
+type RowWriter interface {
+	// WriteHeader writes the header row. It is called at most once, before
+	// any data row, and not at all when the header is disabled.
+	WriteHeader(header []string) error
+
+	// WriteRow writes a single data row. The slice must not be retained.
+	WriteRow(row []string) error
+
+	// Flush pushes any buffered output to the underlying writer.
+	Flush() error
+
+	// Close finishes the output (e.g. writes closing HTML tags) and flushes
+	// it. It is called once after the last row of a successful conversion.
+	Close() error
+}
+
+// NewRowWriter returns the RowWriter that Convert uses for options.Format,
+// writing to w. It can be combined with other sinks, e.g. in a TeeSink.
+func NewRowWriter(w io.Writer, options Options) RowWriter {
+	comment := headerComment(options)
+	switch options.Format {
+	case FormatMarkdown:
+		return &markdownRowWriter{w: bufio.NewWriterSize(w, options.WriterBufferSize), comment: comment}
+	case FormatHTML:
+		return &htmlRowWriter{w: bufio.NewWriterSize(w, options.WriterBufferSize), comment: comment}
+	default:
+		if options.Lossless {
+			l := newLosslessRowWriter(w, options.delimiter(), options.WriterBufferSize)
+			l.comment = comment
+			return l
+		}
+		if options.WriterBufferSize <= 0 {
+			return &csvRowWriter{w: options.newCSVWriter(w), raw: w, comment: comment}
+		}
+		// csv.Writer reuses buf as its own buffer if it is at least as
+		// large as the default; smaller buffers must be flushed separately.
+		buf := bufio.NewWriterSize(w, options.WriterBufferSize)
+		return &csvRowWriter{w: options.newCSVWriter(buf), buf: buf, raw: buf, comment: comment}
+	}
+}
+
+// CSVWriterFactory creates a csv.Writer writing to w, configured by the
+// caller (see Options.CSVWriter).
+type CSVWriterFactory func(w io.Writer) *csv.Writer
+
+// newCSVWriter returns the csv.Writer of FormatCSV output to w, from
+// o.CSVWriter if set, with the Delimiter applied.
+func (o Options) newCSVWriter(w io.Writer) *csv.Writer {
+	if o.CSVWriter == nil {
+		writer := csv.NewWriter(w)
+		writer.Comma = o.delimiter()
+		return writer
+	}
+	writer := o.CSVWriter(w)
+	if o.Delimiter != 0 {
+		writer.Comma = o.Delimiter
+	}
+	return writer
+}
+
+// NewCSVRowWriter returns a RowWriter writing CSV with writer, e.g. one
+// configured by the caller, for ConvertTo. It does not write
+// Options.HeaderComment.
+func NewCSVRowWriter(writer *csv.Writer) RowWriter {
+	return &csvRowWriter{w: writer}
+}
+
+// headerComment returns the lines of options.HeaderComment formatted as
+// comments of options.Format, or "" if there is none.
+func headerComment(options Options) string {
+	if options.HeaderComment == "" {
+		return ""
+	}
+	prefix := options.CommentPrefix
+	if prefix == "" {
+		prefix = DefaultCommentPrefix
+	}
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimSuffix(options.HeaderComment, "\n"), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		switch options.Format {
+		case FormatMarkdown, FormatHTML:
+			// "--" cannot occur within an HTML comment.
+			b.WriteString("<!-- " + strings.ReplaceAll(line, "--", "- -") + " -->\n")
+		default:
+			b.WriteString(prefix + line + "\n")
+		}
+	}
+	return b.String()
+}
+
+// RowBuffer is a RowWriter that keeps the header and rows in memory, e.g.
+// to capture the output of ConvertTo in tests or to feed another sink.
+type RowBuffer struct {
+	Header []string   // nil if no header was written
+	Rows   [][]string // data rows
+}
+
+// WriteHeader stores a copy of header.
+func (b *RowBuffer) WriteHeader(header []string) error {
+	b.Header = append([]string{}, header...)
+	return nil
+}
+
+// WriteRow appends a copy of row.
+func (b *RowBuffer) WriteRow(row []string) error {
+	b.Rows = append(b.Rows, append([]string{}, row...))
+	return nil
+}
+
+// Flush does nothing.
+func (b *RowBuffer) Flush() error { return nil }
+
+// Close does nothing.
+func (b *RowBuffer) Close() error { return nil }
+
+// OutputWrapper wraps the io.Writer passed to Convert, e.g. to compress or
+// encrypt the output as it is written (see the encrypt sub-package).
+type OutputWrapper func(w io.Writer) (io.WriteCloser, error)
+
+// withOutput calls fn with w, wrapped by options.WrapOutput if set. The
+// wrapper is closed when fn returns, also on failure, so that e.g. an
+// encrypted stream is always terminated; the first error is returned.
+func withOutput(w io.Writer, options Options, fn func(w io.Writer) error) error {
+	if options.WrapOutput == nil {
+		return fn(w)
+	}
+	wrapped, err := options.WrapOutput(w)
+	if err != nil {
+		return fmt.Errorf("json2csv: failed to wrap output writer: %w", err)
+	}
+	err = fn(wrapped)
+	if closeErr := wrapped.Close(); closeErr != nil && err == nil {
+		err = fmt.Errorf("json2csv: failed to close wrapped output writer: %w", closeErr)
+	}
+	return err
+}
+
+// --- CSV ---
+
+type csvRowWriter struct {
+	w       *csv.Writer
+	buf     *bufio.Writer // nil unless Options.WriterBufferSize is set
+	raw     io.Writer     // The writer of w, for comment
+	comment string        // See headerComment
+}
+
+func (c *csvRowWriter) WriteHeader(header []string) error {
+	if c.comment != "" {
+		c.w.Flush()
+		if err := c.w.Error(); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(c.raw, c.comment); err != nil {
+			return err
+		}
+	}
+	return c.w.Write(header)
+}
+
+func (c *csvRowWriter) WriteRow(row []string) error { return c.w.Write(row) }
+
+func (c *csvRowWriter) Flush() error {
+	c.w.Flush()
+	if err := c.w.Error(); err != nil || c.buf == nil {
+		return err
+	}
+	return c.buf.Flush()
+}
+
+func (c *csvRowWriter) Close() error { return c.Flush() }
+
+// --- Markdown ---
+
+// markdownRowWriter writes a GitHub-flavored Markdown table. Markdown tables
+// cannot omit the header, so if WriteRow is called first an empty header of
+// the same width is written.
+type markdownRowWriter struct {
+	w             *bufio.Writer
+	comment       string // See headerComment
+	headerWritten bool
+}
+
+func (m *markdownRowWriter) WriteHeader(header []string) error {
+	m.headerWritten = true
+	if _, err := m.w.WriteString(m.comment); err != nil {
+		return err
+	}
+	if err := m.writeLine(header); err != nil {
+		return err
+	}
+	separator := make([]string, len(header))
+	for i := range separator {
+		separator[i] = "---"
+	}
+	_, err := m.w.WriteString("| " + strings.Join(separator, " | ") + " |\n")
+	return err
+}
+
+func (m *markdownRowWriter) WriteRow(row []string) error {
+	if !m.headerWritten {
+		if err := m.WriteHeader(make([]string, len(row))); err != nil {
+			return err
+		}
+	}
+	return m.writeLine(row)
+}
+
+func (m *markdownRowWriter) writeLine(cells []string) error {
+	escaped := make([]string, len(cells))
+	for i, cell := range cells {
+		escaped[i] = escapeMarkdownCell(cell)
+	}
+	_, err := m.w.WriteString("| " + strings.Join(escaped, " | ") + " |\n")
+	return err
+}
+
+func (m *markdownRowWriter) Flush() error { return m.w.Flush() }
+
+func (m *markdownRowWriter) Close() error { return m.w.Flush() }
+
+// markdownCellReplacer escapes characters that would break a table cell.
+var markdownCellReplacer = strings.NewReplacer(
+	`\`, `\\`,
+	"|", `\|`,
+	"\r\n", "<br>",
+	"\n", "<br>",
+	"\r", "<br>",
+)
+
+func escapeMarkdownCell(cell string) string {
+	return markdownCellReplacer.Replace(cell)
+}
+
+// --- HTML ---
+
+// htmlRowWriter writes a single <table> element. The header (if any) goes in
+// <thead>, data rows in <tbody>; close writes the closing tags.
+type htmlRowWriter struct {
+	w       *bufio.Writer
+	comment string // See headerComment
+	inBody  bool
+}
+
+func (h *htmlRowWriter) WriteHeader(header []string) error {
+	if _, err := h.w.WriteString(h.comment + "<table>\n<thead>\n"); err != nil {
+		return err
+	}
+	if err := h.writeCells("th", header); err != nil {
+		return err
+	}
+	_, err := h.w.WriteString("</thead>\n<tbody>\n")
+	h.inBody = true
+	return err
+}
+
+func (h *htmlRowWriter) WriteRow(row []string) error {
+	if err := h.openBody(); err != nil {
+		return err
+	}
+	return h.writeCells("td", row)
+}
+
+// openBody writes the table and tbody start tags when no header was written.
+func (h *htmlRowWriter) openBody() error {
+	if h.inBody {
+		return nil
+	}
+	h.inBody = true
+	_, err := h.w.WriteString("<table>\n<tbody>\n")
+	return err
+}
+
+func (h *htmlRowWriter) writeCells(tag string, cells []string) error {
+	var b strings.Builder
+	b.WriteString("<tr>")
+	for _, cell := range cells {
+		b.WriteString("<" + tag + ">")
+		b.WriteString(html.EscapeString(cell))
+		b.WriteString("</" + tag + ">")
+	}
+	b.WriteString("</tr>\n")
+	_, err := h.w.WriteString(b.String())
+	return err
+}
+
+func (h *htmlRowWriter) Flush() error { return h.w.Flush() }
+
+func (h *htmlRowWriter) Close() error {
+	if err := h.openBody(); err != nil {
+		return err
+	}
+	if _, err := h.w.WriteString("</tbody>\n</table>\n"); err != nil {
+		return err
+	}
+	return h.w.Flush()
+}
@@ -0,0 +1,151 @@
+// json2csv/oversized.go
+
+package json2csv
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrRecordTooLarge is wrapped by the error of a conversion that read a
+// record larger than Options.MaxRecordBytes.
+var ErrRecordTooLarge = errors.New("json2csv: record exceeds MaxRecordBytes")
+
+// OversizedRecordPolicy selects what happens to records larger than
+// Options.MaxRecordBytes.
+type OversizedRecordPolicy int
+
+const (
+	// OversizedRecordsError fails the conversion, or skips the source under
+	// ErrorPolicySkipSource (the default).
+	OversizedRecordsError OversizedRecordPolicy = iota
+
+	// OversizedRecordsSkip skips the record, without decoding the rest of
+	// it, and counts it in Report.RecordsOversized.
+	OversizedRecordsSkip
+)
+
+// recordLimiter reads the input of a jsonStream, failing with
+// ErrRecordTooLarge once the element being decoded, starting at offset
+// start, cannot fit in max bytes. The decoder only reads while the element
+// is incomplete, so an element is rejected before more than max bytes of
+// it are buffered.
+type recordLimiter struct {
+	r     io.Reader
+	max   int64
+	read  int64 // Bytes read from r
+	start int64 // -1 between elements
+}
+
+// recordLimiterChunk bounds the reads of a recordLimiter, and so the bytes
+// read ahead of an element.
+const recordLimiterChunk = 32 << 10
+
+func (l *recordLimiter) Read(p []byte) (int, error) {
+	if l.start >= 0 && l.read-l.start >= l.max {
+		return 0, ErrRecordTooLarge
+	}
+	if len(p) > recordLimiterChunk {
+		p = p[:recordLimiterChunk]
+	}
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	return n, err
+}
+
+// resync skips the element whose decoding failed with err, scanning the
+// input without buffering it, and restarts the decoder at the next element:
+// after the balanced brackets of an oversized element, or at the next
+// record boundary after a malformed one (see skipMalformed). If the element
+// cannot be skipped, the error says why, wrapping cause.
+func (s *jsonStream) resync(cause error) error {
+	rest := bufio.NewReader(io.MultiReader(s.decoder.Buffered(), s.br))
+	offset := s.skipped + s.decoder.InputOffset()
+	var (
+		n   int64
+		err error
+	)
+	oversized := errors.Is(cause, ErrRecordTooLarge)
+	if oversized {
+		n, err = skipValue(rest)
+	} else {
+		n, err = skipMalformed(rest, s.indent, !s.objects)
+	}
+	offset += n
+	if err == io.EOF {
+		s.done = true // Nothing follows to recover.
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("%w (failed to skip it: %v)", cause, err)
+	}
+
+	s.br = rest
+	if s.objects {
+		s.decoder = json.NewDecoder(rest)
+		s.decoder.UseNumber()
+		s.skipped = offset
+		return nil
+	}
+	// Continue the array after the separating comma, as if it started
+	// there.
+	if oversized {
+		next, skipped, err := peekNonSpace(rest)
+		offset += int64(skipped)
+		if err == nil && next == ',' {
+			rest.ReadByte()
+			offset++
+		}
+	}
+	s.decoder = json.NewDecoder(io.MultiReader(strings.NewReader("["), rest))
+	s.decoder.UseNumber()
+	s.skipped = offset - 1
+	_, err = s.decoder.Token()
+	return err
+}
+
+// skipValue reads the JSON object or array at the start of r, after any
+// white space or array comma, and returns the number of bytes read. It only
+// checks that brackets and quotes are balanced.
+func skipValue(r *bufio.Reader) (int64, error) {
+	var (
+		n        int64
+		depth    int
+		inString bool
+		escaped  bool
+	)
+	for {
+		b, err := r.ReadByte()
+		if err == io.EOF {
+			return n, io.ErrUnexpectedEOF
+		} else if err != nil {
+			return n, err
+		}
+		n++
+		switch {
+		case inString:
+			switch {
+			case escaped:
+				escaped = false
+			case b == '\\':
+				escaped = true
+			case b == '"':
+				inString = false
+			}
+		case b == '"':
+			inString = true
+		case b == '{' || b == '[':
+			depth++
+		case b == '}' || b == ']':
+			depth--
+			if depth <= 0 {
+				return n, nil
+			}
+		case depth == 0 && b != ' ' && b != '\t' && b != '\r' && b != '\n' && b != ',':
+			return n, fmt.Errorf("unexpected %q at start of record", b)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// json2csv/paged.go
+
+package json2csv
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// PageSource yields the pages of a paginated API for ConvertPaged.
+type PageSource interface {
+	// NextPage returns the body of the next page, holding a JSON array or
+	// stream of objects, and true; or false once all pages have been read.
+	// Each page is read to the end (and closed, if it is an io.Closer)
+	// before NextPage is called again, so a cursor can be taken from it.
+	NextPage(ctx context.Context) (io.Reader, bool, error)
+}
+
+// PageFunc adapts a function to a PageSource.
+type PageFunc func(ctx context.Context) (io.Reader, bool, error)
+
+// NextPage calls f(ctx).
+func (f PageFunc) NextPage(ctx context.Context) (io.Reader, bool, error) {
+	return f(ctx)
+}
+
+// ConvertPaged converts the pages of pages in order into a single output
+// with one header row, like ConvertSources. The sources are named "page 1",
+// "page 2" and so on. Pages that fail to convert are handled according to
+// Options.ErrorPolicy; an error from NextPage always ends the conversion.
+func ConvertPaged(ctx context.Context, pages PageSource, w io.Writer, options Options) error {
+	return withOutput(w, options, func(w io.Writer) error {
+		c, err := newConversion(NewRowWriter(w, options), options)
+		if err != nil {
+			return err
+		}
+		forEachPage := func(fn func(Source) error) error {
+			for n := 1; ; n++ {
+				r, ok, err := pages.NextPage(ctx)
+				if err != nil {
+					return fmt.Errorf("json2csv: failed to fetch page %d: %w", n, err)
+				}
+				if !ok {
+					return nil
+				}
+				err = fn(Source{Name: fmt.Sprintf("page %d", n), Reader: r})
+				if closer, ok := r.(io.Closer); ok {
+					closer.Close()
+				}
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		if c.discoverKeys {
+			// Pages cannot be fetched twice: spool them to discover the keys.
+			sources, cleanup, err := c.spoolSources(forEachPage, 0)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			forEachPage = func(fn func(Source) error) error {
+				for _, source := range sources {
+					if err := fn(source); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+		}
+		return c.run(func() error {
+			return forEachPage(func(source Source) error {
+				if err := c.convertSource(source); err != nil && !c.skipSource(source.Name, err) {
+					return err
+				}
+				return nil
+			})
+		})
+	})
+}
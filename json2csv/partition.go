@@ -0,0 +1,137 @@
+// json2csv/partition.go
+
+package json2csv
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+)
+
+// PartitionFactory creates the output for the rows whose Options.PartitionBy
+// value is key. It is called once per distinct key, when its first row is
+// written. If the returned writer is an io.Closer, it is closed at the end
+// of the conversion.
+type PartitionFactory func(key string) (io.Writer, error)
+
+// PartitionFiles returns a PartitionFactory that creates files named by
+// formatting pattern with the key, e.g. PartitionFiles("out/country=%s.csv").
+// The key is escaped with url.PathEscape, so it cannot add path separators.
+func PartitionFiles(pattern string) PartitionFactory {
+	return func(key string) (io.Writer, error) {
+		return os.Create(fmt.Sprintf(pattern, url.PathEscape(key)))
+	}
+}
+
+// ConvertPartitioned converts r like Convert, routing every row to the
+// output for its value of the Options.PartitionBy path, e.g. one file per
+// country. The value is resolved like a Field JSONPath (before any
+// transformers) and formatted as a cell; null or missing values use the key
+// "". Each output is a complete file with its own header row, wrapped with
+// Options.WrapOutput if set. All partitions stay open until the end of the
+// conversion.
+func ConvertPartitioned(r io.Reader, create PartitionFactory, options Options) error {
+	if options.PartitionBy == "" {
+		return fmt.Errorf("json2csv: PartitionBy is not set")
+	}
+	partitions := &partitionRowWriter{
+		create:  create,
+		options: options,
+		writers: map[string]*partWriter{},
+	}
+	c, err := newConversion(partitions, options)
+	if err != nil {
+		return err
+	}
+	partitions.key = func() string { return c.partitionKey }
+
+	sources, cleanup, err := c.prepareSources([]Source{{Reader: r}})
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	err = c.run(func() error { return c.convertSource(sources[0]) })
+	if err != nil {
+		partitions.closeAll()
+	}
+	return err
+}
+
+// partitionRowWriter is a RowWriter that routes each row to the partition
+// named by key.
+type partitionRowWriter struct {
+	create  PartitionFactory
+	options Options
+	key     func() string // The partition of the row being written.
+
+	header    []string
+	hasHeader bool
+	writers   map[string]*partWriter
+}
+
+func (p *partitionRowWriter) WriteHeader(header []string) error {
+	p.header = append([]string(nil), header...)
+	p.hasHeader = true
+	return nil // Written at the start of every partition.
+}
+
+func (p *partitionRowWriter) WriteRow(row []string) error {
+	key := p.key()
+	writer, ok := p.writers[key]
+	if !ok {
+		out, err := p.create(key)
+		if err != nil {
+			return fmt.Errorf("json2csv: failed to create output for partition %q: %w", key, err)
+		}
+		if writer, err = openPartWriter(out, p.options); err != nil {
+			return err
+		}
+		p.writers[key] = writer
+		if p.hasHeader {
+			if err := writer.WriteHeader(p.header); err != nil {
+				return err
+			}
+		}
+	}
+	return writer.WriteRow(row)
+}
+
+func (p *partitionRowWriter) Flush() error {
+	for _, key := range p.keys() {
+		if err := p.writers[key].Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes every partition in key order, returning the first error.
+func (p *partitionRowWriter) Close() error {
+	var firstErr error
+	for _, key := range p.keys() {
+		if err := p.writers[key].Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("json2csv: failed to close partition %q: %w", key, err)
+		}
+	}
+	p.writers = map[string]*partWriter{}
+	return firstErr
+}
+
+// closeAll releases the partitions after a failed conversion.
+func (p *partitionRowWriter) closeAll() {
+	for _, writer := range p.writers {
+		writer.closeOut()
+	}
+	p.writers = map[string]*partWriter{}
+}
+
+func (p *partitionRowWriter) keys() []string {
+	keys := make([]string, 0, len(p.writers))
+	for key := range p.writers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,52 @@
+// json2csv/pivot.go
+
+package json2csv
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ArrayMode selects how a Field with "[*]" in its JSONPath handles the array.
+type ArrayMode int
+
+const (
+	// ArrayFlatten writes a row per array item (the default).
+	ArrayFlatten ArrayMode = iota
+
+	// ArrayPivot spreads the first Field.MaxElements items over as many
+	// columns of the record's row, for reporting tools that need one row
+	// per record: "items[*].price" becomes the columns "items_1_price" to
+	// "items_N_price" (or, with a CSVHeader such as "Price", "Price_1" to
+	// "Price_N"). Missing items give empty cells; items beyond MaxElements
+	// are ignored. Pivoted fields do not flatten: if no other field has
+	// "[*]" in its path, every record yields exactly one row.
+	ArrayPivot
+)
+
+// pivotColumns returns the columns of a Field with ArrayPivot: one per
+// element, addressing it by index.
+func pivotColumns(field Field) []Field {
+	starIndex := strings.Index(field.JSONPath, "[*]")
+	before, after := field.JSONPath[:starIndex], field.JSONPath[starIndex+len("[*]"):]
+	var prefix, suffix string
+	if field.CSVHeader != "" {
+		prefix = field.CSVHeader
+	} else {
+		prefix = strings.Join(pathKeys(before), "_")
+		if keys := pathKeys(strings.TrimPrefix(after, ".")); len(keys) > 0 {
+			suffix = "_" + strings.Join(keys, "_")
+		}
+	}
+
+	columns := make([]Field, field.MaxElements)
+	for i := range columns {
+		column := field
+		column.JSONPath = before + "[" + strconv.Itoa(i) + "]" + after
+		column.CSVHeader = prefix + "_" + strconv.Itoa(i+1) + suffix
+		column.ArrayMode = ArrayFlatten
+		column.MaxElements = 0
+		columns[i] = column
+	}
+	return columns
+}
@@ -0,0 +1,84 @@
+// json2csv/plan.go
+
+package json2csv
+
+import (
+	"fmt"
+	"strings"
+)
+
+// fieldPlan is a Field's JSONPath parsed once per conversion, so rows are
+// resolved without looking at the path string again.
+type fieldPlan struct {
+	pseudo   string        // Pseudo-path for conversion metadata, see pseudoPathValue.
+	constant interface{}   // Field.Constant, if not nil.
+	computed bool          // Field.Compute or Field.Query computes the value instead.
+	inItem   bool          // Resolved against the array item rather than the record.
+	path     *compiledPath // The part after "[*]" if inItem; nil if pseudo or constant.
+	resolver PathResolver  // Options.PathResolver, which resolves query instead of path.
+	query    string        // The path for resolver, the part after "[*]" if inItem.
+
+	fallbacks []fieldPlan // Field.FallbackPaths
+}
+
+// compileFieldPlan prepares the resolution of path (see resolvePath).
+func compileFieldPlan(path string) (fieldPlan, error) {
+	if isPseudoPath(path) {
+		return fieldPlan{pseudo: path}, nil
+	}
+	plan := fieldPlan{}
+	effectivePath := path
+	if starIndex := strings.Index(path, "[*]"); starIndex != -1 {
+		plan.inItem = true
+		effectivePath = strings.TrimPrefix(path[starIndex+len("[*]"):], ".")
+	}
+	compiled, err := compilePath(effectivePath)
+	if err != nil {
+		return fieldPlan{}, fmt.Errorf("json2csv: invalid path for field %q: %w", path, err)
+	}
+	plan.path = compiled
+	return plan, nil
+}
+
+// resolveField returns the value of field i for the current row, like
+// resolvePath with the field's JSONPath.
+// Null values are replaced by the first non-null value of the field's
+// FallbackPaths. missing reports that none of these paths is present (see
+// TransformContext.Missing). Only a PathResolver fails.
+func (c *conversion) resolveField(i int, originalRecord map[string]interface{}, item interface{}) (value interface{}, missing bool, _ error) {
+	plan := c.plans[i]
+	value, found, err := c.resolvePlan(plan, originalRecord, item)
+	for j := 0; err == nil && value == nil && j < len(plan.fallbacks); j++ {
+		var fallbackFound bool
+		value, fallbackFound, err = c.resolvePlan(plan.fallbacks[j], originalRecord, item)
+		found = found || fallbackFound
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("json2csv: failed to resolve field %q: %w", c.fields[i].name(), err)
+	}
+	return value, !found, nil
+}
+
+// resolvePlan returns the value of plan's path for the current row, and
+// whether the path is present (see compiledPath.lookup).
+func (c *conversion) resolvePlan(plan fieldPlan, originalRecord map[string]interface{}, item interface{}) (value interface{}, found bool, _ error) {
+	switch {
+	case plan.constant != nil:
+		return plan.constant, true, nil
+	case plan.computed:
+		return nil, true, nil // See Field.value.
+	case plan.pseudo != "":
+		return c.pseudoPathValue(plan.pseudo), true, nil
+	case plan.inItem && item == nil:
+		return nil, false, nil // The row of a record without items.
+	case plan.resolver != nil:
+		value, err := plan.resolve(originalRecord, item)
+		return value, true, err
+	case plan.inItem:
+		value, found = plan.path.lookup(item)
+		return value, found, nil
+	default:
+		value, found = plan.path.lookup(originalRecord)
+		return value, found, nil
+	}
+}
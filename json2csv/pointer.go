@@ -0,0 +1,152 @@
+// json2csv/pointer.go
+
+package json2csv
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PathSyntax selects the syntax of the paths in Options.
+type PathSyntax int
+
+const (
+	// PathSyntaxDefault is the dot syntax described in the package
+	// documentation, e.g. "items[*].price".
+	PathSyntaxDefault PathSyntax = iota
+
+	// PathSyntaxJSONPointer reads paths as JSON Pointers (RFC 6901), e.g.
+	// "/items/0/price", where "~1" stands for "/" and "~0" for "~" within
+	// a key, so keys with dots or brackets need no quoting. The token "-"
+	// marks the array to flatten, like "[*]": "/items/-/price". A token of
+	// digits without a leading zero selects an array element or, if the
+	// value is an object, its member of that name, as in "/users/123/name".
+	// A key expansion path ends in "/*".
+	// Pseudo-paths such as "$sourceFile" are unchanged. Errors and reports
+	// show the paths in the default syntax.
+	PathSyntaxJSONPointer
+)
+
+// nativePaths returns options with the paths of the fields, filters and
+// PartitionBy translated from options.PathSyntax to PathSyntaxDefault.
+func (options Options) nativePaths() (Options, error) {
+	if options.PathSyntax != PathSyntaxJSONPointer {
+		return options, nil
+	}
+	fields := make([]Field, len(options.Fields))
+	for i, field := range options.Fields {
+		var err error
+		pointer := field.JSONPath
+		expand := field.Expand != nil && strings.HasSuffix(pointer, "/*")
+		if expand {
+			pointer = strings.TrimSuffix(pointer, "/*")
+		}
+		if field.JSONPath, err = pointerToPath(pointer); err != nil {
+			return options, fmt.Errorf("json2csv: field %d: %w", i+1, err)
+		}
+		if expand {
+			field.JSONPath += ".*"
+		}
+		fallbacks := make([]string, len(field.FallbackPaths))
+		for j, fallback := range field.FallbackPaths {
+			if fallbacks[j], err = pointerToPath(fallback); err != nil {
+				return options, fmt.Errorf("json2csv: field %d: fallback path: %w", i+1, err)
+			}
+		}
+		if field.FallbackPaths != nil {
+			field.FallbackPaths = fallbacks
+		}
+		fields[i] = field
+	}
+	options.Fields = fields
+
+	var err error
+	if options.PartitionBy, err = pointerToPath(options.PartitionBy); err != nil {
+		return options, fmt.Errorf("json2csv: PartitionBy: %w", err)
+	}
+	if options.TimeWindow != nil {
+		window := *options.TimeWindow
+		if window.Path, err = pointerToPath(window.Path); err != nil {
+			return options, fmt.Errorf("json2csv: TimeWindow: %w", err)
+		}
+		options.TimeWindow = &window
+	}
+	if options.KeyFilter != nil {
+		filter := *options.KeyFilter
+		if filter.Path, err = pointerToPath(filter.Path); err != nil {
+			return options, fmt.Errorf("json2csv: KeyFilter: %w", err)
+		}
+		options.KeyFilter = &filter
+	}
+	if options.ExpectedSchema != nil {
+		schema := *options.ExpectedSchema
+		schema.Paths = make(map[string]FieldType, len(options.ExpectedSchema.Paths))
+		for pointer, fieldType := range options.ExpectedSchema.Paths {
+			path, err := pointerToPath(pointer)
+			if err != nil {
+				return options, fmt.Errorf("json2csv: ExpectedSchema: %w", err)
+			}
+			schema.Paths[path] = fieldType
+		}
+		options.ExpectedSchema = &schema
+	}
+	options.PathSyntax = PathSyntaxDefault
+	return options, nil
+}
+
+// pointerToPath translates a JSON Pointer to the default path syntax, e.g.
+// "/items/-/a.b" to "items[*]['a.b']". Empty and pseudo-paths are returned
+// unchanged.
+func pointerToPath(pointer string) (string, error) {
+	if pointer == "" || isPseudoPath(pointer) {
+		return pointer, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return "", fmt.Errorf("JSON Pointer %q does not start with \"/\"", pointer)
+	}
+	var b strings.Builder
+	for _, token := range strings.Split(pointer[1:], "/") {
+		if strings.Contains(strings.NewReplacer("~0", "", "~1", "").Replace(token), "~") {
+			return "", fmt.Errorf("JSON Pointer %q: invalid escape in %q", pointer, token)
+		}
+		key := strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		switch {
+		case key == "-":
+			b.WriteString("[*]")
+		case isArrayIndex(key):
+			b.WriteString("[" + key + "]")
+		case isIdentifier(key):
+			if b.Len() > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(key)
+		default:
+			b.WriteString("[" + quoteKey(key) + "]")
+		}
+	}
+	return b.String(), nil
+}
+
+// isArrayIndex reports whether token is an array index of a JSON Pointer:
+// digits without a leading zero.
+func isArrayIndex(token string) bool {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return false
+	}
+	_, err := strconv.ParseUint(token, 10, 31)
+	return err == nil
+}
+
+// isIdentifier reports whether key can be written as is in a dot path.
+func isIdentifier(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i, r := range key {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
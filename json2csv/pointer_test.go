@@ -0,0 +1,37 @@
+// json2csv/pointer_test.go
+
+package json2csv_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pradnyoday/go-json2csv/json2csv"
+)
+
+func TestJSONPointerDigitTokens(t *testing.T) {
+	options := json2csv.Options{
+		PathSyntax: json2csv.PathSyntaxJSONPointer,
+		Fields: []json2csv.Field{
+			{JSONPath: "/users/123/name", CSVHeader: "user"},
+			{JSONPath: "/items/0/sku", CSVHeader: "first"},
+			{JSONPath: "/items/-/sku", CSVHeader: "sku"},
+			{JSONPath: "/codes/007", CSVHeader: "code"},
+		},
+	}
+	input := `{"users":{"123":{"name":"ann"}},"items":[{"sku":"a"},{"sku":"b"}],"codes":{"007":"bond"}}
+{"users":[{"name":"x"}],"items":[{"sku":"c"}],"codes":["zero"]}
+`
+	want := `user,first,sku,code
+ann,a,a,bond
+ann,a,b,bond
+,c,c,
+`
+	var out strings.Builder
+	if err := json2csv.Convert(strings.NewReader(input), &out, options); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,57 @@
+// json2csv/preview.go
+
+package json2csv
+
+import (
+	"errors"
+	"io"
+)
+
+// errPreviewDone stops a preview conversion once enough rows are collected.
+var errPreviewDone = errors.New("json2csv: preview complete")
+
+// ConvertPreview converts the start of r with options and returns the header
+// followed by at most n data rows, as they would be written to the output.
+// It reads only as much input as needed and writes nothing, so it can be
+// used to show a mapping preview before running a full export. The header is
+// always included, regardless of Options.OmitHeader; Options.StatsWriter,
+// Options.WrapOutput and Options.Format are ignored.
+func ConvertPreview(r io.Reader, options Options, n int) ([][]string, error) {
+	options.OmitHeader = false
+	options.StatsWriter = nil
+
+	preview := &previewRowWriter{max: n}
+	err := ConvertTo(r, preview, options)
+	if err != nil && !errors.Is(err, errPreviewDone) {
+		return nil, err
+	}
+	return preview.rows, nil
+}
+
+// previewRowWriter collects the header and up to max rows in memory.
+type previewRowWriter struct {
+	rows [][]string
+	max  int
+	data int // data rows collected
+}
+
+func (p *previewRowWriter) WriteHeader(header []string) error {
+	p.rows = append(p.rows, append([]string(nil), header...))
+	if p.max <= 0 {
+		return errPreviewDone
+	}
+	return nil
+}
+
+func (p *previewRowWriter) WriteRow(row []string) error {
+	p.rows = append(p.rows, append([]string(nil), row...))
+	p.data++
+	if p.data >= p.max {
+		return errPreviewDone
+	}
+	return nil
+}
+
+func (p *previewRowWriter) Flush() error { return nil }
+
+func (p *previewRowWriter) Close() error { return nil }
@@ -0,0 +1,215 @@
+// json2csv/records.go
+
+package json2csv
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// RecordSource yields decoded records for ConvertRecords, for input that is
+// not (only) JSON text: records generated by the program, received from a
+// queue or read from another format.
+type RecordSource interface {
+	// Next returns the next record, or io.EOF after the last one. A nil
+	// record is treated like a JSON null in an input array. The record is
+	// not modified, and not retained after the following call to Next.
+	// Other errors end the conversion and are returned unchanged.
+	Next() (map[string]interface{}, error)
+}
+
+// RecordFunc adapts a function to a RecordSource.
+type RecordFunc func() (map[string]interface{}, error)
+
+// Next calls f.
+func (f RecordFunc) Next() (map[string]interface{}, error) {
+	return f()
+}
+
+// JSONRecords returns a RecordSource that decodes the records of r, which
+// holds a JSON array of objects or a stream of objects such as NDJSON, as
+// Convert does.
+func JSONRecords(r io.Reader) RecordSource {
+	stream := &jsonStream{r: r}
+	return RecordFunc(func() (map[string]interface{}, error) {
+		if more, err := stream.next(); err != nil {
+			return nil, err
+		} else if !more {
+			return nil, io.EOF
+		}
+		var record map[string]interface{}
+		if err := stream.decoder.Decode(&record); err != nil {
+			return nil, decodeError(err)
+		}
+		return record, nil
+	})
+}
+
+// ChanRecords returns a RecordSource that receives the records from ch until
+// it is closed.
+func ChanRecords(ch <-chan map[string]interface{}) RecordSource {
+	return RecordFunc(func() (map[string]interface{}, error) {
+		record, ok := <-ch
+		if !ok {
+			return nil, io.EOF
+		}
+		return record, nil
+	})
+}
+
+// MessageDecoder decodes a message holding one binary-encoded record, such
+// as an Avro or Protobuf encoded Kafka message, for MessageRecords. The
+// avro subpackage provides one for Avro; one for Protobuf messages could
+// go through protojson:
+//
+//	decoder := json2csv.MessageDecoderFunc(func(message []byte) (map[string]interface{}, error) {
+//		event := &pb.Event{}
+//		if err := proto.Unmarshal(message, event); err != nil {
+//			return nil, err
+//		}
+//		data, err := protojson.Marshal(event)
+//		if err != nil {
+//			return nil, err
+//		}
+//		var record map[string]interface{}
+//		return record, json.Unmarshal(data, &record)
+//	})
+type MessageDecoder interface {
+	DecodeMessage(message []byte) (map[string]interface{}, error)
+}
+
+// MessageDecoderFunc adapts a function to a MessageDecoder.
+type MessageDecoderFunc func(message []byte) (map[string]interface{}, error)
+
+// DecodeMessage calls f.
+func (f MessageDecoderFunc) DecodeMessage(message []byte) (map[string]interface{}, error) {
+	return f(message)
+}
+
+// MessageRecords returns a RecordSource that decodes with decoder the
+// messages returned by next, e.g. from polling a Kafka consumer, until next
+// returns io.EOF.
+func MessageRecords(next func() ([]byte, error), decoder MessageDecoder) RecordSource {
+	index := 0
+	return RecordFunc(func() (map[string]interface{}, error) {
+		message, err := next()
+		if err != nil {
+			return nil, err
+		}
+		record, err := decoder.DecodeMessage(message)
+		if err != nil {
+			return nil, fmt.Errorf("json2csv: failed to decode message %d: %w", index, err)
+		}
+		index++
+		return record, nil
+	})
+}
+
+// ConvertRecords converts the records of source like Convert converts the
+// records of a JSON array. Record values need not be JSON types: integers,
+// time.Time values and the like are formatted as if returned by a
+// Transformer. If keys of expanded fields must be discovered, the records
+// are first spooled to a temporary file as JSON, so they reach the
+// transformers as decoded JSON values.
+func ConvertRecords(source RecordSource, w io.Writer, options Options) error {
+	return withOutput(w, options, func(w io.Writer) error {
+		return ConvertRecordsTo(source, NewRowWriter(w, options), options)
+	})
+}
+
+// ConvertChan converts the records received from ch until it is closed,
+// like ConvertRecords(ChanRecords(ch), w, options). Records are mapped and
+// flattened as they arrive, without being encoded to JSON. If the
+// conversion fails, ch is no longer received from, so senders should not
+// block on it unconditionally.
+func ConvertChan(ch <-chan map[string]interface{}, w io.Writer, options Options) error {
+	return ConvertRecords(ChanRecords(ch), w, options)
+}
+
+// ConvertRecordsTo is like ConvertRecords but writes the rows to rw, as
+// ConvertTo does.
+func ConvertRecordsTo(source RecordSource, rw RowWriter, options Options) error {
+	c, err := newConversion(rw, options)
+	if err != nil {
+		return err
+	}
+	if c.discoverKeys {
+		spooled, cleanup, err := spoolRecords(source)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		sources, cleanup, err := c.prepareSources([]Source{{Reader: spooled}})
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		return c.run(func() error { return c.convertSource(sources[0]) })
+	}
+	return c.run(func() error { return c.convertRecords(source) })
+}
+
+// convertRecords writes the rows of every record of source.
+func (c *conversion) convertRecords(source RecordSource) error {
+	c.sourceName = ""
+	c.recordIndex = 0
+	c.inRecord = false
+	for {
+		record, err := source.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		c.startRecord(-1)
+		if err := c.processRecord(record, nil); err != nil {
+			return c.locate(err)
+		}
+		c.endRecord()
+	}
+}
+
+// spoolRecords writes the records of source to a temporary file as a JSON
+// array and returns it, positioned at the start; cleanup removes it.
+func spoolRecords(source RecordSource) (f *os.File, cleanup func(), err error) {
+	f, err = os.CreateTemp("", "json2csv-records-*.json")
+	if err != nil {
+		return nil, nil, fmt.Errorf("json2csv: failed to create temporary file: %w", err)
+	}
+	cleanup = func() {
+		f.Close()
+		os.Remove(f.Name())
+	}
+	buf := bufio.NewWriter(f)
+	encoder := json.NewEncoder(buf)
+	buf.WriteByte('[')
+	for index := 0; ; index++ {
+		record, err := source.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		if index > 0 {
+			buf.WriteByte(',')
+		}
+		if err := encoder.Encode(record); err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("json2csv: failed to spool record %d: %w", index, err)
+		}
+	}
+	buf.WriteByte(']')
+	if err := buf.Flush(); err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("json2csv: failed to spool records: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("json2csv: failed to spool records: %w", err)
+	}
+	return f, cleanup, nil
+}
@@ -0,0 +1,336 @@
+// json2csv/registry.go
+
+package json2csv
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// TransformerFactory builds a Transformer from the arguments given in a
+// Config, e.g. [2] for "RoundTo". Numbers arrive as json.Number; use the
+// Args helpers to read them.
+type TransformerFactory func(args Args) (Transformer, error)
+
+// Args are the arguments of a transformer in a Config.
+type Args []interface{}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]TransformerFactory{}
+)
+
+// RegisterTransformer makes a transformer available to Config by name,
+// replacing any earlier registration. The standard transformers are
+// registered under their Go names (e.g. "Trim", "RoundTo").
+func RegisterTransformer(name string, factory TransformerFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = factory
+}
+
+// LookupTransformer builds the registered transformer name with args.
+func LookupTransformer(name string, args Args) (Transformer, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("json2csv: unknown transformer %q", name)
+	}
+	t, err := factory(args)
+	if err != nil {
+		return nil, fmt.Errorf("json2csv: transformer %q: %w", name, err)
+	}
+	return t, nil
+}
+
+// RegisteredTransformers returns the sorted names of all registered transformers.
+func RegisteredTransformers() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Count returns an error unless there are between min and max arguments.
+func (a Args) Count(min, max int) error {
+	if len(a) < min || len(a) > max {
+		if min == max {
+			return fmt.Errorf("expected %d arguments, got %d", min, len(a))
+		}
+		return fmt.Errorf("expected %d to %d arguments, got %d", min, max, len(a))
+	}
+	return nil
+}
+
+// String returns argument i as a string, or def if it is absent.
+func (a Args) String(i int, def string) (string, error) {
+	if i >= len(a) || a[i] == nil {
+		return def, nil
+	}
+	s, ok := a[i].(string)
+	if !ok {
+		return "", fmt.Errorf("argument %d: expected a string, got %T", i+1, a[i])
+	}
+	return s, nil
+}
+
+// Float returns argument i as a float64, or def if it is absent.
+func (a Args) Float(i int, def float64) (float64, error) {
+	if i >= len(a) || a[i] == nil {
+		return def, nil
+	}
+	f, ok, err := numberValue(a[i])
+	if _, isString := a[i].(string); isString || err != nil || !ok {
+		return 0, fmt.Errorf("argument %d: expected a number, got %v", i+1, a[i])
+	}
+	return f, nil
+}
+
+// Int returns argument i as an int, or def if it is absent.
+func (a Args) Int(i int, def int) (int, error) {
+	f, err := a.Float(i, float64(def))
+	if err != nil {
+		return 0, err
+	}
+	if f != float64(int(f)) {
+		return 0, fmt.Errorf("argument %d: expected an integer, got %v", i+1, a[i])
+	}
+	return int(f), nil
+}
+
+// Rune returns argument i, a single-character string, as a rune, or def if
+// it is absent.
+func (a Args) Rune(i int, def rune) (rune, error) {
+	s, err := a.String(i, string(def))
+	if err != nil {
+		return 0, err
+	}
+	if utf8.RuneCountInString(s) != 1 {
+		return 0, fmt.Errorf("argument %d: expected a single character, got %q", i+1, s)
+	}
+	r, _ := utf8.DecodeRuneInString(s)
+	return r, nil
+}
+
+// Location returns argument i, an IANA time zone name such as
+// "Europe/Berlin", as a *time.Location, or def if it is absent.
+func (a Args) Location(i int, def *time.Location) (*time.Location, error) {
+	name, err := a.String(i, "")
+	if err != nil || name == "" {
+		return def, err
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("argument %d: %w", i+1, err)
+	}
+	return loc, nil
+}
+
+// Transformer returns argument i, a transformer in Config form (a name or
+// an object with "name" and "args"), as a Transformer.
+func (a Args) Transformer(i int) (Transformer, error) {
+	raw, err := json.Marshal(a[i])
+	if err != nil {
+		return nil, fmt.Errorf("argument %d: %w", i+1, err)
+	}
+	var tc TransformerConfig
+	if err := json.Unmarshal(raw, &tc); err != nil {
+		return nil, fmt.Errorf("argument %d: %w", i+1, err)
+	}
+	return tc.build()
+}
+
+// noArgs registers a plain Transformer that takes no arguments.
+func noArgs(t Transformer) TransformerFactory {
+	return func(args Args) (Transformer, error) {
+		if err := args.Count(0, 0); err != nil {
+			return nil, err
+		}
+		return t, nil
+	}
+}
+
+func init() {
+	for name, t := range map[string]Transformer{
+		"BoolToYesNo":             BoolToYesNo,
+		"FormatUnixTimestamp":     FormatUnixTimestamp,
+		"ItemsSummaryTransformer": ItemsSummaryTransformer,
+		"Trim":                    Trim,
+		"Upper":                   Upper,
+		"Lower":                   Lower,
+		"TitleCase":               TitleCase,
+		"StripHTML":               StripHTML,
+		"CentsToDollars":          CentsToDollars,
+		"ParseStringNumber":       ParseStringNumber,
+		"StringifyJSON":           StringifyJSON,
+		"LatLon":                  LatLon,
+		"WKTPoint":                WKTPoint,
+	} {
+		RegisterTransformer(name, noArgs(t))
+	}
+
+	RegisterTransformer("Truncate", func(args Args) (Transformer, error) {
+		if err := args.Count(1, 1); err != nil {
+			return nil, err
+		}
+		n, err := args.Int(0, 0)
+		return Truncate(n), err
+	})
+	RegisterTransformer("ReplaceRegex", func(args Args) (Transformer, error) {
+		if err := args.Count(2, 2); err != nil {
+			return nil, err
+		}
+		pattern, err := args.String(0, "")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, err // ReplaceRegex would panic.
+		}
+		repl, err := args.String(1, "")
+		return ReplaceRegex(pattern, repl), err
+	})
+	for name, pad := range map[string]func(int, rune) Transformer{"PadLeft": PadLeft, "PadRight": PadRight} {
+		pad := pad
+		RegisterTransformer(name, func(args Args) (Transformer, error) {
+			if err := args.Count(1, 2); err != nil {
+				return nil, err
+			}
+			width, err := args.Int(0, 0)
+			if err != nil {
+				return nil, err
+			}
+			r, err := args.Rune(1, ' ')
+			return pad(width, r), err
+		})
+	}
+	RegisterTransformer("RoundTo", func(args Args) (Transformer, error) {
+		if err := args.Count(1, 1); err != nil {
+			return nil, err
+		}
+		n, err := args.Int(0, 0)
+		return RoundTo(n), err
+	})
+	RegisterTransformer("MultiplyBy", func(args Args) (Transformer, error) {
+		if err := args.Count(1, 1); err != nil {
+			return nil, err
+		}
+		f, err := args.Float(0, 1)
+		return MultiplyBy(f), err
+	})
+	RegisterTransformer("ClampRange", func(args Args) (Transformer, error) {
+		if err := args.Count(2, 2); err != nil {
+			return nil, err
+		}
+		min, err := args.Float(0, 0)
+		if err != nil {
+			return nil, err
+		}
+		max, err := args.Float(1, 0)
+		return ClampRange(min, max), err
+	})
+	RegisterTransformer("ConvertUnit", func(args Args) (Transformer, error) {
+		if err := args.Count(2, 2); err != nil {
+			return nil, err
+		}
+		from, err := args.String(0, "")
+		if err != nil {
+			return nil, err
+		}
+		to, err := args.String(1, "")
+		if err != nil {
+			return nil, err
+		}
+		return convertUnit(from, to)
+	})
+	RegisterTransformer("FormatDuration", func(args Args) (Transformer, error) {
+		if err := args.Count(0, 1); err != nil {
+			return nil, err
+		}
+		unit, err := args.String(0, "s")
+		if err != nil {
+			return nil, err
+		}
+		return formatDuration(unit)
+	})
+	RegisterTransformer("Geohash", func(args Args) (Transformer, error) {
+		if err := args.Count(0, 1); err != nil {
+			return nil, err
+		}
+		precision, err := args.Int(0, 9)
+		if err != nil {
+			return nil, err
+		}
+		return geohash(precision)
+	})
+	RegisterTransformer("ParseTime", func(args Args) (Transformer, error) {
+		if err := args.Count(0, 1); err != nil {
+			return nil, err
+		}
+		layout, err := args.String(0, "")
+		return ParseTime(layout), err
+	})
+	RegisterTransformer("FormatTime", func(args Args) (Transformer, error) {
+		if err := args.Count(0, 2); err != nil {
+			return nil, err
+		}
+		layout, err := args.String(0, "")
+		if err != nil {
+			return nil, err
+		}
+		loc, err := args.Location(1, nil)
+		return FormatTime(layout, loc), err
+	})
+	RegisterTransformer("FormatTimeLocale", func(args Args) (Transformer, error) {
+		if err := args.Count(2, 3); err != nil {
+			return nil, err
+		}
+		layout, err := args.String(0, "")
+		if err != nil {
+			return nil, err
+		}
+		locale, err := args.String(1, "")
+		if err != nil {
+			return nil, err
+		}
+		loc, err := args.Location(2, nil)
+		return FormatTimeLocale(layout, loc, locale), err
+	})
+	RegisterTransformer("FormatUnixTimestampIn", func(args Args) (Transformer, error) {
+		if err := args.Count(0, 2); err != nil {
+			return nil, err
+		}
+		loc, err := args.Location(0, nil)
+		if err != nil {
+			return nil, err
+		}
+		layout, err := args.String(1, "")
+		return FormatUnixTimestampIn(loc, layout), err
+	})
+	RegisterTransformer("JoinArray", func(args Args) (Transformer, error) {
+		if len(args) < 1 {
+			return nil, args.Count(1, 1)
+		}
+		separator, err := args.String(0, "")
+		if err != nil {
+			return nil, err
+		}
+		elementFormat := make([]Transformer, len(args)-1)
+		for i := range elementFormat {
+			if elementFormat[i], err = args.Transformer(i + 1); err != nil {
+				return nil, err
+			}
+		}
+		return JoinArray(separator, elementFormat...), nil
+	})
+}
@@ -0,0 +1,137 @@
+// json2csv/report.go
+
+package json2csv
+
+import (
+	"io"
+	"time"
+)
+
+// Report summarizes a conversion, for monitoring and reconciling pipelines.
+type Report struct {
+	// RecordsRead is the number of top-level JSON objects decoded.
+	RecordsRead int
+
+	// RecordsFiltered is the number of records rejected by a record-level
+	// TimeWindow or KeyFilter.
+	RecordsFiltered int
+
+	// RecordsOversized is the number of records skipped for exceeding
+	// Options.MaxRecordBytes (see OversizedRecordsSkip).
+	RecordsOversized int
+
+	// RecordsMalformed is the number of syntactically broken records
+	// skipped (see MalformedRecordsSkip).
+	RecordsMalformed int
+
+	// RecordsSkipped is the number of records that produced no rows because
+	// their flattened array was null, missing, empty or held only nulls (see
+	// Options.EmptyArrayBehavior).
+	RecordsSkipped int
+
+	// RowsWritten is the number of data rows written, excluding the header.
+	// It is zero for an empty output.
+	RowsWritten int
+
+	// HeaderWritten reports whether the header row was written (see
+	// Options.OmitHeader and Options.HeaderOnEmpty).
+	HeaderWritten bool
+
+	// RowsFiltered is the number of array items rejected by an item-level
+	// TimeWindow or KeyFilter.
+	RowsFiltered int
+
+	// RowsSkipped is the number of rows dropped by a field's
+	// FieldErrorSkipRow policy.
+	RowsSkipped int
+
+	// InvalidItems is the number of array elements skipped or stringified
+	// under Options.InvalidItems.
+	InvalidItems int
+
+	// LateKeys are the paths of the keys of key expansions first seen after
+	// Options.DiscoveryRecords, which have no column, such as
+	// "metrics.gpu", in the order they were seen.
+	LateKeys []string
+
+	// UnmappedKeys are the paths of the keys no field references, in the
+	// order they were seen, with Options.WarnUnmappedKeys.
+	UnmappedKeys []string
+
+	// SchemaDrift lists the differences between the input and
+	// Options.ExpectedSchema, the first of each kind at each path, in the
+	// order they were seen.
+	SchemaDrift []SchemaDrift
+
+	// Fields reports on each output column, in order.
+	Fields []FieldReport
+
+	// BytesWritten is the number of bytes written to the output writer
+	// (after Options.WrapOutput, if set).
+	BytesWritten int64
+
+	// Duration is the wall-clock time of the conversion.
+	Duration time.Duration
+}
+
+// FieldReport summarizes one output column in a Report.
+type FieldReport struct {
+	Header   string
+	JSONPath string
+
+	// Nulls is the number of rows whose value was null or missing after the
+	// field's transformers ran.
+	Nulls int
+
+	// Missing is the number of rows whose path was not present, as opposed
+	// to present with a null value (see TransformContext.Missing).
+	Missing int
+
+	// Errors is the number of transformer or type errors handled by the
+	// field's OnError policy.
+	Errors int
+
+	// Truncated is the number of cells cut or hashed for exceeding
+	// Options.MaxCellLength.
+	Truncated int
+}
+
+// ConvertWithReport is like Convert but also returns a Report of the
+// conversion. On error the Report covers the work done until the failure.
+func ConvertWithReport(r io.Reader, w io.Writer, options Options) (Report, error) {
+	start := time.Now()
+	var report Report
+	counter := &countingWriter{w: w}
+	err := withOutput(counter, options, func(w io.Writer) error {
+		return convertSourcesTo([]Source{{Reader: r}}, NewRowWriter(w, options), options, &report)
+	})
+	report.BytesWritten = counter.n
+	report.Duration = time.Since(start)
+	return report, err
+}
+
+// initReport sets up the per-column entries of the report, if any.
+func (c *conversion) initReport() {
+	if c.report == nil {
+		return
+	}
+	if c.drift != nil {
+		c.drift.report = c.report
+	}
+	c.report.Fields = make([]FieldReport, len(c.fields))
+	for i, field := range c.fields {
+		c.report.Fields[i] = FieldReport{Header: c.header[i], JSONPath: field.JSONPath}
+	}
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
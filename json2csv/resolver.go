@@ -0,0 +1,67 @@
+// json2csv/resolver.go
+
+package json2csv
+
+import "strings"
+
+// PathResolver resolves the paths of Fields in a path language of its own
+// (see Options.PathResolver). Resolve returns nil for a path that matches
+// nothing; its errors are handled by the field's OnError like transformer
+// errors. It may be called concurrently.
+type PathResolver interface {
+	Resolve(record map[string]interface{}, path string) (interface{}, error)
+}
+
+// DefaultResolver resolves paths with the path syntax of this package, as
+// conversions without a PathResolver do.
+var DefaultResolver PathResolver = dotResolver{}
+
+// dotResolver is the PathResolver of the built-in path syntax.
+type dotResolver struct{}
+
+// Resolve returns the value of path in record.
+func (dotResolver) Resolve(record map[string]interface{}, path string) (interface{}, error) {
+	return getValueByDotPath(record, path)
+}
+
+// resolverPlan prepares the resolution of path with resolver: the part
+// before "[*]" still selects the flattened array, and the part after it is
+// passed to resolver with the item.
+func resolverPlan(path string, resolver PathResolver) fieldPlan {
+	if isPseudoPath(path) {
+		return fieldPlan{pseudo: path}
+	}
+	plan := fieldPlan{resolver: resolver, query: path}
+	if starIndex := strings.Index(path, "[*]"); starIndex != -1 {
+		plan.inItem = true
+		plan.query = strings.TrimPrefix(path[starIndex+len("[*]"):], ".")
+	}
+	return plan
+}
+
+// resolve returns the value of plan's query for the current row.
+func (plan fieldPlan) resolve(originalRecord map[string]interface{}, item interface{}) (interface{}, error) {
+	target := originalRecord
+	if plan.inItem {
+		if plan.query == "" {
+			return item, nil
+		}
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, nil // Paths into items only address objects.
+		}
+		target = m
+	}
+	return plan.resolver.Resolve(target, plan.query)
+}
+
+// validateResolverPath is validatePath for the paths of a PathResolver, of
+// which only the part before "[*]" is in the built-in syntax.
+func validateResolverPath(path string) error {
+	starIndex := strings.Index(path, "[*]")
+	if starIndex == -1 {
+		return nil
+	}
+	_, err := compilePath(strings.TrimSuffix(path[:starIndex], "."))
+	return err
+}
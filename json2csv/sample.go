@@ -0,0 +1,28 @@
+// json2csv/sample.go
+
+package json2csv
+
+// sampled reports whether the next row that passed the filters belongs to
+// the sample selected by Options.SampleEvery and Options.SampleRate.
+func (c *conversion) sampled() bool {
+	index := uint64(c.sampleIndex)
+	c.sampleIndex++
+	if n := c.options.SampleEvery; n > 1 && index%uint64(n) != 0 {
+		return false
+	}
+	if rate := c.options.SampleRate; rate > 0 && rate < 1 {
+		// Hashing the row index instead of drawing from a random source
+		// makes the sample depend only on the seed and the input.
+		return float64(splitmix64(splitmix64(uint64(c.options.SampleSeed))+index)>>11)/(1<<53) < rate
+	}
+	return true
+}
+
+// splitmix64 is the SplitMix64 mixing function, which scatters consecutive
+// inputs uniformly over the 64-bit range.
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
@@ -0,0 +1,224 @@
+// json2csv/schema.go
+
+package json2csv
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// SchemaFormat selects the kind of schema written to Options.SchemaWriter.
+type SchemaFormat int
+
+const (
+	// SchemaTableSchema writes a Frictionless Table Schema (JSON).
+	SchemaTableSchema SchemaFormat = iota
+
+	// SchemaBigQuery writes a BigQuery table schema (a JSON array of
+	// columns, as accepted by "bq load --schema").
+	SchemaBigQuery
+
+	// SchemaSQL writes a CREATE TABLE statement named Options.SchemaTable.
+	SchemaSQL
+)
+
+// ColumnSchema describes one output column in a schema.
+type ColumnSchema struct {
+	Name string
+	Type FieldType // Never TypeAny.
+
+	// Required is set if the column had a value in every row. Columns of
+	// conversions without rows are never required.
+	Required bool
+}
+
+// schemaCollector observes the values of the output columns to infer the
+// types of columns without a declared Field.Type.
+type schemaCollector struct {
+	columns []columnObservation
+	rows    int
+}
+
+type columnObservation struct {
+	declared FieldType
+	nulls    int
+	seen     map[FieldType]bool
+}
+
+func newSchemaCollector(fields []Field) *schemaCollector {
+	s := &schemaCollector{columns: make([]columnObservation, len(fields))}
+	for i, field := range fields {
+		s.columns[i] = columnObservation{declared: field.Type, seen: map[FieldType]bool{}}
+	}
+	return s
+}
+
+// observe records the value of column i in the current row.
+func (s *schemaCollector) observe(i int, value interface{}) {
+	column := &s.columns[i]
+	if value == nil {
+		column.nulls++
+		return
+	}
+	if column.declared == TypeAny {
+		column.seen[valueType(value)] = true
+	}
+}
+
+// endRow counts a written row.
+func (s *schemaCollector) endRow() {
+	s.rows++
+}
+
+// valueType returns the narrowest FieldType for a non-null value.
+func valueType(value interface{}) FieldType {
+	switch v := value.(type) {
+	case bool:
+		return TypeBool
+	case time.Time:
+		return TypeTime
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return TypeInt
+		}
+		return TypeFloat
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return TypeInt
+		}
+		return TypeFloat
+	case float32:
+		return TypeFloat
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return TypeInt
+	default:
+		return TypeString
+	}
+}
+
+// schema returns the schema of the output columns named header.
+func (s *schemaCollector) schema(header []string) []ColumnSchema {
+	schema := make([]ColumnSchema, len(header))
+	for i, name := range header {
+		column := s.columns[i]
+		fieldType := column.declared
+		if fieldType == TypeAny {
+			fieldType = inferType(column.seen)
+		}
+		schema[i] = ColumnSchema{
+			Name:     name,
+			Type:     fieldType,
+			Required: s.rows > 0 && column.nulls == 0,
+		}
+	}
+	return schema
+}
+
+// inferType returns the type that fits all the types seen: numbers widen
+// to TypeFloat, and anything else mixed, or nothing seen, is TypeString.
+func inferType(seen map[FieldType]bool) FieldType {
+	switch {
+	case len(seen) == 1:
+		for t := range seen {
+			return t
+		}
+	case len(seen) == 2 && seen[TypeInt] && seen[TypeFloat]:
+		return TypeFloat
+	}
+	return TypeString
+}
+
+// writeSchema writes schema to w in format, using table as the table name
+// of SQL schemas.
+func writeSchema(w io.Writer, format SchemaFormat, table string, schema []ColumnSchema) error {
+	switch format {
+	case SchemaTableSchema:
+		type tableField struct {
+			Name        string          `json:"name"`
+			Type        string          `json:"type"`
+			Constraints map[string]bool `json:"constraints,omitempty"`
+		}
+		fields := make([]tableField, len(schema))
+		for i, column := range schema {
+			fields[i] = tableField{Name: column.Name, Type: tableSchemaTypes[column.Type]}
+			if column.Required {
+				fields[i].Constraints = map[string]bool{"required": true}
+			}
+		}
+		return writeSchemaJSON(w, map[string]interface{}{"fields": fields})
+	case SchemaBigQuery:
+		type bigQueryField struct {
+			Name string `json:"name"`
+			Type string `json:"type"`
+			Mode string `json:"mode"`
+		}
+		fields := make([]bigQueryField, len(schema))
+		for i, column := range schema {
+			fields[i] = bigQueryField{Name: column.Name, Type: bigQueryTypes[column.Type], Mode: "NULLABLE"}
+			if column.Required {
+				fields[i].Mode = "REQUIRED"
+			}
+		}
+		return writeSchemaJSON(w, fields)
+	case SchemaSQL:
+		if table == "" {
+			table = "data"
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "CREATE TABLE %s (\n", quoteSQLIdentifier(table))
+		for i, column := range schema {
+			fmt.Fprintf(&b, "  %s %s", quoteSQLIdentifier(column.Name), sqlTypes[column.Type])
+			if column.Required {
+				b.WriteString(" NOT NULL")
+			}
+			if i < len(schema)-1 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString(");\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	default:
+		return fmt.Errorf("unknown schema format %d", format)
+	}
+}
+
+var tableSchemaTypes = map[FieldType]string{
+	TypeString: "string",
+	TypeInt:    "integer",
+	TypeFloat:  "number",
+	TypeBool:   "boolean",
+	TypeTime:   "datetime",
+}
+
+var bigQueryTypes = map[FieldType]string{
+	TypeString: "STRING",
+	TypeInt:    "INT64",
+	TypeFloat:  "FLOAT64",
+	TypeBool:   "BOOL",
+	TypeTime:   "TIMESTAMP",
+}
+
+var sqlTypes = map[FieldType]string{
+	TypeString: "TEXT",
+	TypeInt:    "BIGINT",
+	TypeFloat:  "DOUBLE PRECISION",
+	TypeBool:   "BOOLEAN",
+	TypeTime:   "TIMESTAMP WITH TIME ZONE",
+}
+
+func writeSchemaJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// quoteSQLIdentifier quotes name as a standard SQL delimited identifier.
+func quoteSQLIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,288 @@
+// json2csv/sort.go
+
+package json2csv
+
+import (
+	"bufio"
+	"cmp"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// SortKey orders the output by one column (see Options.SortBy).
+type SortKey struct {
+	// Column is the header of the column, as written to the output.
+	Column string
+
+	// Descending reverses the order of the column.
+	Descending bool
+
+	// Numeric compares the cells as numbers instead of as strings. In
+	// ascending order, cells that are not numbers (including empty cells)
+	// come after the numbers.
+	Numeric bool
+}
+
+// DefaultSortMemory is the default of Options.SortMemory.
+const DefaultSortMemory = 64 << 20
+
+// sortColumn is a SortKey resolved to its column index.
+type sortColumn struct {
+	SortKey
+	index int
+}
+
+// sortColumns resolves keys against header.
+func sortColumns(keys []SortKey, header []string) ([]sortColumn, error) {
+	columns := make([]sortColumn, len(keys))
+	for i, key := range keys {
+		index := slices.Index(header, key.Column)
+		if index < 0 {
+			return nil, fmt.Errorf("json2csv: SortBy: no column %q", key.Column)
+		}
+		columns[i] = sortColumn{SortKey: key, index: index}
+	}
+	return columns, nil
+}
+
+// sortRowWriter is a RowWriter that sorts the rows before passing them to
+// out when it is closed. Rows are kept in memory up to memLimit bytes;
+// beyond that they are sorted in runs written to temporary files, which
+// are merged at the end (an external merge sort). The sort is stable.
+type sortRowWriter struct {
+	out      RowWriter
+	columns  []sortColumn
+	lossless bool // Cells are encoded with encodeLosslessCell.
+	memLimit int64
+
+	rows [][]string
+	size int64 // Approximate memory held by rows.
+	runs []*os.File
+}
+
+func newSortRowWriter(out RowWriter, columns []sortColumn, options Options) *sortRowWriter {
+	memLimit := options.SortMemory
+	if memLimit <= 0 {
+		memLimit = DefaultSortMemory
+	}
+	return &sortRowWriter{
+		out:      out,
+		columns:  columns,
+		lossless: options.Lossless && options.Format == FormatCSV,
+		memLimit: memLimit,
+	}
+}
+
+func (s *sortRowWriter) WriteHeader(header []string) error {
+	return s.out.WriteHeader(header)
+}
+
+func (s *sortRowWriter) WriteRow(row []string) error {
+	row = slices.Clone(row)
+	s.rows = append(s.rows, row)
+	s.size += 24 // The slice header.
+	for _, cell := range row {
+		s.size += int64(len(cell)) + 16
+	}
+	if s.size >= s.memLimit {
+		return s.spill()
+	}
+	return nil
+}
+
+// Flush does nothing: no row can be written before all have been seen.
+func (s *sortRowWriter) Flush() error { return nil }
+
+// Close writes the sorted rows to out and closes it.
+func (s *sortRowWriter) Close() error {
+	defer s.remove()
+	if len(s.runs) == 0 {
+		slices.SortStableFunc(s.rows, s.compare)
+		for _, row := range s.rows {
+			if err := s.out.WriteRow(row); err != nil {
+				return err
+			}
+		}
+		s.rows = nil
+		return s.out.Close()
+	}
+	if err := s.spill(); err != nil {
+		return err
+	}
+	if err := s.merge(); err != nil {
+		return err
+	}
+	return s.out.Close()
+}
+
+// spill sorts the rows in memory and writes them to a new run.
+func (s *sortRowWriter) spill() error {
+	slices.SortStableFunc(s.rows, s.compare)
+	f, err := os.CreateTemp("", "json2csv-sort-*.bin")
+	if err != nil {
+		return fmt.Errorf("json2csv: failed to create temporary file: %w", err)
+	}
+	s.runs = append(s.runs, f)
+	w := bufio.NewWriter(f)
+	var buf []byte
+	for _, row := range s.rows {
+		buf = binary.AppendUvarint(buf[:0], uint64(len(row)))
+		for _, cell := range row {
+			buf = binary.AppendUvarint(buf, uint64(len(cell)))
+			buf = append(buf, cell...)
+		}
+		if _, err := w.Write(buf); err != nil {
+			return fmt.Errorf("json2csv: failed to write sort run: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("json2csv: failed to write sort run: %w", err)
+	}
+	clear(s.rows)
+	s.rows = s.rows[:0]
+	s.size = 0
+	return nil
+}
+
+// merge writes the rows of all runs to out in order.
+func (s *sortRowWriter) merge() error {
+	readers := &runHeap{compare: s.compare}
+	for i, f := range s.runs {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("json2csv: failed to read sort run: %w", err)
+		}
+		r := &runReader{r: bufio.NewReader(f), run: i}
+		if ok, err := r.next(); err != nil {
+			return err
+		} else if ok {
+			readers.readers = append(readers.readers, r)
+		}
+	}
+	heap.Init(readers)
+	for readers.Len() > 0 {
+		r := readers.readers[0]
+		if err := s.out.WriteRow(r.row); err != nil {
+			return err
+		}
+		if ok, err := r.next(); err != nil {
+			return err
+		} else if ok {
+			heap.Fix(readers, 0)
+		} else {
+			heap.Pop(readers)
+		}
+	}
+	return nil
+}
+
+// remove deletes the runs.
+func (s *sortRowWriter) remove() {
+	for _, f := range s.runs {
+		f.Close()
+		os.Remove(f.Name())
+	}
+	s.runs = nil
+}
+
+// compare orders rows by the sort columns.
+func (s *sortRowWriter) compare(a, b []string) int {
+	for _, column := range s.columns {
+		x, y := a[column.index], b[column.index]
+		if s.lossless {
+			x, y = decodeLosslessCell(x), decodeLosslessCell(y)
+		}
+		var order int
+		if column.Numeric {
+			order = compareNumeric(x, y)
+		} else {
+			order = strings.Compare(x, y)
+		}
+		if column.Descending {
+			order = -order
+		}
+		if order != 0 {
+			return order
+		}
+	}
+	return 0
+}
+
+// compareNumeric compares x and y as numbers, placing non-numbers after
+// numbers and comparing them as strings.
+func compareNumeric(x, y string) int {
+	fx, errX := strconv.ParseFloat(strings.TrimSpace(x), 64)
+	fy, errY := strconv.ParseFloat(strings.TrimSpace(y), 64)
+	switch {
+	case errX == nil && errY == nil:
+		return cmp.Compare(fx, fy)
+	case errX == nil:
+		return -1
+	case errY == nil:
+		return 1
+	default:
+		return strings.Compare(x, y)
+	}
+}
+
+// runReader reads the rows of a sort run.
+type runReader struct {
+	r   *bufio.Reader
+	run int
+	row []string
+}
+
+// next reads the next row, reporting false at the end of the run.
+func (r *runReader) next() (bool, error) {
+	n, err := binary.ReadUvarint(r.r)
+	if err == io.EOF {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("json2csv: failed to read sort run: %w", err)
+	}
+	row := make([]string, n)
+	for i := range row {
+		size, err := binary.ReadUvarint(r.r)
+		if err != nil {
+			return false, fmt.Errorf("json2csv: failed to read sort run: %w", err)
+		}
+		cell := make([]byte, size)
+		if _, err := io.ReadFull(r.r, cell); err != nil {
+			return false, fmt.Errorf("json2csv: failed to read sort run: %w", err)
+		}
+		row[i] = string(cell)
+	}
+	r.row = row
+	return true, nil
+}
+
+// runHeap is a heap of runReaders ordered by their current row, then by
+// run, which keeps the merge stable.
+type runHeap struct {
+	readers []*runReader
+	compare func(a, b []string) int
+}
+
+func (h *runHeap) Len() int { return len(h.readers) }
+
+func (h *runHeap) Less(i, j int) bool {
+	if order := h.compare(h.readers[i].row, h.readers[j].row); order != 0 {
+		return order < 0
+	}
+	return h.readers[i].run < h.readers[j].run
+}
+
+func (h *runHeap) Swap(i, j int) { h.readers[i], h.readers[j] = h.readers[j], h.readers[i] }
+
+func (h *runHeap) Push(x interface{}) { h.readers = append(h.readers, x.(*runReader)) }
+
+func (h *runHeap) Pop() interface{} {
+	last := h.readers[len(h.readers)-1]
+	h.readers = h.readers[:len(h.readers)-1]
+	return last
+}
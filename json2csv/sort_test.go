@@ -0,0 +1,107 @@
+// json2csv/sort_test.go
+
+package json2csv
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// rowRecorder is a RowWriter keeping the rows written to it.
+type rowRecorder struct {
+	rows   [][]string
+	closed bool
+}
+
+func (r *rowRecorder) WriteHeader(header []string) error { return nil }
+func (r *rowRecorder) WriteRow(row []string) error {
+	r.rows = append(r.rows, row)
+	return nil
+}
+func (r *rowRecorder) Flush() error { return nil }
+func (r *rowRecorder) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestSortRowWriterSpill(t *testing.T) {
+	// Rows of name, size and input position; names and sizes repeat so that
+	// the stability of the sort shows in the position.
+	var input [][]string
+	for i := 0; i < 50; i++ {
+		input = append(input, []string{
+			string(rune('a' + i*7%5)),
+			fmt.Sprint(i * 13 % 11),
+			fmt.Sprint(i),
+		})
+	}
+	input = append(input, []string{"c", "n/a", "50"}, []string{"a", "", "51"})
+
+	tests := []struct {
+		name     string
+		keys     []SortKey
+		memLimit int64 // Zero keeps every row in memory.
+	}{
+		{"in memory", []SortKey{{Column: "name"}}, 0},
+		{"run per row", []SortKey{{Column: "name"}}, 1},
+		{"several runs", []SortKey{{Column: "name"}}, 600},
+		{"numeric descending", []SortKey{{Column: "size", Numeric: true, Descending: true}}, 300},
+		{"two keys", []SortKey{{Column: "name", Descending: true}, {Column: "size", Numeric: true}}, 500},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			columns, err := sortColumns(test.keys, []string{"name", "size", "position"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The expected order, sorted in memory.
+			want := &rowRecorder{}
+			inMemory := newSortRowWriter(want, columns, Options{})
+			for _, row := range input {
+				inMemory.WriteRow(row)
+			}
+			if err := inMemory.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			got := &rowRecorder{}
+			s := newSortRowWriter(got, columns, Options{SortMemory: test.memLimit})
+			for _, row := range input {
+				if err := s.WriteRow(row); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if runs := len(s.runs); test.memLimit == 0 && runs != 0 || test.memLimit > 0 && runs < 2 {
+				t.Errorf("%d runs spilled with SortMemory %d", runs, test.memLimit)
+			}
+			if err := s.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if !got.closed {
+				t.Error("the output was not closed")
+			}
+			if s.runs != nil {
+				t.Error("the runs were not removed")
+			}
+			if !reflect.DeepEqual(got.rows, want.rows) {
+				t.Errorf("merged rows differ from the in-memory sort:\n%v\nwant\n%v", got.rows, want.rows)
+			}
+			for i := 1; i < len(got.rows); i++ {
+				if order := s.compare(got.rows[i-1], got.rows[i]); order > 0 {
+					t.Fatalf("rows %v and %v are out of order", got.rows[i-1], got.rows[i])
+				} else if order == 0 && atoi(got.rows[i-1][2]) > atoi(got.rows[i][2]) {
+					t.Fatalf("equal rows %v and %v lost their input order", got.rows[i-1], got.rows[i])
+				}
+			}
+		})
+	}
+}
+
+func atoi(s string) int {
+	var n int
+	fmt.Sscan(strings.TrimSpace(s), &n)
+	return n
+}
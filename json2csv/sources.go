@@ -0,0 +1,198 @@
+// json2csv/sources.go
+
+package json2csv
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// Source is a named JSON input for ConvertSources. Name identifies the input
+// (typically a file path or URL) in errors and in the "$sourceFile" pseudo-path.
+type Source struct {
+	Name   string
+	Reader io.Reader
+}
+
+// Pseudo-paths can be used as a Field JSONPath to emit provenance data instead
+// of a value from the record. They start with "$", which is never a valid
+// leading character of a record path.
+const (
+	// PathSourceFile resolves to the Name of the Source the record came from.
+	PathSourceFile = "$sourceFile"
+
+	// PathSourceRecordIndex resolves to the zero-based index of the record
+	// within its Source (the position in the top-level JSON array).
+	PathSourceRecordIndex = "$sourceRecordIndex"
+
+	// PathItemIndex resolves to the zero-based index of the row's item
+	// within the flattened array, or null for rows without an item.
+	PathItemIndex = "$itemIndex"
+
+	// PathArrayLength resolves to the number of elements of the flattened
+	// array of the record, including nulls (0 if it is null or missing).
+	PathArrayLength = "$arrayLength"
+
+	// PathRecordNumber resolves to the one-based number of the record
+	// within the whole conversion, counting the records of all sources.
+	PathRecordNumber = "$recordNumber"
+
+	// PathRowNumber resolves to the one-based number of the row among the
+	// data rows written, in the order they are produced (before SortBy and
+	// GroupBy).
+	PathRowNumber = "$rowNumber"
+
+	// PathNow resolves to the time the conversion started, as a time.Time
+	// written in RFC 3339 format. All rows of a conversion share it.
+	PathNow = "$now"
+)
+
+// ErrorPolicy selects how ConvertSources, ConvertFiles and ConvertFS handle
+// a source that fails.
+type ErrorPolicy int
+
+const (
+	// ErrorPolicyAbort stops the conversion at the first error.
+	ErrorPolicyAbort ErrorPolicy = iota
+
+	// ErrorPolicySkipSource abandons a source that cannot be opened or
+	// holds invalid JSON or data, reports it to Options.OnSourceError and
+	// continues with the next source. Rows already written from the source
+	// are kept. Errors writing the output still abort the conversion.
+	ErrorPolicySkipSource
+)
+
+// skipSource reports whether the error err of the named source is to be
+// skipped under Options.ErrorPolicy, calling Options.OnSourceError if so.
+func (c *conversion) skipSource(name string, err error) bool {
+	if c.options.ErrorPolicy != ErrorPolicySkipSource || c.outputFailed || errors.Is(err, errLimitReached) {
+		return false
+	}
+	c.metrics.IncErrors(1)
+	if c.options.OnSourceError != nil {
+		c.options.OnSourceError(name, err)
+	}
+	return true
+}
+
+// ConvertFiles opens each path in turn and converts them with ConvertSources,
+// using the path as the Source Name. Files are opened lazily and closed as soon
+// as they have been converted, so any number of files can be processed.
+func ConvertFiles(paths []string, w io.Writer, options Options) error {
+	return convertOpened(paths, func(path string) (io.ReadCloser, error) {
+		return os.Open(path)
+	}, w, options)
+}
+
+// ConvertFS converts the files in fsys matching pattern (see fs.Glob), in
+// lexical order, like ConvertFiles. It is typically used to combine
+// partitioned exports, e.g. ConvertFS(os.DirFS(dir), "2024-*/*.json", ...).
+// A pattern matching no files is an error.
+func ConvertFS(fsys fs.FS, pattern string, w io.Writer, options Options) error {
+	names, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return fmt.Errorf("json2csv: invalid file pattern %q: %w", pattern, err)
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("json2csv: no files match %q", pattern)
+	}
+	return convertOpened(names, func(name string) (io.ReadCloser, error) {
+		return fsys.Open(name)
+	}, w, options)
+}
+
+// convertOpened converts the named files, opened with open, into one output.
+func convertOpened(names []string, open func(name string) (io.ReadCloser, error), w io.Writer, options Options) error {
+	return withOutput(w, options, func(w io.Writer) error {
+		c, err := newConversion(NewRowWriter(w, options), options)
+		if err != nil {
+			return err
+		}
+		if c.discoverKeys {
+			// Read every file once (or up to Options.DiscoveryRecords) to
+			// discover the keys of expanded fields. Failing files are
+			// skipped silently here and reported below.
+			err := c.collectKeys(func(fn func(Source) error) error {
+				for _, name := range names {
+					f, err := open(name)
+					if err != nil {
+						if c.options.ErrorPolicy == ErrorPolicySkipSource {
+							continue
+						}
+						return fmt.Errorf("json2csv: failed to open input file: %w", err)
+					}
+					err = fn(Source{Name: name, Reader: f})
+					f.Close()
+					if errors.Is(err, errDiscoveryDone) || err != nil && c.options.ErrorPolicy != ErrorPolicySkipSource {
+						return err
+					}
+				}
+				return nil
+			}, c.options.DiscoveryRecords)
+			if err != nil {
+				return err
+			}
+		}
+		return c.run(func() error {
+			for _, name := range names {
+				f, err := open(name)
+				if err != nil {
+					err = fmt.Errorf("json2csv: failed to open input file: %w", err)
+				} else {
+					err = c.convertSource(Source{Name: name, Reader: f})
+					f.Close()
+				}
+				if err != nil && !c.skipSource(name, err) {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// isPseudoPath reports whether path refers to conversion metadata.
+func isPseudoPath(path string) bool {
+	return strings.HasPrefix(path, "$")
+}
+
+// isKnownPseudoPath reports whether path is one of the supported pseudo-paths.
+func isKnownPseudoPath(path string) bool {
+	switch path {
+	case PathSourceFile, PathSourceRecordIndex, PathItemIndex, PathArrayLength,
+		PathRecordNumber, PathRowNumber, PathNow:
+		return true
+	}
+	return false
+}
+
+// pseudoPathValue resolves a pseudo-path for the record currently being processed.
+func (c *conversion) pseudoPathValue(path string) interface{} {
+	switch path {
+	case PathSourceFile:
+		return c.sourceName
+	case PathSourceRecordIndex:
+		return c.recordIndex
+	case PathItemIndex:
+		if c.itemIndex < 0 {
+			return nil
+		}
+		return c.itemIndex
+	case PathArrayLength:
+		if c.flattenArrayPath == "" {
+			return nil // No array is flattened.
+		}
+		return c.arrayLength
+	case PathRecordNumber:
+		return c.recordNumber
+	case PathRowNumber:
+		return c.rowsWritten + 1
+	case PathNow:
+		return c.startTime
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+// json2csv/split.go
+
+package json2csv
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+)
+
+// splitRowWriter is a RowWriter that spreads the rows over a sequence of
+// parts, each produced by open and each starting with the header (if one is
+// written). A new part is started once the current one holds maxRows rows
+// or, if its RowWriter reports its size, maxBytes bytes; limits <= 0 are
+// ignored. At least one part is always produced, so an empty conversion
+// still yields a file with just the header.
+type splitRowWriter struct {
+	open     func(part int) (RowWriter, error) // part numbers start at 1
+	maxRows  int
+	maxBytes int64
+
+	header    []string
+	hasHeader bool
+
+	current RowWriter
+	part    int
+	rows    int // rows in the current part
+}
+
+func (s *splitRowWriter) WriteHeader(header []string) error {
+	s.header = append([]string(nil), header...)
+	s.hasHeader = true
+	return nil // Written at the start of every part.
+}
+
+func (s *splitRowWriter) WriteRow(row []string) error {
+	if s.current != nil && s.full() {
+		if err := s.closePart(); err != nil {
+			return err
+		}
+	}
+	if s.current == nil {
+		if err := s.openPart(); err != nil {
+			return err
+		}
+	}
+	s.rows++
+	return s.current.WriteRow(row)
+}
+
+// full reports whether the current part has reached a limit.
+func (s *splitRowWriter) full() bool {
+	if s.maxRows > 0 && s.rows >= s.maxRows {
+		return true
+	}
+	if sized, ok := s.current.(interface{ size() int64 }); ok && s.maxBytes > 0 && s.rows > 0 {
+		return sized.size() >= s.maxBytes
+	}
+	return false
+}
+
+func (s *splitRowWriter) openPart() error {
+	s.part++
+	s.rows = 0
+	current, err := s.open(s.part)
+	if err != nil {
+		return err
+	}
+	s.current = current
+	if s.hasHeader {
+		return s.current.WriteHeader(s.header)
+	}
+	return nil
+}
+
+func (s *splitRowWriter) closePart() error {
+	err := s.current.Close()
+	s.current = nil
+	return err
+}
+
+func (s *splitRowWriter) Flush() error {
+	if s.current == nil {
+		return nil
+	}
+	return s.current.Flush()
+}
+
+func (s *splitRowWriter) Close() error {
+	if s.current == nil && s.part == 0 {
+		if err := s.openPart(); err != nil {
+			return err
+		}
+	}
+	if s.current == nil {
+		return nil
+	}
+	return s.closePart()
+}
+
+// WriterFactory creates the output of one part of a split conversion (see
+// ConvertSplit). Part numbers start at 1.
+type WriterFactory func(part int) (io.WriteCloser, error)
+
+// PartFiles returns a WriterFactory that creates files named by formatting
+// pattern with the part number, e.g. PartFiles("out/part-%04d.csv").
+func PartFiles(pattern string) WriterFactory {
+	return func(part int) (io.WriteCloser, error) {
+		return os.Create(fmt.Sprintf(pattern, part))
+	}
+}
+
+// ConvertSplit converts r like Convert, spreading the rows over outputs
+// created by create: a new part is started once the current one holds
+// Options.MaxRowsPerFile rows or Options.MaxBytesPerFile bytes. Each part is
+// a complete file with its own header row, wrapped with Options.WrapOutput
+// if set. At least one part is always created.
+func ConvertSplit(r io.Reader, create WriterFactory, options Options) error {
+	parts := &splitRowWriter{
+		maxRows:  options.MaxRowsPerFile,
+		maxBytes: options.MaxBytesPerFile,
+		open: func(part int) (RowWriter, error) {
+			out, err := create(part)
+			if err != nil {
+				return nil, fmt.Errorf("json2csv: failed to create output part %d: %w", part, err)
+			}
+			return openPartWriter(out, options)
+		},
+	}
+	if err := ConvertTo(r, parts, options); err != nil {
+		if part, ok := parts.current.(*partWriter); ok {
+			part.abort()
+		}
+		return err
+	}
+	return nil
+}
+
+// partWriter writes one part of a ConvertSplit or ConvertPartitioned
+// conversion, counting the bytes that reach its output.
+type partWriter struct {
+	RowWriter
+	out     io.Writer      // From the factory; closed if it is an io.Closer.
+	wrapped io.WriteCloser // Options.WrapOutput around out, if set.
+	buf     *bufio.Writer
+	counter *countingWriter
+	flush   bool // Flush every row so that size is exact.
+}
+
+func openPartWriter(out io.Writer, options Options) (*partWriter, error) {
+	p := &partWriter{out: out, counter: &countingWriter{w: out}, flush: options.MaxBytesPerFile > 0}
+	var w io.Writer = p.counter
+	if options.WrapOutput != nil {
+		var err error
+		if p.wrapped, err = options.WrapOutput(w); err != nil {
+			p.closeOut()
+			return nil, fmt.Errorf("json2csv: failed to wrap output writer: %w", err)
+		}
+		w = p.wrapped
+	}
+	// Rows are flushed from the row writer into buf, which batches the
+	// writes to the output.
+	p.buf = bufio.NewWriterSize(w, options.WriterBufferSize)
+	p.RowWriter = NewRowWriter(p.buf, options)
+	return p, nil
+}
+
+func (p *partWriter) WriteRow(row []string) error {
+	if err := p.RowWriter.WriteRow(row); err != nil {
+		return err
+	}
+	if p.flush {
+		return p.RowWriter.Flush()
+	}
+	return nil
+}
+
+// size returns the number of bytes written to the part so far, including
+// those still buffered.
+func (p *partWriter) size() int64 {
+	return p.counter.n + int64(p.buf.Buffered())
+}
+
+func (p *partWriter) Flush() error {
+	if err := p.RowWriter.Flush(); err != nil {
+		return err
+	}
+	return p.buf.Flush()
+}
+
+func (p *partWriter) Close() error {
+	err := p.RowWriter.Close()
+	if err == nil {
+		err = p.buf.Flush()
+	}
+	if p.wrapped != nil {
+		if closeErr := p.wrapped.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("json2csv: failed to close wrapped output writer: %w", closeErr)
+		}
+	}
+	if closeErr := p.closeOut(); closeErr != nil && err == nil {
+		err = fmt.Errorf("json2csv: failed to close output part: %w", closeErr)
+	}
+	return err
+}
+
+// abort releases the part after a failed conversion. Outputs that can be
+// aborted, such as those of BlobParts, are aborted so that no partial part
+// is completed; others are closed with the rows written so far.
+func (p *partWriter) abort() error {
+	if aborter, ok := p.out.(interface{ abort() error }); ok {
+		return aborter.abort()
+	}
+	return p.Close()
+}
+
+// closeOut closes the part's output if it is an io.Closer.
+func (p *partWriter) closeOut() error {
+	if closer, ok := p.out.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+// json2csv/sql.go
+
+package json2csv
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ConvertRows converts the result set rows like Convert, with each SQL row
+// as a record keyed by column name (see SQLRecords). Unlike SQLRecords, it
+// reads the paths in Options.PathSyntax and also decodes the columns that
+// TimeWindow, KeyFilter and PartitionBy descend into. rows is read to the
+// end but not closed.
+func ConvertRows(rows *sql.Rows, w io.Writer, options Options) error {
+	source, err := sqlRecords(rows, jsonColumnSet(options))
+	if err != nil {
+		return err
+	}
+	return ConvertRecords(source, w, options)
+}
+
+// SQLRecords returns a RecordSource with a record per row of rows, keyed by
+// column name. Text columns that a path of fields, or one of their
+// FallbackPaths, descends into (e.g. "payload" for "payload.items[*].sku")
+// are decoded as JSON, so JSON columns can be flattened with the usual
+// Fields and Transformers. Other columns keep their driver values, with
+// []byte turned into string. The paths are read in PathSyntaxDefault.
+func SQLRecords(rows *sql.Rows, fields []Field) (RecordSource, error) {
+	return sqlRecords(rows, jsonColumnSet(Options{Fields: fields}))
+}
+
+// sqlRecords implements SQLRecords, decoding jsonColumns as JSON.
+func sqlRecords(rows *sql.Rows, jsonColumns columnSet) (RecordSource, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("json2csv: failed to read columns: %w", err)
+	}
+
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	index := 0
+	return RecordFunc(func() (map[string]interface{}, error) {
+		if !rows.Next() {
+			if err := rows.Err(); err != nil {
+				return nil, fmt.Errorf("json2csv: failed to read rows: %w", err)
+			}
+			return nil, io.EOF
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, fmt.Errorf("json2csv: failed to scan row %d: %w", index, err)
+		}
+		record := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			value, err := sqlValue(values[i], jsonColumns.has(column))
+			if err != nil {
+				return nil, fmt.Errorf("json2csv: failed to decode column %q of row %d: %w", column, index, err)
+			}
+			record[column] = value
+		}
+		index++
+		return record, nil
+	}), nil
+}
+
+// columnSet is the set of columns holding JSON; all is set if a path may
+// descend into any column (e.g. "..sku").
+type columnSet struct {
+	names map[string]bool
+	all   bool
+}
+
+func (s columnSet) has(column string) bool {
+	return s.all || s.names[column]
+}
+
+// jsonColumnSet returns the columns that the paths of options descend into:
+// those of the fields, including their FallbackPaths, of TimeWindow,
+// KeyFilter and PartitionBy.
+func jsonColumnSet(options Options) columnSet {
+	set := columnSet{names: map[string]bool{}}
+	options, err := options.nativePaths()
+	if err != nil {
+		return set // The conversion fails to validate the paths.
+	}
+	for _, field := range options.Fields {
+		set.addPath(field.JSONPath)
+		for _, fallback := range field.FallbackPaths {
+			set.addPath(fallback)
+		}
+	}
+	if options.TimeWindow != nil {
+		set.addPath(options.TimeWindow.Path)
+	}
+	if options.KeyFilter != nil {
+		set.addPath(options.KeyFilter.Path)
+	}
+	set.addPath(options.PartitionBy)
+	return set
+}
+
+// addPath adds the column that path descends into, if any.
+func (s *columnSet) addPath(path string) {
+	if isPseudoPath(path) {
+		return
+	}
+	prefix, _, flattened := strings.Cut(path, "[*]")
+	compiled, err := compilePath(strings.TrimSuffix(prefix, "."))
+	if err != nil || len(compiled.segments) == 0 {
+		return
+	}
+	first := compiled.segments[0]
+	switch {
+	case first.kind != segmentChild || first.recursive:
+		s.all = true
+	case flattened || len(compiled.segments) > 1:
+		s.names[first.name] = true
+	}
+}
+
+// sqlValue converts a scanned driver value for use in a record, decoding it
+// as JSON if isJSON.
+func sqlValue(value interface{}, isJSON bool) (interface{}, error) {
+	var text []byte
+	switch v := value.(type) {
+	case []byte:
+		text = v
+	case string:
+		text = []byte(v)
+	default:
+		return value, nil
+	}
+	if !isJSON {
+		return string(text), nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(text))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
@@ -0,0 +1,266 @@
+// json2csv/stream_items.go
+
+package json2csv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// spoolMemoryLimit is the number of bytes of raw items an itemSpool keeps in
+// memory before moving them to a temporary file.
+const spoolMemoryLimit = 8 << 20
+
+// itemSpool holds the raw JSON items of one record's flattened array when
+// Options.StreamItems is set, in memory or, past spoolMemoryLimit, in a
+// temporary file, so that they can be decoded one at a time once the rest
+// of the record is known.
+type itemSpool struct {
+	streamed bool // The array of the current record was spooled.
+	count    int  // The number of items spooled.
+	mem      bytes.Buffer
+	file     *os.File
+	fileBuf  *bufio.Writer
+	spilled  bool // The current record's items are in file.
+}
+
+// reset prepares the spool for the next record.
+func (s *itemSpool) reset() {
+	s.streamed = false
+	s.count = 0
+	s.mem.Reset()
+	s.spilled = false
+}
+
+// add appends one raw item.
+func (s *itemSpool) add(raw json.RawMessage) error {
+	s.count++
+	if !s.spilled {
+		s.mem.Write(raw)
+		s.mem.WriteByte('\n')
+		if s.mem.Len() < spoolMemoryLimit {
+			return nil
+		}
+		return s.spill()
+	}
+	s.fileBuf.Write(raw)
+	if err := s.fileBuf.WriteByte('\n'); err != nil {
+		return fmt.Errorf("json2csv: failed to spool array items: %w", err)
+	}
+	return nil
+}
+
+// spill moves the items held in memory to the temporary file.
+func (s *itemSpool) spill() error {
+	if s.file == nil {
+		f, err := os.CreateTemp("", "json2csv-items-*.json")
+		if err != nil {
+			return fmt.Errorf("json2csv: failed to create temporary file: %w", err)
+		}
+		s.file = f
+		s.fileBuf = bufio.NewWriter(f)
+	} else {
+		if err := s.file.Truncate(0); err != nil {
+			return fmt.Errorf("json2csv: failed to spool array items: %w", err)
+		}
+		if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("json2csv: failed to spool array items: %w", err)
+		}
+		s.fileBuf.Reset(s.file)
+	}
+	if _, err := s.fileBuf.Write(s.mem.Bytes()); err != nil {
+		return fmt.Errorf("json2csv: failed to spool array items: %w", err)
+	}
+	s.mem.Reset()
+	s.spilled = true
+	return nil
+}
+
+// each decodes the spooled items in order, calling fn with the index and
+// value of each.
+func (s *itemSpool) each(fn func(index int, item interface{}) error) error {
+	var r io.Reader = bytes.NewReader(s.mem.Bytes())
+	if s.spilled {
+		if err := s.fileBuf.Flush(); err != nil {
+			return fmt.Errorf("json2csv: failed to spool array items: %w", err)
+		}
+		if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("json2csv: failed to read spooled array items: %w", err)
+		}
+		r = s.file
+	}
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	for index := 0; ; index++ {
+		var item interface{}
+		if err := decoder.Decode(&item); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("json2csv: failed to read spooled array items: %w", err)
+		}
+		if err := fn(index, item); err != nil {
+			return err
+		}
+	}
+}
+
+// close removes the temporary file, if any.
+func (s *itemSpool) close() {
+	if s.file != nil {
+		s.file.Close()
+		os.Remove(s.file.Name())
+		s.file = nil
+	}
+}
+
+// decodeRecords streams the JSON array of records in r, calling fn for each
+// after marking its start with startRecord.
+// With Options.StreamItems, the flattened array is left out of the record
+// and its items are passed in a spool instead; otherwise items is nil.
+func (c *conversion) decodeRecords(r io.Reader, fn func(record map[string]interface{}, items *itemSpool) error) error {
+	r, err := decodeInput(r, c.options.InputEncoding)
+	if err != nil {
+		return err
+	}
+	if c.options.LenientJSON {
+		r = newLenientReader(r)
+	}
+	if !c.options.StreamItems {
+		stream := &jsonStream{r: r, multiple: c.options.AllowMultipleDocuments, maxElementBytes: c.options.MaxRecordBytes, skipElement: c.skipRecord}
+		return stream.each(func(decoder *json.Decoder, offset int64) error {
+			c.startRecord(offset)
+			start := decoder.InputOffset()
+			var record map[string]interface{}
+			if err := decoder.Decode(&record); errors.Is(err, ErrRecordTooLarge) {
+				return err
+			} else if err != nil {
+				return decodeError(err)
+			}
+			// The record may have been read ahead of the limit.
+			if max := c.options.MaxRecordBytes; max > 0 && decoder.InputOffset()-start > max {
+				if c.skipRecord(ErrRecordTooLarge) {
+					return nil
+				}
+				return ErrRecordTooLarge
+			}
+			return fn(record, nil)
+		})
+	}
+
+	spool := &itemSpool{}
+	defer spool.close()
+	keys := c.flattenPath.childKeys()
+	stream := &jsonStream{r: r, multiple: c.options.AllowMultipleDocuments, skipElement: c.skipRecord}
+	return stream.each(func(decoder *json.Decoder, offset int64) error {
+		c.startRecord(offset)
+		spool.reset()
+		token, err := decoder.Token()
+		if err != nil {
+			return decodeError(err)
+		}
+		var record map[string]interface{}
+		if delim, ok := token.(json.Delim); ok && delim == '{' {
+			if record, err = decodeStreamingObject(decoder, keys, spool); err != nil {
+				return decodeError(err)
+			}
+		} else if token != nil {
+			return fmt.Errorf("json2csv: failed to decode json object: unexpected %v at start of record", token)
+		}
+		return fn(record, spool)
+	})
+}
+
+// decodeStreamingObject decodes the rest of an object whose "{" has been
+// read. The array at keys (relative to the object) is written to spool
+// item by item instead of being stored; if the value at keys is not an
+// array it is stored as usual.
+func decodeStreamingObject(decoder *json.Decoder, keys []string, spool *itemSpool) (map[string]interface{}, error) {
+	object := map[string]interface{}{}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := token.(string)
+		if token, err = decoder.Token(); err != nil {
+			return nil, err
+		}
+		delim, isDelim := token.(json.Delim)
+		switch {
+		case key != keys[0] || !isDelim:
+			object[key], err = decodeTokenValue(decoder, token)
+		case len(keys) == 1 && delim == '[':
+			spool.streamed = true
+			err = spoolArray(decoder, spool)
+		case len(keys) > 1 && delim == '{':
+			object[key], err = decodeStreamingObject(decoder, keys[1:], spool)
+		default:
+			object[key], err = decodeTokenValue(decoder, token)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, err := decoder.Token(); err != nil { // The closing "}".
+		return nil, err
+	}
+	return object, nil
+}
+
+// spoolArray writes the elements of an array whose "[" has been read to
+// spool and reads the closing "]".
+func spoolArray(decoder *json.Decoder, spool *itemSpool) error {
+	for decoder.More() {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return err
+		}
+		if err := spool.add(raw); err != nil {
+			return err
+		}
+	}
+	_, err := decoder.Token()
+	return err
+}
+
+// decodeTokenValue decodes the value starting with token, which has been
+// read from decoder.
+func decodeTokenValue(decoder *json.Decoder, token json.Token) (interface{}, error) {
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return token, nil // A scalar; numbers are json.Number.
+	}
+	switch delim {
+	case '{':
+		object := map[string]interface{}{}
+		for decoder.More() {
+			keyToken, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			var value interface{}
+			if err := decoder.Decode(&value); err != nil {
+				return nil, err
+			}
+			object[keyToken.(string)] = value
+		}
+		_, err := decoder.Token()
+		return object, err
+	default: // '['
+		array := []interface{}{}
+		for decoder.More() {
+			var value interface{}
+			if err := decoder.Decode(&value); err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		_, err := decoder.Token()
+		return array, err
+	}
+}
@@ -0,0 +1,50 @@
+// json2csv/structs.go
+
+package json2csv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ConvertStructs converts items like ConvertRecords, with each item encoded
+// as by encoding/json (honoring json struct tags and json.Marshaler) to make
+// its record. Items must encode to JSON objects (or null).
+func ConvertStructs[T any](items []T, w io.Writer, options Options) error {
+	return ConvertRecords(StructRecords(items), w, options)
+}
+
+// StructRecords returns a RecordSource with the record of each item, as
+// described for ConvertStructs.
+func StructRecords[T any](items []T) RecordSource {
+	index := 0
+	return RecordFunc(func() (map[string]interface{}, error) {
+		if index >= len(items) {
+			return nil, io.EOF
+		}
+		record, err := structRecord(items[index])
+		if err != nil {
+			return nil, fmt.Errorf("json2csv: failed to encode item %d: %w", index, err)
+		}
+		index++
+		return record, nil
+	})
+}
+
+// structRecord returns item round-tripped through JSON, with numbers as
+// json.Number like records decoded by Convert.
+func structRecord(item interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var record map[string]interface{}
+	if err := decoder.Decode(&record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
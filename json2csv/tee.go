@@ -0,0 +1,246 @@
+// json2csv/tee.go
+
+package json2csv
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// TeeSink is a RowWriter that writes every header and row to each of its
+// sinks in order, e.g. a local archive file and an HTTP upload, so an export
+// can be delivered and archived in a single pass over the input.
+//
+// Writes are synchronous: a row is handed to the next sink only after the
+// previous one accepted it, so the slowest sink throttles the conversion and
+// no unbounded buffering happens inside the tee. With TeeOptions.Concurrent,
+// each sink is written by its own goroutine instead.
+//
+// The first write error stops the tee: it is returned (identifying the failed
+// sink) and every later WriteHeader or WriteRow call fails with the same
+// error, so all sinks hold the same prefix of rows, except that sinks
+// before the failed one may have received the row being written. Flush and
+// Close always reach every sink and return all of their errors joined.
+// TeeOptions.KeepGoing continues with the other sinks instead.
+type TeeSink struct {
+	options TeeOptions
+	sinks   []*teeTarget
+	err     error // first write error, sticky
+	closed  bool  // The goroutines of concurrent sinks have stopped.
+}
+
+// TeeOptions configures a TeeSink.
+type TeeOptions struct {
+	// Concurrent writes to each sink from its own goroutine, through a
+	// queue of Buffer rows, so that a slow sink such as an upload only
+	// throttles the conversion once its queue is full. A write error of a
+	// sink is returned by a later call. Flush waits for every queue to be
+	// written. Close must be called, even after a failed conversion, to
+	// stop the goroutines.
+	Concurrent bool
+	Buffer     int
+
+	// KeepGoing drops a failing sink and continues with the others, so
+	// that e.g. a failed upload does not abort the local export; the
+	// failure is reported to OnSinkError and the sink is not called
+	// again, not even to be closed. Writes fail only once every sink has
+	// failed.
+	KeepGoing   bool
+	OnSinkError func(sink int, err error)
+}
+
+// DefaultTeeBuffer is the default of TeeOptions.Buffer.
+const DefaultTeeBuffer = 64
+
+// teeTarget is a sink of a TeeSink.
+type teeTarget struct {
+	index  int
+	sink   RowWriter
+	failed bool // Dropped under KeepGoing.
+
+	// With TeeOptions.Concurrent, ops feed the goroutine writing the sink,
+	// which closes done when it returns and records its first error.
+	ops  chan teeOp
+	done chan struct{}
+	mu   sync.Mutex
+	err  error
+}
+
+// teeOp is a call on a concurrent sink. reply, if not nil, receives the
+// error of the sink once the call is done.
+type teeOp struct {
+	fn    func(RowWriter) error
+	reply chan error
+}
+
+// NewTeeSink returns a TeeSink writing to sinks.
+func NewTeeSink(sinks ...RowWriter) *TeeSink {
+	return NewTeeSinkWithOptions(TeeOptions{}, sinks...)
+}
+
+// NewTeeSinkWithOptions returns a TeeSink writing to sinks as configured by
+// options.
+func NewTeeSinkWithOptions(options TeeOptions, sinks ...RowWriter) *TeeSink {
+	if options.Buffer <= 0 {
+		options.Buffer = DefaultTeeBuffer
+	}
+	t := &TeeSink{options: options}
+	for i, sink := range sinks {
+		target := &teeTarget{index: i, sink: sink}
+		if options.Concurrent {
+			target.ops = make(chan teeOp, options.Buffer)
+			target.done = make(chan struct{})
+			go target.run()
+		}
+		t.sinks = append(t.sinks, target)
+	}
+	return t
+}
+
+func (t *TeeSink) WriteHeader(header []string) error {
+	return t.write(func(sink RowWriter) error { return sink.WriteHeader(header) })
+}
+
+func (t *TeeSink) WriteRow(row []string) error {
+	if t.options.Concurrent {
+		row = append([]string(nil), row...) // The caller may reuse row.
+	}
+	return t.write(func(sink RowWriter) error { return sink.WriteRow(row) })
+}
+
+func (t *TeeSink) write(fn func(sink RowWriter) error) error {
+	if t.err != nil {
+		return t.err
+	}
+	for _, target := range t.sinks {
+		if target.failed {
+			continue
+		}
+		var err error
+		if t.options.Concurrent {
+			if err = target.error(); err == nil {
+				target.ops <- teeOp{fn: fn}
+			}
+		} else {
+			err = fn(target.sink)
+		}
+		if err != nil {
+			if err := t.fail(target, err); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fail handles the write error err of target. It returns the error of the
+// tee, if it must stop.
+func (t *TeeSink) fail(target *teeTarget, err error) error {
+	err = fmt.Errorf("tee sink %d: %w", target.index, err)
+	if !t.options.KeepGoing {
+		t.err = err
+		return err
+	}
+	target.failed = true
+	if t.options.OnSinkError != nil {
+		t.options.OnSinkError(target.index, err)
+	}
+	for _, target := range t.sinks {
+		if !target.failed {
+			return nil
+		}
+	}
+	t.err = fmt.Errorf("json2csv: every tee sink failed, the last with %w", err)
+	return t.err
+}
+
+func (t *TeeSink) Flush() error {
+	return t.each(RowWriter.Flush, false)
+}
+
+func (t *TeeSink) Close() error {
+	return t.each(RowWriter.Close, true)
+}
+
+// each calls fn on every sink, even after failures, and joins the errors.
+// Under KeepGoing, sinks that failed are skipped and new failures are
+// dropped as for writes. last stops the goroutines of concurrent sinks.
+func (t *TeeSink) each(fn func(RowWriter) error, last bool) error {
+	errs := make([]error, len(t.sinks))
+	if t.options.Concurrent {
+		if t.closed {
+			return nil // E.g. the final Flush of a conversion after Close.
+		}
+		t.closed = last
+		replies := make([]chan error, len(t.sinks))
+		for i, target := range t.sinks {
+			if !target.failed {
+				replies[i] = make(chan error, 1)
+				target.ops <- teeOp{fn: fn, reply: replies[i]}
+			}
+			if last {
+				close(target.ops)
+			}
+		}
+		for i, reply := range replies {
+			if reply != nil {
+				errs[i] = <-reply
+			}
+			if last {
+				<-t.sinks[i].done
+			}
+		}
+	} else {
+		for i, target := range t.sinks {
+			if !target.failed {
+				errs[i] = fn(target.sink)
+			}
+		}
+	}
+
+	var joined []error
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		if t.options.KeepGoing {
+			if err := t.fail(t.sinks[i], err); err != nil {
+				return err
+			}
+			continue
+		}
+		err = fmt.Errorf("tee sink %d: %w", i, err)
+		if t.options.Concurrent && t.err == nil {
+			t.err = err // A write may have failed.
+		}
+		joined = append(joined, err)
+	}
+	return errors.Join(joined...)
+}
+
+// run performs the calls on a concurrent sink until ops is closed. After an
+// error, later calls are skipped and their replies get the error.
+func (target *teeTarget) run() {
+	defer close(target.done)
+	var err error
+	for op := range target.ops {
+		if err == nil {
+			if err = op.fn(target.sink); err != nil {
+				target.mu.Lock()
+				target.err = err
+				target.mu.Unlock()
+			}
+		}
+		if op.reply != nil {
+			op.reply <- err
+		}
+	}
+}
+
+// error returns the first error of a concurrent sink, if any.
+func (target *teeTarget) error() error {
+	target.mu.Lock()
+	defer target.mu.Unlock()
+	return target.err
+}
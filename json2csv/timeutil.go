@@ -0,0 +1,96 @@
+// json2csv/timeutil.go
+
+package json2csv
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// EpochUnit is the unit of numeric (Unix epoch) timestamps.
+type EpochUnit int
+
+const (
+	// EpochSeconds interprets numbers as seconds since the Unix epoch.
+	EpochSeconds EpochUnit = iota
+	// EpochMillis interprets numbers as milliseconds since the Unix epoch.
+	EpochMillis
+	// EpochAuto interprets numbers with an absolute value of at least 1e11
+	// as milliseconds and smaller ones as seconds. 1e11 seconds is in the
+	// year 5138, while 1e11 milliseconds is in 1973.
+	EpochAuto
+)
+
+// epochAutoThreshold is the magnitude from which EpochAuto assumes millis.
+const epochAutoThreshold = 1e11
+
+// resolve returns EpochSeconds or EpochMillis for a value of magnitude v.
+func (u EpochUnit) resolve(v float64) EpochUnit {
+	if u != EpochAuto {
+		return u
+	}
+	if math.Abs(v) >= epochAutoThreshold {
+		return EpochMillis
+	}
+	return EpochSeconds
+}
+
+// epochToTime converts an epoch value in unit to a time.Time. Fractional
+// values keep their sub-unit precision.
+func epochToTime(v float64, unit EpochUnit) time.Time {
+	if unit.resolve(v) == EpochMillis {
+		v /= 1000
+	}
+	sec, frac := math.Modf(v)
+	return time.Unix(int64(sec), int64(frac*1e9))
+}
+
+// parseTimeValue converts a decoded JSON value to a time.Time. Strings are
+// parsed with layout (time.RFC3339 if empty), numbers are Unix epoch values in
+// unit. The boolean result is false for nil values (JSON null or a missing path).
+func parseTimeValue(value interface{}, layout string, unit EpochUnit) (time.Time, bool, error) {
+	if layout == "" {
+		layout = time.RFC3339
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return time.Time{}, false, nil
+	case time.Time:
+		return v, true, nil
+	case string:
+		t, err := time.Parse(layout, strings.TrimSpace(v))
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("cannot parse %q with layout %q: %w", v, layout, err)
+		}
+		return t, true, nil
+	case json.Number: // json.Decoder.UseNumber() is used by Convert
+		if i, err := v.Int64(); err == nil {
+			if unit.resolve(float64(i)) == EpochMillis {
+				return time.UnixMilli(i), true, nil
+			}
+			return time.Unix(i, 0), true, nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("cannot convert json.Number %q to a timestamp: %w", v, err)
+		}
+		return epochToTime(f, unit), true, nil
+	case float64:
+		return epochToTime(v, unit), true, nil
+	case float32:
+		return epochToTime(float64(v), unit), true, nil
+	case int, int8, int16, int32, int64:
+		i := reflect.ValueOf(v).Int()
+		if unit.resolve(float64(i)) == EpochMillis {
+			return time.UnixMilli(i), true, nil
+		}
+		return time.Unix(i, 0), true, nil
+	default:
+		return time.Time{}, false, fmt.Errorf("unsupported type %T for timestamp", value)
+	}
+}
@@ -0,0 +1,118 @@
+// json2csv/transform.go
+
+package json2csv
+
+import "fmt"
+
+// Chain returns a Transformer that applies transformers in order, passing each
+// one's output to the next, e.g. Chain(Trim, Lower, Truncate(50)). Nil
+// transformers are skipped. The first error stops the chain.
+func Chain(transformers ...Transformer) Transformer {
+	return func(value interface{}, originalRecord map[string]interface{}) (interface{}, error) {
+		return applyTransformers(transformers, value, originalRecord)
+	}
+}
+
+// applyTransformers runs transformers in order over value.
+func applyTransformers(transformers []Transformer, value interface{}, originalRecord map[string]interface{}) (interface{}, error) {
+	for i, transformer := range transformers {
+		if transformer == nil {
+			continue
+		}
+		var err error
+		value, err = transformer(value, originalRecord)
+		if err != nil {
+			if len(transformers) > 1 {
+				return nil, fmt.Errorf("transformer %d: %w", i+1, err)
+			}
+			return nil, err
+		}
+	}
+	return value, nil
+}
+
+// TransformContext describes the row a ContextTransformer is applied to.
+type TransformContext struct {
+	// Record is the original record, as passed to a Transformer.
+	Record map[string]interface{}
+
+	// Item is the current item of the flattened array, or nil for a row
+	// without an item (see EmptyArrayEmitParent).
+	Item interface{}
+
+	// ItemIndex is the zero-based index of Item within the flattened array,
+	// or -1 if there is no item.
+	ItemIndex int
+
+	// RecordIndex is the zero-based index of the record within its Source.
+	RecordIndex int
+
+	// Source is the Name of the Source of the record, if any.
+	Source string
+
+	// Field is the field being converted.
+	Field Field
+
+	// Missing reports that the field's path (and any FallbackPaths) is not
+	// present in the row, as opposed to present with a JSON null value; in
+	// both cases the value is nil unless Field.MissingValue is set. It is
+	// false for constant, computed and query fields and for values resolved
+	// by a PathResolver.
+	Missing bool
+}
+
+// ContextTransformer is a Transformer that receives the context of the row
+// along with the value, e.g. to combine the value with sibling values of
+// the item:
+//
+//	func(value interface{}, ctx *json2csv.TransformContext) (interface{}, error) {
+//		item, _ := ctx.Item.(map[string]interface{})
+//		price, _ := value.(json.Number)
+//		quantity, _ := item["quantity"].(json.Number)
+//		p, err1 := price.Float64()
+//		q, err2 := quantity.Float64()
+//		if err1 != nil || err2 != nil {
+//			return nil, nil // Not both numbers.
+//		}
+//		return p * q, nil
+//	}
+//
+// Numbers of the input are json.Number values. The context is only valid
+// during the call.
+type ContextTransformer func(value interface{}, ctx *TransformContext) (interface{}, error)
+
+// ComputeFunc computes the value of a field from the row (see
+// Field.Compute), e.g. a full name:
+//
+//	func(ctx *json2csv.TransformContext) (interface{}, error) {
+//		first, _ := ctx.Record["first_name"].(string)
+//		last, _ := ctx.Record["last_name"].(string)
+//		return strings.TrimSpace(first + " " + last), nil
+//	}
+//
+// The context is only valid during the call.
+type ComputeFunc func(ctx *TransformContext) (interface{}, error)
+
+// Query is a compiled expression of a query language other than the path
+// syntax of this package (see Field.Query). Evaluate must not modify record
+// and may be called concurrently.
+type Query interface {
+	Evaluate(record map[string]interface{}) (interface{}, error)
+}
+
+// transform applies the field's Transformer followed by its Transformers
+// and ContextTransformer.
+func (f Field) transform(value interface{}, ctx *TransformContext) (interface{}, error) {
+	if f.Transformer != nil {
+		var err error
+		value, err = f.Transformer(value, ctx.Record)
+		if err != nil {
+			return nil, err
+		}
+	}
+	value, err := applyTransformers(f.Transformers, value, ctx.Record)
+	if err != nil || f.ContextTransformer == nil {
+		return value, err
+	}
+	return f.ContextTransformer(value, ctx)
+}
@@ -0,0 +1,40 @@
+// json2csv/transform_array.go
+
+package json2csv
+
+import (
+	"fmt"
+	"strings"
+)
+
+// JoinArray returns a Transformer that joins the elements of an array into a
+// single cell separated by separator, e.g. JoinArray(";") turns
+// ["book", "fiction"] into "book;fiction". Each element is first passed
+// through elementFormat, if given, in order (see Chain), so
+// JoinArray(", ", RoundTo(2)) formats a list of prices.
+//
+// Null elements are written as empty strings. Nil is passed through
+// unchanged and an empty array becomes an empty string; values that are not
+// arrays return an error.
+func JoinArray(separator string, elementFormat ...Transformer) Transformer {
+	format := Chain(elementFormat...)
+	return func(value interface{}, originalRecord map[string]interface{}) (interface{}, error) {
+		if value == nil {
+			return nil, nil
+		}
+		elements, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("json2csv: JoinArray: unsupported non-array type %T", value)
+		}
+
+		parts := make([]string, len(elements))
+		for i, element := range elements {
+			formatted, err := format(element, originalRecord)
+			if err != nil {
+				return nil, fmt.Errorf("json2csv: JoinArray: element %d: %w", i, err)
+			}
+			parts[i] = valueToString(formatted)
+		}
+		return strings.Join(parts, separator), nil
+	}
+}
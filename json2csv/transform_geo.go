@@ -0,0 +1,245 @@
+// json2csv/transform_geo.go
+
+package json2csv
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GeoPoint is a geographic position in decimal degrees. It is written as
+// "lat,lon" unless formatted by LatLon, WKTPoint or Geohash.
+//
+// The geo transformers accept GeoPoint values (e.g. from GeoPointFrom),
+// objects with "lat" and "lon" (or "lng", "latitude" and "longitude")
+// numbers, GeoJSON points such as {"type": "Point", "coordinates": [lon,
+// lat]} and "lat,lon" strings. Nil is passed through.
+type GeoPoint struct {
+	Lat, Lon float64
+}
+
+// String returns the point as "lat,lon".
+func (p GeoPoint) String() string {
+	return strconv.FormatFloat(p.Lat, 'f', -1, 64) + "," + strconv.FormatFloat(p.Lon, 'f', -1, 64)
+}
+
+// geoPointValue converts value to a GeoPoint. The boolean result is false
+// for nil.
+func geoPointValue(value interface{}) (GeoPoint, bool, error) {
+	var p GeoPoint
+	switch v := value.(type) {
+	case nil:
+		return p, false, nil
+	case GeoPoint:
+		p = v
+	case map[string]interface{}:
+		if coordinates, ok := v["coordinates"].([]interface{}); ok && v["type"] == "Point" {
+			if len(coordinates) < 2 {
+				return p, false, errors.New("GeoJSON point without two coordinates")
+			}
+			var err error
+			if p.Lon, err = coordinate(coordinates[0]); err != nil {
+				return p, false, err
+			}
+			if p.Lat, err = coordinate(coordinates[1]); err != nil {
+				return p, false, err
+			}
+			break
+		}
+		lat, lon := firstKey(v, "lat", "latitude"), firstKey(v, "lon", "lng", "longitude")
+		if lat == nil || lon == nil {
+			return p, false, errors.New("object without lat and lon")
+		}
+		var err error
+		if p.Lat, err = coordinate(lat); err != nil {
+			return p, false, err
+		}
+		if p.Lon, err = coordinate(lon); err != nil {
+			return p, false, err
+		}
+	case string:
+		lat, lon, ok := strings.Cut(v, ",")
+		if !ok {
+			return p, false, fmt.Errorf("cannot convert %q to a point", v)
+		}
+		var err error
+		if p.Lat, err = coordinate(lat); err != nil {
+			return p, false, err
+		}
+		if p.Lon, err = coordinate(lon); err != nil {
+			return p, false, err
+		}
+	default:
+		return p, false, fmt.Errorf("unsupported point type %T", value)
+	}
+	if p.Lat < -90 || p.Lat > 90 || p.Lon < -180 || p.Lon > 180 {
+		return p, false, fmt.Errorf("point %v is out of range", p)
+	}
+	return p, true, nil
+}
+
+// firstKey returns the value of the first of keys present in m.
+func firstKey(m map[string]interface{}, keys ...string) interface{} {
+	for _, key := range keys {
+		if value, ok := m[key]; ok {
+			return value
+		}
+	}
+	return nil
+}
+
+// coordinate converts a latitude or longitude to a float64.
+func coordinate(value interface{}) (float64, error) {
+	f, ok, err := numberValue(value)
+	if err == nil && !ok {
+		err = errors.New("missing coordinate")
+	}
+	return f, err
+}
+
+// geoTransformer adapts a GeoPoint function to a Transformer.
+func geoTransformer(name string, fn func(GeoPoint) string) Transformer {
+	return func(value interface{}, originalRecord map[string]interface{}) (interface{}, error) {
+		p, ok, err := geoPointValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("json2csv: %s: %w", name, err)
+		}
+		if !ok {
+			return nil, nil
+		}
+		return fn(p), nil
+	}
+}
+
+// LatLon is a Transformer that formats points as "lat,lon", e.g.
+// "52.52,13.405".
+var LatLon = geoTransformer("LatLon", GeoPoint.String)
+
+// WKTPoint is a Transformer that formats points as Well-Known Text, with
+// the longitude first: "POINT(13.405 52.52)".
+var WKTPoint = geoTransformer("WKTPoint", func(p GeoPoint) string {
+	return "POINT(" + strconv.FormatFloat(p.Lon, 'f', -1, 64) + " " + strconv.FormatFloat(p.Lat, 'f', -1, 64) + ")"
+})
+
+// geohashAlphabet is the base 32 alphabet of geohashes.
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// Geohash returns a Transformer that encodes points as geohashes of
+// precision characters (1 to 12), e.g. Geohash(7) turns 52.52,13.405 into
+// "u33dc0c". It fails if precision is out of range.
+func Geohash(precision int) (Transformer, error) {
+	t, err := geohash(precision)
+	if err != nil {
+		return nil, fmt.Errorf("json2csv: Geohash: %w", err)
+	}
+	return t, nil
+}
+
+// MustGeohash is like Geohash but panics if precision is out of range.
+func MustGeohash(precision int) Transformer {
+	t, err := Geohash(precision)
+	if err != nil {
+		panic(err.Error())
+	}
+	return t
+}
+
+// geohash is Geohash with unprefixed errors, for the registry.
+func geohash(precision int) (Transformer, error) {
+	if precision < 1 || precision > 12 {
+		return nil, fmt.Errorf("precision %d is not between 1 and 12", precision)
+	}
+	return geoTransformer("Geohash", func(p GeoPoint) string {
+		latRange, lonRange := [2]float64{-90, 90}, [2]float64{-180, 180}
+		hash := make([]byte, precision)
+		even := true // Bits alternate between longitude and latitude.
+		for i := range hash {
+			var index byte
+			for bit := 0; bit < 5; bit++ {
+				r, x := &latRange, p.Lat
+				if even {
+					r, x = &lonRange, p.Lon
+				}
+				mid := (r[0] + r[1]) / 2
+				index <<= 1
+				if x >= mid {
+					index |= 1
+					r[0] = mid
+				} else {
+					r[1] = mid
+				}
+				even = !even
+			}
+			hash[i] = geohashAlphabet[index]
+		}
+		return string(hash)
+	}), nil
+}
+
+// GeoPointFrom returns a ComputeFunc (see Field.Compute) that combines the
+// numbers at latPath and lonPath, e.g. "items[*].lat" and "items[*].lon",
+// into a GeoPoint, for data with separate latitude and longitude fields.
+// It yields nil if either is null. It fails if a path is invalid or a
+// pseudo-path.
+func GeoPointFrom(latPath, lonPath string) (ComputeFunc, error) {
+	lat, err := newRowPath(latPath)
+	if err != nil {
+		return nil, fmt.Errorf("json2csv: GeoPointFrom: %w", err)
+	}
+	lon, err := newRowPath(lonPath)
+	if err != nil {
+		return nil, fmt.Errorf("json2csv: GeoPointFrom: %w", err)
+	}
+	return func(ctx *TransformContext) (interface{}, error) {
+		latValue, lonValue := lat.resolve(ctx), lon.resolve(ctx)
+		if latValue == nil || lonValue == nil {
+			return nil, nil
+		}
+		p, _, err := geoPointValue(map[string]interface{}{"lat": latValue, "lon": lonValue})
+		if err != nil {
+			return nil, fmt.Errorf("json2csv: GeoPointFrom: %w", err)
+		}
+		return p, nil
+	}, nil
+}
+
+// MustGeoPointFrom is like GeoPointFrom but panics if a path is invalid,
+// like regexp.MustCompile, so that it can be used in Field literals.
+func MustGeoPointFrom(latPath, lonPath string) ComputeFunc {
+	compute, err := GeoPointFrom(latPath, lonPath)
+	if err != nil {
+		panic(err.Error())
+	}
+	return compute
+}
+
+// rowPath is a path resolved against the row of a TransformContext.
+type rowPath struct {
+	plan fieldPlan
+}
+
+// newRowPath compiles path, which must not be a pseudo-path.
+func newRowPath(path string) (rowPath, error) {
+	if isPseudoPath(path) {
+		return rowPath{}, fmt.Errorf("pseudo-path %q cannot be used here", path)
+	}
+	plan, err := compileFieldPlan(path)
+	if err != nil {
+		return rowPath{}, err
+	}
+	return rowPath{plan: plan}, nil
+}
+
+// resolve returns the value of the path for the row of ctx: for paths with
+// "[*]", the part after it within the current item.
+func (rp rowPath) resolve(ctx *TransformContext) interface{} {
+	if !rp.plan.inItem {
+		return rp.plan.path.evaluate(ctx.Record)
+	}
+	if ctx.Item == nil {
+		return nil
+	}
+	return rp.plan.path.evaluate(ctx.Item)
+}
@@ -0,0 +1,30 @@
+// json2csv/transform_json.go
+
+package json2csv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// StringifyJSON is a Transformer that renders objects and arrays as compact
+// JSON, e.g. {"a":1,"b":[true,null]}, so nested structures are preserved
+// verbatim in a single cell instead of Go's map[a:1 b:[true <nil>]] form.
+// Object keys are sorted and HTML characters are not escaped. Nil and scalar
+// values are returned unchanged.
+func StringifyJSON(value interface{}, originalRecord map[string]interface{}) (interface{}, error) {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+	default:
+		return value, nil
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, fmt.Errorf("json2csv: StringifyJSON: %w", err)
+	}
+	return string(bytes.TrimSuffix(buf.Bytes(), []byte("\n"))), nil
+}
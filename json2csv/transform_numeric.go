@@ -0,0 +1,179 @@
+// json2csv/transform_numeric.go
+
+package json2csv
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// --- Standard numeric Transformers ---
+//
+// The numeric transformers accept any JSON number (json.Number, float64 or Go
+// integer types) as well as numeric strings, pass nil through unchanged and
+// return an error for values that are not numbers. Results are returned as
+// json.Number so they are written in plain decimal notation (never as 1e+06)
+// and can be fed into further numeric transformers.
+
+// numberValue converts value to a float64. The boolean result is false for nil.
+func numberValue(value interface{}) (float64, bool, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, false, nil
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return 0, false, fmt.Errorf("cannot convert %q to a number: %w", v, err)
+		}
+		return f, true, nil
+	case float64:
+		return v, true, nil
+	case float32:
+		return float64(v), true, nil
+	case int, int8, int16, int32, int64:
+		return float64(reflect.ValueOf(v).Int()), true, nil
+	case uint, uint8, uint16, uint32, uint64:
+		return float64(reflect.ValueOf(v).Uint()), true, nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, false, fmt.Errorf("cannot convert %q to a number", v)
+		}
+		return f, true, nil
+	default:
+		return 0, false, fmt.Errorf("unsupported non-numeric type %T", value)
+	}
+}
+
+// formatNumber renders f in plain decimal notation with the minimal number
+// of digits needed to represent it exactly.
+func formatNumber(f float64) json.Number {
+	return json.Number(strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+// numericTransformer adapts a float64 function to a Transformer.
+func numericTransformer(name string, fn func(float64) json.Number) Transformer {
+	return func(value interface{}, originalRecord map[string]interface{}) (interface{}, error) {
+		f, ok, err := numberValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("json2csv: %s: %w", name, err)
+		}
+		if !ok {
+			return nil, nil
+		}
+		return fn(f), nil
+	}
+}
+
+// RoundTo returns a Transformer that rounds numbers to n decimal places
+// (half away from zero) and always writes exactly n decimals, e.g. RoundTo(2)
+// turns 10.5 into "10.50". A negative n rounds to tens, hundreds, etc.
+func RoundTo(n int) Transformer {
+	scale := math.Pow(10, float64(n))
+	decimals := n
+	if decimals < 0 {
+		decimals = 0
+	}
+	return numericTransformer("RoundTo", func(f float64) json.Number {
+		rounded := math.Round(f*scale) / scale
+		return json.Number(strconv.FormatFloat(rounded, 'f', decimals, 64))
+	})
+}
+
+// MultiplyBy returns a Transformer that multiplies numbers by factor, e.g.
+// MultiplyBy(0.001) to convert grams to kilograms.
+func MultiplyBy(factor float64) Transformer {
+	return numericTransformer("MultiplyBy", func(f float64) json.Number {
+		return formatNumber(f * factor)
+	})
+}
+
+// ClampRange returns a Transformer that limits numbers to [min, max]. Values
+// inside the range are returned unchanged.
+func ClampRange(min, max float64) Transformer {
+	return func(value interface{}, originalRecord map[string]interface{}) (interface{}, error) {
+		f, ok, err := numberValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("json2csv: ClampRange: %w", err)
+		}
+		switch {
+		case !ok:
+			return nil, nil
+		case f < min:
+			return formatNumber(min), nil
+		case f > max:
+			return formatNumber(max), nil
+		}
+		return value, nil
+	}
+}
+
+// CentsToDollars is a Transformer that converts an amount in minor units
+// (cents) to major units with two decimals, e.g. 1234 becomes "12.34".
+// Integer amounts are converted exactly, without floating point rounding.
+func CentsToDollars(value interface{}, originalRecord map[string]interface{}) (interface{}, error) {
+	var cents int64
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case json.Number:
+		i, err := v.Int64()
+		if err != nil {
+			return centsToDollarsFloat(value, originalRecord)
+		}
+		cents = i
+	case int, int8, int16, int32, int64:
+		cents = reflect.ValueOf(v).Int()
+	default:
+		return centsToDollarsFloat(value, originalRecord)
+	}
+
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+	}
+	abs := uint64(cents)
+	if cents < 0 {
+		abs = uint64(-cents)
+	}
+	return json.Number(fmt.Sprintf("%s%d.%02d", sign, abs/100, abs%100)), nil
+}
+
+var centsToDollarsFloat = numericTransformer("CentsToDollars", func(f float64) json.Number {
+	return json.Number(strconv.FormatFloat(f/100, 'f', 2, 64))
+})
+
+// ParseStringNumber is a Transformer that parses numbers stored as strings,
+// such as " 1,234.50 " or "1_000", into a json.Number. White space, ","
+// thousands separators and "_" digit separators are removed. Empty strings
+// become nil; strings that are not numbers return an error. Values that are
+// already numbers are returned unchanged.
+func ParseStringNumber(value interface{}, originalRecord map[string]interface{}) (interface{}, error) {
+	s, isString := value.(string)
+	if !isString {
+		if _, _, err := numberValue(value); err != nil {
+			return nil, fmt.Errorf("json2csv: ParseStringNumber: %w", err)
+		}
+		return value, nil
+	}
+
+	cleaned := strings.Map(func(r rune) rune {
+		switch r {
+		case ',', '_', ' ', '\t', '\n', '\r':
+			return -1
+		}
+		return r
+	}, s)
+	cleaned = strings.TrimPrefix(cleaned, "+")
+	if cleaned == "" {
+		return nil, nil
+	}
+	if _, err := strconv.ParseFloat(cleaned, 64); err != nil {
+		return nil, fmt.Errorf("json2csv: ParseStringNumber: cannot parse %q as a number", s)
+	}
+	return json.Number(cleaned), nil
+}
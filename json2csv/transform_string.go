@@ -0,0 +1,207 @@
+// json2csv/transform_string.go
+
+package json2csv
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// --- Standard string Transformers ---
+//
+// The string transformers below pass nil through unchanged (it is written as
+// an empty cell) and convert any other non-string value to its CSV string
+// form before transforming it, so they can be used on numbers and booleans too.
+
+// stringValue returns the string form of value, or false for nil.
+func stringValue(value interface{}) (string, bool) {
+	if value == nil {
+		return "", false
+	}
+	if s, ok := value.(string); ok {
+		return s, true
+	}
+	return valueToString(value), true
+}
+
+// mapString applies fn to the string form of value. Nil stays nil.
+func mapString(value interface{}, fn func(string) string) interface{} {
+	s, ok := stringValue(value)
+	if !ok {
+		return nil
+	}
+	return fn(s)
+}
+
+// stringTransformer adapts a string function to a Transformer.
+func stringTransformer(fn func(string) string) Transformer {
+	return func(value interface{}, originalRecord map[string]interface{}) (interface{}, error) {
+		return mapString(value, fn), nil
+	}
+}
+
+// Trim is a Transformer that removes leading and trailing white space.
+func Trim(value interface{}, originalRecord map[string]interface{}) (interface{}, error) {
+	return mapString(value, strings.TrimSpace), nil
+}
+
+// Upper is a Transformer that converts a value to upper case.
+func Upper(value interface{}, originalRecord map[string]interface{}) (interface{}, error) {
+	return mapString(value, strings.ToUpper), nil
+}
+
+// Lower is a Transformer that converts a value to lower case.
+func Lower(value interface{}, originalRecord map[string]interface{}) (interface{}, error) {
+	return mapString(value, strings.ToLower), nil
+}
+
+// TitleCase is a Transformer that upper-cases the first letter of every word
+// and lower-cases the rest ("jOHN o'neil-smith" becomes "John O'neil-Smith").
+// Words are separated by any rune that is not a letter, digit or apostrophe.
+func TitleCase(value interface{}, originalRecord map[string]interface{}) (interface{}, error) {
+	return mapString(value, toTitleCase), nil
+}
+
+func toTitleCase(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	inWord := false
+	for _, r := range s {
+		if inWord {
+			b.WriteRune(unicode.ToLower(r))
+		} else {
+			b.WriteRune(unicode.ToTitle(r))
+		}
+		inWord = unicode.IsLetter(r) || unicode.IsDigit(r) || r == '\'' || r == '’'
+	}
+	return b.String()
+}
+
+// Truncate returns a Transformer that shortens values to at most n runes.
+// A negative n is treated as zero.
+func Truncate(n int) Transformer {
+	if n < 0 {
+		n = 0
+	}
+	return stringTransformer(func(s string) string {
+		if utf8.RuneCountInString(s) <= n {
+			return s
+		}
+		i, count := 0, 0
+		for i = range s {
+			if count == n {
+				break
+			}
+			count++
+		}
+		return s[:i]
+	})
+}
+
+// ReplaceRegex returns a Transformer that replaces all matches of the regular
+// expression pattern with repl, which may reference submatches as in
+// regexp.Regexp.ReplaceAllString ("$1"). Like regexp.MustCompile, it panics if
+// pattern does not compile, so invalid mappings fail at configuration time.
+func ReplaceRegex(pattern, repl string) Transformer {
+	re := regexp.MustCompile(pattern)
+	return stringTransformer(func(s string) string {
+		return re.ReplaceAllString(s, repl)
+	})
+}
+
+// PadLeft returns a Transformer that left-pads values with pad up to width
+// runes, e.g. PadLeft(6, '0') turns "42" into "000042". Longer values are
+// left unchanged.
+func PadLeft(width int, pad rune) Transformer {
+	return stringTransformer(func(s string) string {
+		if n := width - utf8.RuneCountInString(s); n > 0 {
+			return strings.Repeat(string(pad), n) + s
+		}
+		return s
+	})
+}
+
+// PadRight returns a Transformer that right-pads values with pad up to width
+// runes. Longer values are left unchanged.
+func PadRight(width int, pad rune) Transformer {
+	return stringTransformer(func(s string) string {
+		if n := width - utf8.RuneCountInString(s); n > 0 {
+			return s + strings.Repeat(string(pad), n)
+		}
+		return s
+	})
+}
+
+// StripHTML is a Transformer that removes HTML tags and comments, drops the
+// contents of <script> and <style> elements and unescapes entities such as
+// "&amp;". It is meant for cleaning up rich-text fields, not for sanitizing
+// untrusted HTML.
+func StripHTML(value interface{}, originalRecord map[string]interface{}) (interface{}, error) {
+	return mapString(value, stripHTMLTags), nil
+}
+
+func stripHTMLTags(s string) string {
+	var b strings.Builder
+	for len(s) > 0 {
+		lt := strings.IndexByte(s, '<')
+		if lt == -1 {
+			b.WriteString(s)
+			break
+		}
+		b.WriteString(s[:lt])
+		s = s[lt:]
+
+		if strings.HasPrefix(s, "<!--") {
+			end := strings.Index(s, "-->")
+			if end == -1 {
+				break
+			}
+			s = s[end+len("-->"):]
+			continue
+		}
+
+		gt := strings.IndexByte(s, '>')
+		if gt == -1 {
+			break // Unterminated tag: drop the remainder.
+		}
+		tag := strings.ToLower(s[1:gt])
+		s = s[gt+1:]
+
+		// Skip everything up to the matching end tag of raw text elements.
+		for _, raw := range []string{"script", "style"} {
+			if tag == raw || strings.HasPrefix(tag, raw+" ") {
+				end := indexASCIIFold(s, "</"+raw)
+				if end == -1 {
+					s = ""
+				} else {
+					s = s[end:]
+				}
+			}
+		}
+	}
+	return html.UnescapeString(b.String())
+}
+
+// indexASCIIFold returns the index in s of the first instance of the
+// lowercase ASCII string substr, ignoring the case of ASCII letters, or -1.
+// Searching strings.ToLower(s) instead would give indexes of the lowered
+// string, whose length can differ from that of s.
+func indexASCIIFold(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		match := true
+		for j := 0; j < len(substr) && match; j++ {
+			c := s[i+j]
+			if 'A' <= c && c <= 'Z' {
+				c += 'a' - 'A'
+			}
+			match = c == substr[j]
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
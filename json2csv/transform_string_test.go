@@ -0,0 +1,27 @@
+// json2csv/transform_string_test.go
+
+package json2csv
+
+import "testing"
+
+func TestStripHTMLTags(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"<p>Fish &amp; <b>chips</b></p>", "Fish & chips"},
+		{"a<!-- note -->b", "ab"},
+		{"a<script>x < y</script>b", "ab"},
+		{"a<STYLE type=text/css>p {}</Style>b", "ab"},
+		// Lowering "İ" changes its length, which must not shift the end of
+		// the script.
+		{"<script>İİİİ</script>after", "after"},
+		{"İ<script>x</SCRIPT>ü", "İü"},
+		{"a<script>never closed", "a"},
+		{"a<b", "a"},
+	}
+	for _, test := range tests {
+		if got := stripHTMLTags(test.in); got != test.want {
+			t.Errorf("stripHTMLTags(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// json2csv/transform_time.go
+
+package json2csv
+
+import (
+	"fmt"
+	"time"
+)
+
+// --- Standard date/time Transformers ---
+
+// ParseTime returns a Transformer that converts timestamps to time.Time so
+// they can be reformatted by FormatTime or a custom transformer. Strings are
+// parsed with layout (time.RFC3339 if empty, which also accepts fractional
+// seconds); numbers are Unix epoch seconds or milliseconds, told apart by
+// magnitude (see EpochAuto). Nil is passed through.
+//
+// A time.Time written without FormatTime is rendered as RFC 3339.
+func ParseTime(layout string) Transformer {
+	return func(value interface{}, originalRecord map[string]interface{}) (interface{}, error) {
+		t, ok, err := parseTimeValue(value, layout, EpochAuto)
+		if err != nil {
+			return nil, fmt.Errorf("json2csv: ParseTime: %w", err)
+		}
+		if !ok {
+			return nil, nil
+		}
+		return t, nil
+	}
+}
+
+// FormatTime returns a Transformer that formats timestamps with layout
+// (time.RFC3339 if empty) in loc (UTC if nil). It accepts time.Time values,
+// e.g. from ParseTime with a custom layout, as well as RFC 3339 strings and
+// epoch seconds or milliseconds. Nil is passed through.
+//
+// For example, FormatTime("02.01.2006 15:04", berlin) renders
+// "2024-06-01T12:00:00Z" as "01.06.2024 14:00".
+func FormatTime(layout string, loc *time.Location) Transformer {
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	return func(value interface{}, originalRecord map[string]interface{}) (interface{}, error) {
+		t, ok, err := parseTimeValue(value, time.RFC3339, EpochAuto)
+		if err != nil {
+			return nil, fmt.Errorf("json2csv: FormatTime: %w", err)
+		}
+		if !ok {
+			return nil, nil
+		}
+		return t.In(loc).Format(layout), nil
+	}
+}
@@ -0,0 +1,181 @@
+// json2csv/transform_units.go
+
+package json2csv
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// unit is a unit of measurement: a value v of the unit is v*factor+offset
+// in the base unit of its dimension.
+type unit struct {
+	dimension      string
+	factor, offset float64
+}
+
+// units are the units known to ConvertUnit and FormatDuration.
+var units = map[string]unit{
+	// Data, in bytes. KB, MB, ... are decimal; KiB, MiB, ... binary.
+	"B":   {"data", 1, 0},
+	"KB":  {"data", 1e3, 0},
+	"MB":  {"data", 1e6, 0},
+	"GB":  {"data", 1e9, 0},
+	"TB":  {"data", 1e12, 0},
+	"KiB": {"data", 1 << 10, 0},
+	"MiB": {"data", 1 << 20, 0},
+	"GiB": {"data", 1 << 30, 0},
+	"TiB": {"data", 1 << 40, 0},
+
+	// Length, in meters.
+	"mm": {"length", 0.001, 0},
+	"cm": {"length", 0.01, 0},
+	"m":  {"length", 1, 0},
+	"km": {"length", 1000, 0},
+	"in": {"length", 0.0254, 0},
+	"ft": {"length", 0.3048, 0},
+	"yd": {"length", 0.9144, 0},
+	"mi": {"length", 1609.344, 0},
+
+	// Mass, in grams.
+	"g":  {"mass", 1, 0},
+	"kg": {"mass", 1000, 0},
+	"oz": {"mass", 28.349523125, 0},
+	"lb": {"mass", 453.59237, 0},
+
+	// Temperature, in degrees Celsius.
+	"C": {"temperature", 1, 0},
+	"F": {"temperature", 5.0 / 9, -32 * 5.0 / 9},
+	"K": {"temperature", 1, -273.15},
+
+	// Time, in seconds.
+	"ms":  {"time", 0.001, 0},
+	"s":   {"time", 1, 0},
+	"min": {"time", 60, 0},
+	"h":   {"time", 3600, 0},
+	"d":   {"time", 86400, 0},
+}
+
+// unitConversion returns the function converting values from one unit to
+// another, which must measure the same dimension.
+func unitConversion(from, to string) (func(float64) float64, error) {
+	f, ok := units[from]
+	if !ok {
+		return nil, fmt.Errorf("unknown unit %q", from)
+	}
+	t, ok := units[to]
+	if !ok {
+		return nil, fmt.Errorf("unknown unit %q", to)
+	}
+	if f.dimension != t.dimension {
+		return nil, fmt.Errorf("cannot convert %s (%s) to %s (%s)", from, f.dimension, to, t.dimension)
+	}
+	return func(v float64) float64 {
+		return roundSignificant((v*f.factor + f.offset - t.offset) / t.factor)
+	}, nil
+}
+
+// roundSignificant rounds f to 12 significant digits, dropping the noise of
+// floating-point arithmetic such as 211.99999999999997 for 212.
+func roundSignificant(f float64) float64 {
+	rounded, err := strconv.ParseFloat(strconv.FormatFloat(f, 'g', 12, 64), 64)
+	if err != nil {
+		return f
+	}
+	return rounded
+}
+
+// ConvertUnit returns a Transformer that converts numbers from one unit of
+// measurement to another, e.g. ConvertUnit("B", "MiB") for file sizes or
+// ConvertUnit("C", "F") for temperatures. Results are rounded to 12
+// significant digits; combine with RoundTo for fewer decimals.
+//
+// The units are: data B, KB, MB, GB, TB (powers of 1000) and KiB, MiB, GiB,
+// TiB (powers of 1024); length mm, cm, m, km, in, ft, yd, mi; mass g, kg,
+// oz, lb; temperature C, F, K; time ms, s, min, h, d. It fails if a unit is
+// unknown or the units measure different things.
+func ConvertUnit(from, to string) (Transformer, error) {
+	t, err := convertUnit(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("json2csv: ConvertUnit: %w", err)
+	}
+	return t, nil
+}
+
+// MustConvertUnit is like ConvertUnit but panics if the units are invalid,
+// like regexp.MustCompile, so that it can be used in Field literals and
+// invalid mappings fail at configuration time.
+func MustConvertUnit(from, to string) Transformer {
+	t, err := ConvertUnit(from, to)
+	if err != nil {
+		panic(err.Error())
+	}
+	return t
+}
+
+// convertUnit is ConvertUnit with unprefixed errors, for the registry.
+func convertUnit(from, to string) (Transformer, error) {
+	convert, err := unitConversion(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return numericTransformer("ConvertUnit", func(f float64) json.Number {
+		return formatNumber(convert(f))
+	}), nil
+}
+
+// FormatDuration returns a Transformer that formats numbers of timeUnit
+// (see ConvertUnit) as "HH:MM:SS", rounded to the second, e.g.
+// FormatDuration("s") turns 3725 into "01:02:05". Hours are not wrapped
+// into days ("27:00:00") and negative durations start with "-". It fails
+// if timeUnit is not a time unit.
+func FormatDuration(timeUnit string) (Transformer, error) {
+	t, err := formatDuration(timeUnit)
+	if err != nil {
+		return nil, fmt.Errorf("json2csv: FormatDuration: %w", err)
+	}
+	return t, nil
+}
+
+// MustFormatDuration is like FormatDuration but panics if timeUnit is not a
+// time unit.
+func MustFormatDuration(timeUnit string) Transformer {
+	t, err := FormatDuration(timeUnit)
+	if err != nil {
+		panic(err.Error())
+	}
+	return t
+}
+
+// formatDuration is FormatDuration with unprefixed errors, for the
+// registry.
+func formatDuration(timeUnit string) (Transformer, error) {
+	toSeconds, err := unitConversion(timeUnit, "s")
+	if err != nil {
+		return nil, err
+	}
+	return func(value interface{}, originalRecord map[string]interface{}) (interface{}, error) {
+		f, ok, err := numberValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("json2csv: FormatDuration: %w", err)
+		}
+		if !ok {
+			return nil, nil
+		}
+		seconds := math.Round(toSeconds(f))
+		if math.IsInf(seconds, 0) || math.IsNaN(seconds) || math.Abs(seconds) > math.MaxInt64/2 {
+			return nil, fmt.Errorf("json2csv: FormatDuration: %v is out of range", f)
+		}
+		var b strings.Builder
+		total := int64(seconds)
+		if total < 0 {
+			b.WriteByte('-')
+			total = -total
+		}
+		fmt.Fprintf(&b, "%02d:%02d:%02d", total/3600, total/60%60, total%60)
+		return b.String(), nil
+	}, nil
+}
@@ -1,9 +1,11 @@
 // json2csv/types.go
+
 package json2csv
 
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"reflect"
 	"time"
 )
@@ -20,14 +22,99 @@ type Transformer func(value interface{}, originalRecord map[string]interface{})
 // Example: "user_id", "address.city", "items[*].item_id"
 type Field struct {
 	// JSONPath is the dot-separated path to the value in the JSON object.
-	// Can include "[*]" to denote an array for flattening.
+	// Can include "[*]" to denote an array for flattening. Indexes, slices,
+	// wildcards, recursive descent and filters are supported as well, e.g.
+	// "items[0].sku" or "items[?(@.price>10)][*].sku" (see Paths in the
+	// package documentation).
+	// Pseudo-paths starting with "$" (see PathSourceFile) resolve to
+	// conversion metadata instead of record data.
 	JSONPath string
 
+	// FallbackPaths are tried in order when the value at JSONPath is null
+	// or missing: the field takes the first non-null value, e.g. of
+	// "contact.mobile", then "contact.phone". Like JSONPath, they may
+	// address the flattened array with "[*]" or be pseudo-paths.
+	FallbackPaths []string
+
 	// CSVHeader is the header text for this column in the output CSV.
 	CSVHeader string
 
 	// Transformer is an optional function to modify the value before writing it to CSV.
 	Transformer Transformer
+
+	// Transformers is an optional list of transformers applied in order after
+	// Transformer, each receiving the previous one's output. See also Chain.
+	Transformers []Transformer
+
+	// ContextTransformer, if set, is applied after Transformers. Unlike
+	// them, it also sees the current array item and the position of the row
+	// (see TransformContext).
+	ContextTransformer ContextTransformer
+
+	// Expand, if set, expands a JSONPath ending in ".*" into one column per
+	// key of the object (see KeyExpansion).
+	Expand *KeyExpansion
+
+	// ArrayMode selects how a JSONPath with "[*]" handles the array: by
+	// default each item gets a row; ArrayPivot spreads the items over
+	// MaxElements columns instead.
+	ArrayMode ArrayMode
+
+	// MaxElements is the number of columns of an ArrayPivot field.
+	MaxElements int
+
+	// Type, if set, declares the type of the column's values, checked after
+	// the transformers (see FieldType and Options.StrictTypes). A value that
+	// does not fit the type fails the conversion, unless OnError says
+	// otherwise.
+	Type FieldType
+
+	// OnError selects how a transformer error or a value that does not fit
+	// Type is handled: by default it fails the conversion, but it can also
+	// give an empty cell, Default, or drop the row (see FieldErrorPolicy).
+	OnError FieldErrorPolicy
+
+	// Locale, if set, overrides Options.Locale for the field; LocaleNone
+	// disables it.
+	Locale string
+
+	// Default is the value written under FieldErrorUseDefault. It is
+	// formatted like any other value.
+	Default interface{}
+
+	// MissingValue, if not nil, replaces the value of the field when its
+	// path is not present in the row, leaving JSON null values nil, e.g.
+	// "N/A" for downstream systems that treat a missing key differently
+	// from null. The transformers and Type apply to it.
+	MissingValue interface{}
+
+	// KeepWhitespace exempts the field from Options.TrimSpace and
+	// Options.CollapseWhitespace, e.g. for preformatted text.
+	KeepWhitespace bool
+
+	// Required makes a null or missing value (after the transformers) an
+	// error, handled by OnError like a type error. With
+	// FieldErrorUseDefault, Default replaces such values.
+	Required bool
+
+	// Constant, if not nil, is the value of the field in every row, such as
+	// an environment name or an export batch ID; JSONPath must then be
+	// empty. The transformers and Type apply to it like to any other value.
+	Constant interface{}
+
+	// Compute, if set, computes the value of the field from the record and
+	// the current item, for columns combining several values such as
+	// price * quantity; JSONPath must then be empty. Its errors are handled
+	// like transformer errors, and the transformers and Type apply to its
+	// result.
+	Compute ComputeFunc
+
+	// Query, if set, computes the value of the field with an expression in
+	// another query language, such as jq (package json2csv/jq) or JMESPath
+	// (package json2csv/jmespath), evaluated against the whole record;
+	// JSONPath must then be empty. Its errors are handled like transformer
+	// errors, and the transformers and Type apply to its result.
+	Query Query
 }
 
 // Options contains configuration for the JSON to CSV conversion.
@@ -40,20 +127,453 @@ type Options struct {
 	// preceding the first "[*]".
 	Fields []Field
 
+	// SelectHeaders, if not nil, keeps only the Fields whose CSVHeader it
+	// lists, and DropHeaders removes the Fields whose CSVHeader it lists,
+	// so one field list can serve several exports, e.g. a full and a slim
+	// one. The kept fields stay in their order. Listing a header that no
+	// field has is an error.
+	SelectHeaders []string
+	DropHeaders   []string
+
+	// ColumnOrder, if set, reorders the columns by CSVHeader, independently
+	// of the order of Fields: the fields it lists come first, in its order,
+	// followed by the others in their order. Like SelectHeaders, it may list
+	// dropped fields but not headers that no field has.
+	ColumnOrder []string
+
+	// PathSyntax selects the syntax of the paths of Fields, TimeWindow,
+	// KeyFilter and PartitionBy. Defaults to PathSyntaxDefault.
+	PathSyntax PathSyntax
+
+	// PathResolver, if set, resolves the JSONPaths and FallbackPaths of the
+	// fields in a path language of its own. The part of a path before the
+	// first "[*]" still selects the flattened array in the built-in syntax;
+	// the part after it is resolved against each item, which must be an
+	// object. Pseudo-paths, filters and PartitionBy are unaffected. Key
+	// expansion and ArrayPivot cannot be used, nor a PathSyntax.
+	PathResolver PathResolver
+
 	// Delimiter is the character used to separate fields in the CSV output.
-	// Defaults to ',' if the zero value '\0' is used.
+	// Defaults to DefaultDelimiter if the zero value '\0' is used.
 	Delimiter rune
 
-	// AddHeader determines whether to include a header row at the beginning
-	// of the output CSV based on the CSVHeader fields. Defaults to true if
-	// explicitly set to true, otherwise false (Go zero value). Note: the
-	// Convert function applies a default of true if not explicitly set to false.
+	// CSVWriter, if set, creates the csv.Writer of FormatCSV output instead
+	// of the package, so that callers control UseCRLF, Comma and whatever
+	// else csv.Writer offers. Delimiter, if set, overrides the Comma of
+	// the writer; functions that read existing files back, such as
+	// ConvertAppend, parse them with Delimiter, so set it rather than Comma
+	// for those. It cannot be combined with Lossless. To write with a
+	// single pre-configured csv.Writer, use ConvertTo with NewCSVRowWriter.
+	CSVWriter CSVWriterFactory
+
+	// OmitHeader disables the header row. By default the header is
+	// written, from the CSVHeader of the fields.
+	OmitHeader bool
+
+	// AddHeader requests the header row.
+	//
+	// Deprecated: Since v1 the header row is written unless OmitHeader is
+	// set, and AddHeader has no effect. This is a breaking change for code
+	// that left AddHeader false to omit the header, which must set
+	// OmitHeader instead (see CHANGELOG.md). The field is kept so that code
+	// setting it continues to compile.
 	AddHeader bool
+
+	// HeaderOnEmpty selects whether the header is written when the
+	// conversion writes no data rows. By default it is; with
+	// HeaderOnEmptyOmit the header is only written along with the first
+	// row, so an empty conversion writes nothing (see also
+	// Report.RowsWritten and Report.HeaderWritten).
+	HeaderOnEmpty HeaderOnEmpty
+
+	// Format selects the output table format. Defaults to FormatCSV.
+	// Delimiter only applies to FormatCSV.
+	Format Format
+
+	// TimeWindow, if set, skips records whose timestamp at TimeWindow.Path
+	// falls outside the window.
+	TimeWindow *TimeWindow
+
+	// KeyFilter, if set, skips records whose value at KeyFilter.Path is not
+	// in its allow list (or is in its deny list).
+	KeyFilter *KeyFilter
+
+	// Offset skips the first Offset rows (or records, see LimitUnit) and
+	// Limit, if positive, stops the conversion after Limit more, without
+	// reading the rest of the input. Rows are counted after filtering,
+	// records as they are read, across all sources.
+	Offset int
+	Limit  int
+
+	// LimitUnit selects what Offset and Limit count. Defaults to LimitRows.
+	LimitUnit LimitUnit
+
+	// GroupBy, if set, aggregates the rows by key columns, writing one row
+	// per group (see GroupBy). SortBy applies to the grouped rows. It cannot
+	// be combined with StatsWriter, SchemaWriter or PartitionBy, and
+	// Report.RowsWritten counts the rows before grouping.
+	GroupBy *GroupBy
+
+	// SortBy, if set, sorts the rows by these columns, the first key being
+	// the most significant; rows that compare equal keep their input order.
+	// No row is written before the input has been read completely. Rows
+	// beyond SortMemory bytes are sorted in temporary files and merged, so
+	// inputs larger than memory can be sorted. Offset and Limit apply to the
+	// unsorted rows.
+	SortBy []SortKey
+
+	// SortMemory is the approximate number of bytes of rows SortBy keeps in
+	// memory. Defaults to DefaultSortMemory.
+	SortMemory int64
+
+	// SampleEvery, if greater than 1, keeps only every SampleEvery-th row
+	// (the first, the n+1-th, ...) that passes the filters. SampleRate, if
+	// between 0 and 1, keeps each row with that probability, e.g. 0.01 for
+	// about 1% of the rows. Sampling is deterministic: the same input,
+	// options and SampleSeed select the same rows. Both apply before Offset
+	// and Limit (in rows), for profiling a sample of very large inputs.
+	SampleEvery int
+	SampleRate  float64
+	SampleSeed  int64
+
+	// EmptyArrayBehavior selects what happens to records without items to
+	// flatten. By default they are skipped.
+	EmptyArrayBehavior EmptyArrayBehavior
+
+	// AllowScalarArrayItems flattens arrays of scalars, such as
+	// "tags": ["a", "b"], instead of failing on their non-object elements:
+	// a "[*]" path with nothing after it, such as "tags[*]", yields the
+	// scalar, and other item paths yield null. Nested arrays still fail.
+	AllowScalarArrayItems bool
+
+	// InvalidItems selects how elements of the flattened array that cannot
+	// be items (non-objects, or nested arrays with AllowScalarArrayItems)
+	// are handled. By default they fail the conversion.
+	InvalidItems InvalidItemPolicy
+
+	// StatsWriter, if set, receives a column profile as CSV (see StatsHeader)
+	// after a successful conversion: one row per output column with its
+	// non-null count, estimated distinct count, min, max and a sample value.
+	// Typically this is a stats.csv file delivered next to the output.
+	StatsWriter io.Writer
+
+	// IncludePaths and ExcludePaths filter the columns discovered for key
+	// expansions (see KeyExpansion), e.g. to drop PII columns. They are
+	// path.Match patterns matched against the expanded path without "[*]",
+	// and the key joined by a dot: the key "email" of "users[*].attrs.*"
+	// is matched as "users.attrs.email", so "*.email" excludes it. A key
+	// gets a column if it matches an include pattern (or IncludePaths is
+	// empty) and no exclude pattern. KeyExpansion.Keys are not filtered.
+	IncludePaths []string
+	ExcludePaths []string
+
+	// DiscoveryRecords, if positive, discovers the keys of key expansions
+	// from the first DiscoveryRecords records only, instead of the whole
+	// input: only they are read twice, from a temporary file, and the rest
+	// of the input is streamed once, so the first rows are written sooner
+	// and large inputs are not copied. Keys first seen in later records get
+	// no column; they are listed in Report.LateKeys. ConvertPages always
+	// spools every page.
+	DiscoveryRecords int
+
+	// WarnUnmappedKeys collects the keys of the input that no field
+	// references, to notice when an upstream API adds data the output does
+	// not carry. Each is reported once, as the path of the outermost such
+	// key in the default syntax, such as "items[*].discount", in
+	// Report.UnmappedKeys and to OnUnmappedKey, if set. A field references
+	// the keys along its JSONPath and FallbackPaths and everything below
+	// them; Constant, Compute and Query fields reference no key. It cannot
+	// be combined with a PathResolver.
+	WarnUnmappedKeys bool
+	OnUnmappedKey    func(path string)
+
+	// ExpectedSchema, if set, checks every record against the keys and
+	// types it lists, reporting new keys, missing keys and type changes in
+	// Report.SchemaDrift or failing the conversion, as it selects. With
+	// StreamItems, only the items of converted records are checked.
+	ExpectedSchema *ExpectedSchema
+
+	// SchemaWriter, if set, receives a schema of the output in SchemaFormat
+	// after a successful conversion, for loading the output into a database
+	// or warehouse. Column types are the Field.Type if set, and otherwise
+	// inferred from the values written (after transformers).
+	SchemaWriter io.Writer
+
+	// SchemaFormat selects the schema written to SchemaWriter.
+	SchemaFormat SchemaFormat
+
+	// SchemaTable is the table name in SchemaSQL schemas. Defaults to "data".
+	SchemaTable string
+
+	// WrapOutput, if set, wraps the output writer before anything is written
+	// to it, e.g. with encrypt.Age to encrypt the export as it is produced so
+	// plaintext never reaches the destination. The wrapper is closed when the
+	// conversion ends.
+	WrapOutput OutputWrapper
+
+	// Locale, if set, is a BCP 47 language tag such as "de" or "fr-CH"
+	// whose decimal and grouping separators are used for the numbers
+	// written by every field (see also Field.Locale): with "de", 1234.56
+	// is written as "1.234,56". Integers are grouped as well, so ID columns
+	// may need a Field.Locale of LocaleNone. Numbers keep their fraction
+	// digits. Dates can be localized with FormatTimeLocale. Locale does not
+	// apply to Lossless output.
+	Locale string
+
+	// HeaderTranslations, if set, localizes the header row for the selected
+	// locale. Values are not translated.
+	HeaderTranslations *HeaderTranslations
+
+	// BiDi selects how header and data cells with right-to-left text are
+	// written. Defaults to BiDiNone.
+	BiDi BiDiMode
+
+	// Lossless selects the type-preserving CSV profile (FormatCSV only):
+	// strings are always quoted, numbers and booleans never are and null is
+	// written as LosslessNull, so a reverse conversion can restore the JSON
+	// types exactly. Rows passed to a RowWriter hold the encoded cells.
+	Lossless bool
+
+	// HeaderNaming derives the header of fields without a CSVHeader from
+	// their JSONPath. Defaults to HeaderNamingNone, which leaves them empty.
+	HeaderNaming HeaderNaming
+
+	// HeaderCase converts the header cells to upper or lower case, after
+	// HeaderNaming and HeaderTranslations. Defaults to HeaderCaseAsIs.
+	HeaderCase HeaderCase
+
+	// HeaderComment, if set, is written before the header row, e.g.
+	// "generated 2024-06-01 by json2csv v1.2" for ingest systems that
+	// expect annotated files. Each of its lines is prefixed with
+	// CommentPrefix in FormatCSV and written as an HTML comment in
+	// FormatMarkdown and FormatHTML. It is only written along with the
+	// header, by the RowWriters of NewRowWriter.
+	HeaderComment string
+
+	// CommentPrefix starts the lines of HeaderComment in FormatCSV.
+	// Defaults to DefaultCommentPrefix.
+	CommentPrefix string
+
+	// DuplicateHeaders selects whether duplicate header cells are written
+	// as is (the default), rejected or renamed with a numeric suffix.
+	DuplicateHeaders DuplicateHeaders
+
+	// StrictTypes disables the coercion of values to their Field.Type, so
+	// that e.g. the string "42" in a TypeInt column is an error.
+	StrictTypes bool
+
+	// PreserveNumberStrings writes the numbers of the input exactly as they
+	// appear in it, e.g. "0.30" or "1.0" in a TypeInt column, instead of
+	// normalizing them to their Field.Type. Locale formatting only applies
+	// to numbers it can format without rounding through float64, so 64-bit
+	// IDs and long decimals are written unchanged. Transformers such as
+	// RoundTo still compute with float64 values.
+	PreserveNumberStrings bool
+
+	// MaxCellLength, if positive, limits the cells to that many bytes, so
+	// that a pathological value such as a large embedded JSON document
+	// does not produce a row loaders reject. LongCells selects what happens
+	// to longer cells; they are counted in Report and in the
+	// StatsTruncatedColumn of the StatsWriter profile. With Lossless, only
+	// strings are limited, before quoting.
+	MaxCellLength int
+	LongCells     LongCellPolicy
+
+	// Newlines selects how line breaks within the header and the cells are
+	// written: as they are (the default), replaced with NewlineReplacement
+	// or escaped (see NewlinePolicy).
+	Newlines           NewlinePolicy
+	NewlineReplacement string
+
+	// TrimSpace removes leading and trailing white space from all string
+	// cells, and CollapseWhitespace replaces each run of white space within
+	// them, line breaks included, with a single space, as the Trim
+	// transformer would for each field. Fields with KeepWhitespace are
+	// written as they are.
+	TrimSpace          bool
+	CollapseWhitespace bool
+
+	// Metrics, if set, receives the number of records read, rows written
+	// and errors of the conversion as it runs, and its duration.
+	Metrics Metrics
+
+	// ErrorPolicy selects whether a failing input source aborts the
+	// conversion (the default) or is skipped.
+	ErrorPolicy ErrorPolicy
+
+	// OnSourceError, if set, is called with the name and error of every
+	// source skipped under ErrorPolicySkipSource.
+	OnSourceError func(source string, err error)
+
+	// MaxRecordBytes, if positive, rejects records larger than that many
+	// bytes of JSON, so that a pathological record cannot exhaust memory:
+	// a record is rejected as soon as its size exceeds the limit, having
+	// buffered at most that much of it, and is not decoded further.
+	// OversizedRecords selects what happens to it. It applies to JSON
+	// sources, not to RecordSources, and cannot be combined with
+	// StreamItems, which already bounds the memory of large records.
+	MaxRecordBytes   int64
+	OversizedRecords OversizedRecordPolicy
+
+	// MalformedRecords selects what happens to syntactically broken JSON
+	// records, e.g. in partially corrupted log dumps: by default the
+	// conversion fails, losing everything after the first bad byte, while
+	// MalformedRecordsSkip skips to the next record and continues.
+	// OnMalformedRecord, if set, is called with the error of every skipped
+	// record, a *ConvertError giving its position. It applies to JSON
+	// sources, not to RecordSources.
+	MalformedRecords  MalformedRecordPolicy
+	OnMalformedRecord func(err error)
+
+	// AllowMultipleDocuments converts inputs holding several concatenated
+	// JSON arrays, e.g. "[...][...]" or one array per line, streaming the
+	// records of each in sequence. Content after an array that does not
+	// start another one is skipped as trailing garbage, up to the next
+	// line starting with '['. Without it, conversion stops at the end of
+	// the first array and anything after it is ignored.
+	AllowMultipleDocuments bool
+
+	// LenientJSON accepts // and /* */ comments and trailing commas in
+	// objects and arrays, as found in hand-maintained configuration-style
+	// JSON files. They are filtered out as the input is read; byte offsets
+	// in errors still refer to the original input.
+	LenientJSON bool
+
+	// InputEncoding selects the character encoding of JSON sources, e.g.
+	// InputEncodingAuto for UTF-16 files written by Windows tools. A UTF-8
+	// byte order mark is always stripped.
+	InputEncoding InputEncoding
+
+	// StreamItems decodes the items of the flattened array one at a time
+	// instead of decoding each record whole, so that a record with a huge
+	// array does not have to fit in memory. The array's items are spooled
+	// (to a temporary file past a few megabytes) until the rest of the
+	// record has been read. The flattened array must be addressed by object
+	// keys only, such as "data.items[*]", and is not visible to record-level
+	// paths and filters. An invalid item (such as a non-object) fails the
+	// conversion after the rows of the items before it have been written.
+	StreamItems bool
+
+	// PartitionBy is the path whose value selects the output of each row in
+	// ConvertPartitioned, e.g. "country" or "items[*].region". Other
+	// conversion functions ignore it. It cannot be combined with SortBy or
+	// GroupBy.
+	PartitionBy string
+
+	// MaxRowsPerFile and MaxBytesPerFile, if positive, limit the size of
+	// each output file of ConvertSplit. A file may exceed MaxBytesPerFile by
+	// the last row written to it. Other conversion functions ignore them.
+	MaxRowsPerFile  int
+	MaxBytesPerFile int64
+
+	// FlushEvery, if positive, flushes the output after every FlushEvery data
+	// rows, so that readers of the output (e.g. tail -f) see rows as they are
+	// converted and a crash loses at most that many rows. Zero flushes only
+	// at the end. Flushing does not reach through Options.WrapOutput: a
+	// compressing or encrypting wrapper may still hold the data back.
+	FlushEvery int
+
+	// SyncFiles syncs the files written by ConvertToFile to stable storage
+	// before they replace their destination, so that a power loss cannot
+	// leave an empty or partial file behind. Other conversion functions
+	// ignore it.
+	SyncFiles bool
+
+	// WriterBufferSize is the size in bytes of the output buffer. Larger
+	// buffers mean fewer writes to the underlying writer; zero uses the
+	// default of 4096 bytes.
+	WriterBufferSize int
 }
 
 // DefaultDelimiter is the comma character.
 const DefaultDelimiter = ','
 
+// delimiter returns the Delimiter, or DefaultDelimiter if it is unset.
+func (o Options) delimiter() rune {
+	if o.Delimiter == 0 {
+		return DefaultDelimiter
+	}
+	return o.Delimiter
+}
+
+// DefaultCommentPrefix is the default of Options.CommentPrefix.
+const DefaultCommentPrefix = "# "
+
+// HeaderOnEmpty selects whether an output without data rows gets a header.
+type HeaderOnEmpty int
+
+const (
+	// HeaderOnEmptyWrite writes the header regardless of the rows (the
+	// default).
+	HeaderOnEmptyWrite HeaderOnEmpty = iota
+
+	// HeaderOnEmptyOmit writes the header only if there is a data row.
+	HeaderOnEmptyOmit
+)
+
+// Format identifies the kind of table Convert writes.
+type Format int
+
+const (
+	// FormatCSV writes delimiter-separated values (the default).
+	FormatCSV Format = iota
+
+	// FormatMarkdown writes a GitHub-flavored Markdown table. Markdown tables
+	// require a header, so an empty one is written with OmitHeader.
+	FormatMarkdown
+
+	// FormatHTML writes a single HTML <table> element, with the header row
+	// (if enabled) in <thead> and data rows in <tbody>. Cells are HTML-escaped.
+	FormatHTML
+)
+
+// EmptyArrayBehavior selects what Convert does with a record whose flattened
+// array is null, missing, empty or holds only nulls.
+type EmptyArrayBehavior int
+
+const (
+	// EmptyArraySkipRecord writes no row for the record (the default).
+	EmptyArraySkipRecord EmptyArrayBehavior = iota
+
+	// EmptyArrayEmitParent writes one row for the record, with the values
+	// of its record-level fields and null for the item fields ("[*]"
+	// paths). Item-level filters apply to that row as usual.
+	EmptyArrayEmitParent
+
+	// EmptyArrayError fails the conversion.
+	EmptyArrayError
+)
+
+// LimitUnit selects what Options.Offset and Options.Limit count.
+type LimitUnit int
+
+const (
+	// LimitRows counts the data rows that pass the filters.
+	LimitRows LimitUnit = iota
+
+	// LimitRecords counts the input records, whatever rows they produce.
+	LimitRecords
+)
+
+// InvalidItemPolicy selects how Convert handles an element of the flattened
+// array that is not an object, for feeds that mix objects with occasional
+// strings or numbers.
+type InvalidItemPolicy int
+
+const (
+	// InvalidItemsError fails the conversion (the default).
+	InvalidItemsError InvalidItemPolicy = iota
+
+	// InvalidItemsSkip ignores the element.
+	InvalidItemsSkip
+
+	// InvalidItemsStringify flattens the element as a string item: its
+	// text, or compact JSON for arrays. As for scalar items (see
+	// Options.AllowScalarArrayItems), a "[*]" path with nothing after it
+	// yields the string and other item paths yield null.
+	InvalidItemsStringify
+)
+
 // --- Standard Transformers provided by the package ---
 
 // BoolToYesNo is a Transformer that converts a boolean value to "Yes" or "No".
@@ -73,38 +593,69 @@ func BoolToYesNo(value interface{}, originalRecord map[string]interface{}) (inte
 	return value, nil
 }
 
+// DefaultTimestampLayout is the layout used by FormatUnixTimestamp.
+const DefaultTimestampLayout = "2006-01-02 15:04:05"
+
 // FormatUnixTimestamp is a Transformer that converts a Unix timestamp (float64 or int)
 // to a formatted date string. It handles nil values by returning an empty string.
+// The timestamp is rendered in the local time zone with DefaultTimestampLayout.
+//
+// Deprecated: The output depends on the time zone of the host. Use
+// FormatUnixTimestampIn, e.g. FormatUnixTimestampIn(time.Local, "") for the
+// same output.
 func FormatUnixTimestamp(value interface{}, originalRecord map[string]interface{}) (interface{}, error) {
-    // Handle nil value gracefully (e.g., from missing field or JSON null)
-    if value == nil {
-        return "", nil // Return empty string for nil timestamp, no error
-    }
+	return formatUnixTimestamp("FormatUnixTimestamp", value, time.Local, DefaultTimestampLayout)
+}
+
+// FormatUnixTimestampIn returns a Transformer like FormatUnixTimestamp that
+// renders timestamps in loc (UTC if nil) using layout (DefaultTimestampLayout
+// if empty), e.g. FormatUnixTimestampIn(tokyo, "2006/01/02 15:04 MST").
+func FormatUnixTimestampIn(loc *time.Location, layout string) Transformer {
+	if loc == nil {
+		loc = time.UTC
+	}
+	if layout == "" {
+		layout = DefaultTimestampLayout
+	}
+	return func(value interface{}, originalRecord map[string]interface{}) (interface{}, error) {
+		return formatUnixTimestamp("FormatUnixTimestampIn", value, loc, layout)
+	}
+}
+
+// formatUnixTimestamp implements FormatUnixTimestamp and FormatUnixTimestampIn.
+// name is used in error messages.
+func formatUnixTimestamp(name string, value interface{}, loc *time.Location, layout string) (interface{}, error) {
+	// Handle nil value gracefully (e.g., from missing field or JSON null)
+	if value == nil {
+		return "", nil // Return empty string for nil timestamp, no error
+	}
 
 	var t time.Time
 	switch v := value.(type) {
 	case float64: // Common for numbers decoded into interface{}
 		t = time.Unix(int64(v), 0)
 	case int, int8, int16, int32, int64: // Handle if decoded into specific int types
-        t = time.Unix(reflect.ValueOf(v).Int(), 0) // Use reflection for generic int conversion
+		t = time.Unix(reflect.ValueOf(v).Int(), 0) // Use reflection for generic int conversion
 	case json.Number: // If json.Decoder.UseNumber() is used
 		i, err := v.Int64()
 		if err != nil {
-			return nil, fmt.Errorf("json2csv: FormatUnixTimestamp: cannot convert json.Number %q to int64: %w", v, err)
+			return nil, fmt.Errorf("json2csv: %s: cannot convert json.Number %q to int64: %w", name, v, err)
 		}
 		t = time.Unix(i, 0)
 	default:
 		// If not nil and not a recognized number type, it's an unsupported type.
 		// Return an error to indicate a problem with the data type.
-		return value, fmt.Errorf("json2csv: FormatUnixTimestamp: unsupported non-nil type %T for timestamp", value)
+		return value, fmt.Errorf("json2csv: %s: unsupported non-nil type %T for timestamp", name, value)
 	}
-	// Use a common format, can be made configurable via transformer options if needed
-	return t.Format("2006-01-02 15:04:05"), nil
+	return t.In(loc).Format(layout), nil
 }
 
 // ItemsSummaryTransformer is a Transformer that summarizes a JSON array
 // by reporting its size or status (e.g., "3 Items", "Empty Array", "Null Array").
-// Useful for fields in the non-flattening scenario that are arrays.
+//
+// Deprecated: It was meant for the arrays of the non-flattening mode removed
+// before v1. Use JoinArray to write the elements of an array that is not
+// flattened.
 func ItemsSummaryTransformer(value interface{}, originalRecord map[string]interface{}) (interface{}, error) {
 	if value == nil {
 		return "Null Array", nil // Handle nil value (JSON null or missing path)
@@ -130,7 +681,7 @@ func ItemsSummaryTransformer(value interface{}, originalRecord map[string]interf
 
 // getValueByDotPath is a helper function (defined in utils.go) to retrieve values by path.
 // No need to define it here.
-// func getValueByDotPath(data map[string]interface{}, path string) (interface{}, error) { ... }
+// func getValueByDotPath(data interface{}, path string) (interface{}, error) { ... }
 
 // getFlattenArrayPath is a helper function (defined in utils.go) to determine the array path for flattening.
 // No need to define it here.
@@ -0,0 +1,169 @@
+// json2csv/unmapped.go
+
+package json2csv
+
+import (
+	"sort"
+)
+
+// keyTree holds the keys the fields reference below one node of a record.
+type keyTree struct {
+	all  bool                // A field references the node itself, so all of its keys.
+	keys map[string]*keyTree // Child keys by name
+	any  *keyTree            // Any child key or array element
+}
+
+// child returns the tree of key, creating it if needed.
+func (t *keyTree) child(key string) *keyTree {
+	if t.keys == nil {
+		t.keys = map[string]*keyTree{}
+	}
+	if t.keys[key] == nil {
+		t.keys[key] = &keyTree{}
+	}
+	return t.keys[key]
+}
+
+// addPath adds the keys referenced by the compiled path cp to the tree.
+func (t *keyTree) addPath(cp *compiledPath) {
+	for _, segment := range cp.segments {
+		if segment.recursive {
+			t.all = true // Any descendant may match.
+			return
+		}
+		if segment.kind == segmentChild {
+			t = t.child(segment.name)
+			continue
+		}
+		if t.any == nil {
+			t.any = &keyTree{}
+		}
+		t = t.any
+	}
+	t.all = true
+}
+
+// unmappedKeys finds the keys of the input that no field references, for
+// Options.WarnUnmappedKeys.
+type unmappedKeys struct {
+	root      *keyTree
+	items     *keyTree // The items of the flattened array, for Options.StreamItems
+	itemsPath string
+	seen      map[string]bool
+	report    func(path string)
+}
+
+// newUnmappedKeys builds the key tree of fields, whose paths are in the
+// default syntax. Pseudo-paths, Constants, Compute and Query fields
+// reference no key.
+func newUnmappedKeys(fields []Field, flattenArrayPath string, report func(path string)) (*unmappedKeys, error) {
+	u := &unmappedKeys{root: &keyTree{}, seen: map[string]bool{}, report: report}
+	for _, field := range fields {
+		if field.Constant != nil || field.Compute != nil || field.Query != nil {
+			continue
+		}
+		for _, path := range append([]string{field.JSONPath}, field.FallbackPaths...) {
+			if path == "" || isPseudoPath(path) {
+				continue
+			}
+			cp, err := compilePath(path)
+			if err != nil {
+				return nil, err
+			}
+			u.root.addPath(cp)
+		}
+	}
+	if flattenArrayPath != "" {
+		cp, err := compilePath(flattenArrayPath)
+		if err != nil {
+			return nil, err
+		}
+		u.items = u.root
+		for _, key := range cp.childKeys() {
+			if u.items = u.items.keys[key]; u.items == nil {
+				break
+			}
+		}
+		if u.items != nil && !u.items.all {
+			u.items = u.items.any
+		}
+		u.itemsPath = flattenArrayPath + "[*]"
+	}
+	return u, nil
+}
+
+// forRun returns a copy of u for another conversion, with its own seen
+// keys and reporting to report. The key trees are shared: they are not
+// modified once built.
+func (u *unmappedKeys) forRun(report func(path string)) *unmappedKeys {
+	run := *u
+	run.seen = map[string]bool{}
+	run.report = report
+	return &run
+}
+
+// checkRecord reports the unmapped keys of record.
+func (u *unmappedKeys) checkRecord(record map[string]interface{}) {
+	u.walk(record, u.root, "")
+}
+
+// checkItem reports the unmapped keys of a streamed item of the flattened
+// array.
+func (u *unmappedKeys) checkItem(item interface{}) {
+	u.walk(item, u.items, u.itemsPath)
+}
+
+// walk reports the keys of value, found at path, that are not in t. Only
+// the outermost unmapped key of a subtree is reported.
+func (u *unmappedKeys) walk(value interface{}, t *keyTree, path string) {
+	if t == nil {
+		if path != "" && !u.seen[path] {
+			u.seen[path] = true
+			u.report(path)
+		}
+		return
+	}
+	if t.all {
+		return
+	}
+	switch value := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys) // Report new keys in a stable order.
+		for _, key := range keys {
+			child := t.keys[key]
+			if child == nil {
+				child = t.any
+			}
+			u.walk(value[key], child, childPath(path, key))
+		}
+	case []interface{}:
+		for _, element := range value {
+			u.walk(element, t.any, path+"[*]")
+		}
+	}
+}
+
+// reportUnmappedKey records a key found by Options.WarnUnmappedKeys.
+func (c *conversion) reportUnmappedKey(path string) {
+	if c.report != nil {
+		c.report.UnmappedKeys = append(c.report.UnmappedKeys, path)
+	}
+	if c.options.OnUnmappedKey != nil {
+		c.options.OnUnmappedKey(path)
+	}
+}
+
+// childPath returns the path of key below path in the default syntax.
+func childPath(path, key string) string {
+	switch {
+	case !isIdentifier(key):
+		return path + "[" + quoteKey(key) + "]"
+	case path == "":
+		return key
+	}
+	return path + "." + key
+}
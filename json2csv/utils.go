@@ -1,10 +1,14 @@
 // json2csv/utils.go
+
 package json2csv
 
 import (
 	"encoding/json"
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // valueToString is a helper to safely get a string representation of a value.
@@ -19,14 +23,36 @@ func valueToString(value interface{}) string {
 	case string:
 		return v
 	case float64: // JSON numbers are typically decoded as float64 by default
-		return fmt.Sprintf("%g", v) // Use %g to avoid trailing zeros for integers
+		return formatFloat(v, 64) // No trailing zeros for integers, and no exponent for large IDs
+	case float32:
+		return formatFloat(float64(v), 32)
 	case bool:
-		return fmt.Sprintf("%t", v)
-	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, complex64, complex128:
-		// Handle explicit integer types if they occur
-		return fmt.Sprintf("%v", v)
+		return strconv.FormatBool(v)
+	// Handle explicit integer types if they occur, without going through fmt.
+	case int:
+		return strconv.Itoa(v)
+	case int8:
+		return strconv.FormatInt(int64(v), 10)
+	case int16:
+		return strconv.FormatInt(int64(v), 10)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint8:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
 	case json.Number: // If json.Decoder.UseNumber() is used
 		return v.String()
+	case time.Time: // Produced by time transformers such as ParseTime
+		return v.Format(time.RFC3339Nano)
 	default:
 		// For slices, maps, or other complex types at the leaf, stringify them.
 		// This might produce verbose output like map[key:value].
@@ -35,13 +61,27 @@ func valueToString(value interface{}) string {
 	}
 }
 
+// formatFloat formats f like encoding/json: in plain decimal notation
+// unless it is below 1e-6 or from 1e21 in magnitude, so integral values such
+// as IDs are never written as 1e+06.
+func formatFloat(f float64, bitSize int) string {
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		return strconv.FormatFloat(f, 'g', -1, bitSize)
+	}
+	return strconv.FormatFloat(f, 'f', -1, bitSize)
+}
+
 // getFlattenArrayPath determines the path to the primary array for flattening
 // based on the Field JSONPaths. It finds the path segment immediately preceding
 // the first occurrence of "[*]" in any field's path.
 // Returns the array path or an empty string if no field contains "[*]".
 // Assumes if multiple fields use "[*]", they refer to the same base array path.
+// Fields with ArrayPivot do not flatten and are ignored.
 func getFlattenArrayPath(fields []Field) string {
 	for _, field := range fields {
+		if field.ArrayMode == ArrayPivot {
+			continue
+		}
 		starIndex := strings.Index(field.JSONPath, "[*]")
 		if starIndex != -1 {
 			// Found "[*]". The array path is the part before "[*]".
@@ -109,63 +149,17 @@ func getValueForField(field Field, data map[string]interface{}, originalRecord m
 }
 
 
-// getValueByDotPath retrieves a value from a nested map[string]interface{}
-// using a dot-separated path (e.g., "user.address.city").
-// This is a simplified version for paths *without* "[*]".
-// It handles nested maps. If a path segment is not found, or if an intermediate
-// segment is nil or not a map, it returns nil and a nil error, indicating
-// the path could not be fully resolved to a value (e.g., null or missing intermediate).
-// It returns an error only for syntactically invalid paths (like empty segments).
-func getValueByDotPath(data map[string]interface{}, path string) (interface{}, error) {
-	// An empty path for getValueByDotPath after "[*]" is valid for "array[*]" case.
-    // But if called with a non-empty path, empty segments are invalid.
-	if path == "" {
-        // If path is empty, it implies the caller wanted the data map itself (e.g., for "array[*]").
-        // This case is handled in getValueForField. If this function is called with "" path,
-        // it's likely an error in logic flow unless the original path was just "[*]".
-        // Let's return an error if called with empty path when not expected.
-        // Revisit getValueForField: if path is "array[*]", effectivePath is "".
-        // If getValueByDotPath("") is called, it should return the 'data' map itself.
-        // Let's adjust this helper's behavior slightly.
-        return data, nil // If path is empty, return the current data object/map
+// getValueByDotPath retrieves a value from nested JSON data (usually a
+// map[string]interface{}) using a path such as "user.address.city" (see jsonpath.go for the full
+// syntax). If a path segment is not found, or if an intermediate segment is
+// nil or of the wrong type, it returns nil and a nil error, indicating the
+// path could not be fully resolved to a value. Query paths (with wildcards,
+// slices, filters or "..") return the list of matched values instead.
+// It returns an error only for syntactically invalid paths.
+func getValueByDotPath(data interface{}, path string) (interface{}, error) {
+	compiled, err := compilePath(path)
+	if err != nil {
+		return nil, err
 	}
-
-	keys := strings.Split(path, ".")
-	currentValue := interface{}(data)
-
-	for i, key := range keys {
-		if key == "" {
-             // This should ideally not happen with paths correctly derived from JSONPath,
-             // but as a safeguard:
-             return nil, fmt.Errorf("json2csv: invalid dot path segment (empty key) at index %d", i)
-        }
-
-		// Check if the current value is a map
-		if m, ok := currentValue.(map[string]interface{}); ok {
-			// Current value is a map, try to get the next key
-			nextValue, exists := m[key]
-			if !exists {
-				// Key not found at this level. Path cannot be fully resolved.
-				return nil, nil // Return nil value, nil error
-			}
-			currentValue = nextValue
-		} else if currentValue == nil {
-            // Current value is nil. Path cannot be resolved further.
-            return nil, nil // Return nil value, nil error
-        } else {
-			// Current value is not a map, and not nil.
-            // If there are more keys in the path, we can't traverse further.
-            if i < len(keys)-1 {
-                 // Expected a map to continue traversing, but got something else.
-                 // Path cannot be fully resolved.
-                 return nil, nil // Return nil value, nil error
-            }
-            // If it is the last segment, the currentValue is the final value.
-            // This case means the path pointed directly to a non-map, non-nil value.
-            // The currentValue already holds this value.
-		}
-	}
-
-	// Successfully traversed the entire path
-	return currentValue, nil
-}
\ No newline at end of file
+	return compiled.evaluate(data), nil
+}
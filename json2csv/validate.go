@@ -0,0 +1,316 @@
+// json2csv/validate.go
+
+package json2csv
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/language"
+)
+
+// Validate checks options for configuration errors: missing Fields or
+// flattening array, an invalid Delimiter, negative flush, buffer or file
+// sizes, empty or malformed JSONPaths, fields flattening different arrays,
+// misconfigured key expansions, malformed IncludePaths or ExcludePaths and
+// (with DuplicateHeadersError) duplicate headers. All problems found are
+// returned together, joined with errors.Join. Convert and the other
+// conversion functions call Validate before writing any output.
+func (options Options) Validate() error {
+	var errs []error
+	report := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("json2csv: "+format, args...))
+	}
+
+	if options.PathSyntax < PathSyntaxDefault || options.PathSyntax > PathSyntaxJSONPointer {
+		report("unknown PathSyntax %d", options.PathSyntax)
+	}
+	if options.PathResolver != nil && options.PathSyntax != PathSyntaxDefault {
+		report("PathSyntax cannot be combined with a PathResolver")
+	}
+	if options.PathResolver != nil && options.WarnUnmappedKeys {
+		report("WarnUnmappedKeys cannot be combined with a PathResolver")
+	}
+	checkPath := validatePath
+	if options.PathResolver != nil {
+		checkPath = validateResolverPath
+	}
+	options, err := options.nativePaths()
+	if err != nil {
+		return err // The paths cannot be checked further.
+	}
+	if options, err = options.selectFields(); err != nil {
+		return err
+	}
+
+	flattenArrayPath := getFlattenArrayPath(options.Fields)
+	pivots := slices.ContainsFunc(options.Fields, func(field Field) bool { return field.ArrayMode == ArrayPivot })
+	if len(options.Fields) == 0 {
+		report("no Fields are configured")
+	} else if flattenArrayPath == "" && !pivots {
+		report("flattening is the only supported mode. At least one Field JSONPath must contain '[*]'")
+	}
+
+	if options.Format == FormatCSV {
+		if d := options.delimiter(); d == '"' || d == '\r' || d == '\n' || !utf8.ValidRune(d) || d == utf8.RuneError {
+			report("invalid Delimiter %q", d)
+		}
+		if options.CSVWriter != nil && options.Lossless {
+			report("CSVWriter cannot be combined with Lossless")
+		}
+	}
+
+	if options.StreamItems && flattenArrayPath == "" {
+		report("StreamItems requires a flattened array")
+	} else if options.StreamItems {
+		if compiled, err := compilePath(flattenArrayPath); err == nil && compiled.childKeys() == nil {
+			report("StreamItems requires a flattened array addressed by object keys, got %q", flattenArrayPath)
+		}
+	}
+	if options.FlushEvery < 0 {
+		report("negative FlushEvery %d", options.FlushEvery)
+	}
+	if options.PartitionBy != "" {
+		if isPseudoPath(options.PartitionBy) {
+			if !isKnownPseudoPath(options.PartitionBy) {
+				report("PartitionBy: unknown pseudo-path %q", options.PartitionBy)
+			}
+		} else if err := validatePath(options.PartitionBy); err != nil {
+			report("PartitionBy: %s", strings.TrimPrefix(err.Error(), "json2csv: "))
+		} else if starIndex := strings.Index(options.PartitionBy, "[*]"); starIndex != -1 {
+			if arrayPath := strings.TrimSuffix(options.PartitionBy[:starIndex], "."); arrayPath != flattenArrayPath {
+				report("PartitionBy: flattens array %q, but the fields flatten %q", arrayPath, flattenArrayPath)
+			}
+		}
+		// The partition of a row is known only while it is converted, not
+		// when sorted or grouped rows are written.
+		if len(options.SortBy) > 0 {
+			report("PartitionBy cannot be combined with SortBy")
+		}
+		if options.GroupBy != nil {
+			report("PartitionBy cannot be combined with GroupBy")
+		}
+	}
+	if options.MaxRowsPerFile < 0 || options.MaxBytesPerFile < 0 {
+		report("negative MaxRowsPerFile or MaxBytesPerFile")
+	}
+	if options.WriterBufferSize < 0 {
+		report("negative WriterBufferSize %d", options.WriterBufferSize)
+	}
+	for _, pattern := range append(slices.Clip(options.IncludePaths), options.ExcludePaths...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			report("invalid path pattern %q: %v", pattern, err)
+		}
+	}
+	if options.Offset < 0 || options.Limit < 0 {
+		report("negative Offset or Limit")
+	}
+	if options.LimitUnit < LimitRows || options.LimitUnit > LimitRecords {
+		report("unknown LimitUnit %d", options.LimitUnit)
+	}
+	if options.GroupBy != nil {
+		if options.StatsWriter != nil || options.SchemaWriter != nil {
+			report("GroupBy cannot be combined with StatsWriter or SchemaWriter")
+		}
+		for i, aggregate := range options.GroupBy.Aggregates {
+			if _, ok := aggregateFuncNames[aggregate.Func]; !ok {
+				report("GroupBy aggregate %d: unknown %v", i+1, aggregate.Func)
+			} else if aggregate.Column == "" && aggregate.Func != AggregateCount {
+				report("GroupBy aggregate %d: %v requires a Column", i+1, aggregate.Func)
+			}
+		}
+	}
+	for _, key := range options.SortBy {
+		if key.Column == "" {
+			report("SortBy: empty column")
+		}
+	}
+	if options.SortMemory < 0 {
+		report("negative SortMemory %d", options.SortMemory)
+	}
+	if options.SampleEvery < 0 {
+		report("negative SampleEvery %d", options.SampleEvery)
+	}
+	if !(options.SampleRate >= 0 && options.SampleRate <= 1) {
+		report("SampleRate %v is not between 0 and 1", options.SampleRate)
+	}
+	if options.Newlines < NewlinesKeep || options.Newlines > NewlinesEscape {
+		report("unknown Newlines policy %d", options.Newlines)
+	} else if options.Newlines == NewlinesReplace && strings.ContainsAny(options.NewlineReplacement, "\r\n") {
+		report("NewlineReplacement %q contains a line break", options.NewlineReplacement)
+	}
+	if schema := options.ExpectedSchema; schema != nil {
+		for _, path := range slices.Sorted(maps.Keys(schema.Paths)) {
+			if _, err := schemaSegments(path); err != nil {
+				errs = append(errs, err)
+			} else if _, ok := fieldTypeNames[schema.Paths[path]]; !ok {
+				report("ExpectedSchema: path %q: unknown %v", path, schema.Paths[path])
+			}
+		}
+		for _, action := range []DriftAction{schema.NewKeys, schema.MissingKeys, schema.TypeChanges} {
+			if action < DriftWarn || action > DriftIgnore {
+				report("ExpectedSchema: unknown DriftAction %d", action)
+			}
+		}
+	}
+	if options.MaxRecordBytes < 0 {
+		report("MaxRecordBytes %d is negative", options.MaxRecordBytes)
+	} else if options.MaxRecordBytes > 0 && options.StreamItems {
+		report("MaxRecordBytes cannot be combined with StreamItems")
+	}
+	if options.OversizedRecords < OversizedRecordsError || options.OversizedRecords > OversizedRecordsSkip {
+		report("unknown OversizedRecords policy %d", options.OversizedRecords)
+	}
+	if options.InputEncoding < InputEncodingUTF8 || options.InputEncoding > InputEncodingUTF16BE {
+		report("unknown InputEncoding %d", options.InputEncoding)
+	}
+	if options.MalformedRecords < MalformedRecordsError || options.MalformedRecords > MalformedRecordsSkip {
+		report("unknown MalformedRecords policy %d", options.MalformedRecords)
+	}
+	if options.DiscoveryRecords < 0 {
+		report("DiscoveryRecords %d is negative", options.DiscoveryRecords)
+	}
+	if options.MaxCellLength < 0 {
+		report("MaxCellLength %d is negative", options.MaxCellLength)
+	}
+	if options.LongCells < LongCellsTruncate || options.LongCells > LongCellsHash {
+		report("unknown LongCells policy %d", options.LongCells)
+	} else if hashLength := len(LongCellHashPrefix) + 2*sha256.Size; options.LongCells == LongCellsHash && options.MaxCellLength > 0 && options.MaxCellLength < hashLength {
+		report("MaxCellLength %d is shorter than the %d bytes of a LongCellsHash cell", options.MaxCellLength, hashLength)
+	}
+	if options.EmptyArrayBehavior < EmptyArraySkipRecord || options.EmptyArrayBehavior > EmptyArrayError {
+		report("unknown EmptyArrayBehavior %d", options.EmptyArrayBehavior)
+	}
+	if options.InvalidItems < InvalidItemsError || options.InvalidItems > InvalidItemsStringify {
+		report("unknown InvalidItems %d", options.InvalidItems)
+	}
+	if options.HeaderOnEmpty < HeaderOnEmptyWrite || options.HeaderOnEmpty > HeaderOnEmptyOmit {
+		report("unknown HeaderOnEmpty %d", options.HeaderOnEmpty)
+	}
+	if options.HeaderCase < HeaderCaseAsIs || options.HeaderCase > HeaderCaseLower {
+		report("unknown HeaderCase %d", options.HeaderCase)
+	}
+	if options.Locale != "" {
+		if _, err := language.Parse(options.Locale); err != nil {
+			report("invalid Locale %q: %v", options.Locale, err)
+		}
+	}
+	if options.SchemaFormat < SchemaTableSchema || options.SchemaFormat > SchemaSQL {
+		report("unknown SchemaFormat %d", options.SchemaFormat)
+	}
+
+	for i, field := range options.Fields {
+		name := fmt.Sprintf("field %d", i+1)
+		if field.CSVHeader != "" {
+			name += fmt.Sprintf(" (%q)", field.CSVHeader)
+		}
+
+		if len(field.FallbackPaths) > 0 && (field.Constant != nil || field.Compute != nil || field.Query != nil || field.Expand != nil || field.ArrayMode != ArrayFlatten) {
+			report("%s: FallbackPaths cannot be combined with a Constant, Compute, Query, key expansion or ArrayPivot", name)
+		}
+		for _, fallback := range field.FallbackPaths {
+			if isPseudoPath(fallback) {
+				if !isKnownPseudoPath(fallback) {
+					report("%s: unknown pseudo-path %q", name, fallback)
+				}
+			} else if err := checkPath(fallback); err != nil {
+				report("%s: fallback path: %s", name, strings.TrimPrefix(err.Error(), "json2csv: "))
+			} else if starIndex := strings.Index(fallback, "[*]"); starIndex != -1 {
+				if arrayPath := strings.TrimSuffix(fallback[:starIndex], "."); arrayPath != flattenArrayPath {
+					report("%s: fallback path %q flattens array %q, but %q is flattened", name, fallback, arrayPath, flattenArrayPath)
+				}
+			}
+		}
+
+		switch {
+		case field.Query != nil:
+			if field.JSONPath != "" || field.Constant != nil || field.Compute != nil {
+				report("%s: a Query field cannot have a JSONPath, a Constant or a Compute", name)
+			} else if field.Expand != nil || field.ArrayMode != ArrayFlatten {
+				report("%s: a Query field cannot be expanded or pivoted", name)
+			}
+			continue
+		case field.Compute != nil:
+			if field.JSONPath != "" || field.Constant != nil {
+				report("%s: a computed field cannot have a JSONPath or a Constant", name)
+			} else if field.Expand != nil || field.ArrayMode != ArrayFlatten {
+				report("%s: a computed field cannot be expanded or pivoted", name)
+			}
+			continue
+		case field.Constant != nil:
+			if field.JSONPath != "" {
+				report("%s: has both a JSONPath and a Constant", name)
+			} else if field.Expand != nil || field.ArrayMode != ArrayFlatten {
+				report("%s: a Constant cannot be expanded or pivoted", name)
+			}
+			continue
+		case field.JSONPath == "":
+			report("%s: JSONPath is empty", name)
+			continue
+		case isPseudoPath(field.JSONPath):
+			if !isKnownPseudoPath(field.JSONPath) {
+				report("%s: unknown pseudo-path %q", name, field.JSONPath)
+			}
+			continue
+		}
+		if err := checkPath(field.JSONPath); err != nil {
+			report("%s: %s", name, strings.TrimPrefix(err.Error(), "json2csv: "))
+			continue
+		}
+		if options.PathResolver != nil && (field.Expand != nil || field.ArrayMode != ArrayFlatten) {
+			report("%s: key expansion and ArrayPivot cannot be combined with a PathResolver", name)
+		}
+		if field.ArrayMode == ArrayPivot {
+			switch {
+			case strings.Count(field.JSONPath, "[*]") != 1:
+				report("%s: ArrayPivot requires a path with exactly one \"[*]\"", name)
+			case field.MaxElements <= 0:
+				report("%s: ArrayPivot requires a positive MaxElements", name)
+			case field.Expand != nil:
+				report("%s: ArrayPivot cannot be combined with key expansion", name)
+			}
+		} else if field.ArrayMode != ArrayFlatten {
+			report("%s: unknown ArrayMode %d", name, field.ArrayMode)
+		} else if starIndex := strings.Index(field.JSONPath, "[*]"); starIndex != -1 {
+			if arrayPath := strings.TrimSuffix(field.JSONPath[:starIndex], "."); arrayPath != flattenArrayPath {
+				report("%s: flattens array %q, but another field flattens %q; only one array can be flattened", name, arrayPath, flattenArrayPath)
+			}
+		}
+		if _, ok := fieldTypeNames[field.Type]; !ok {
+			report("%s: unknown %v", name, field.Type)
+		}
+		if field.Locale != "" && field.Locale != LocaleNone {
+			if _, err := language.Parse(field.Locale); err != nil {
+				report("%s: invalid Locale %q: %v", name, field.Locale, err)
+			}
+		}
+		if _, ok := fieldErrorPolicyNames[field.OnError]; !ok {
+			report("%s: unknown %v", name, field.OnError)
+		}
+		if field.Expand != nil && !strings.HasSuffix(field.JSONPath, ".*") {
+			report("%s: key expansion requires a path ending in \".*\"", name)
+		}
+	}
+
+	if fields, _, err := expandFields(options.Fields, nil); err == nil && options.DuplicateHeaders == DuplicateHeadersError {
+		header := make([]string, len(fields))
+		for i, field := range fields {
+			header[i] = field.CSVHeader
+			if header[i] == "" {
+				header[i] = options.HeaderNaming.header(field.JSONPath)
+			}
+			header[i] = options.HeaderCase.apply(header[i])
+		}
+		if _, err := options.DuplicateHeaders.apply(header); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,40 @@
+// json2csv/whitespace.go
+
+package json2csv
+
+import (
+	"strings"
+	"unicode"
+)
+
+// normalizeSpace applies Options.TrimSpace and Options.CollapseWhitespace
+// to s.
+func (options Options) normalizeSpace(s string) string {
+	if options.CollapseWhitespace {
+		s = collapseSpace(s)
+	}
+	if options.TrimSpace {
+		s = strings.TrimSpace(s)
+	}
+	return s
+}
+
+// collapseSpace replaces each run of white space in s, including line
+// breaks, with a single space.
+func collapseSpace(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	inSpace := false
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			if !inSpace {
+				b.WriteByte(' ')
+			}
+			inSpace = true
+			continue
+		}
+		inSpace = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
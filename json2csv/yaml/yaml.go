@@ -0,0 +1,209 @@
+// json2csv/yaml/yaml.go
+
+// Package yaml converts YAML input, such as configuration inventories,
+// through the same pipeline as JSON, decoded with gopkg.in/yaml.v3:
+//
+//	err := yaml.Convert(f, os.Stdout, json2csv.Options{Fields: fields})
+//
+// The input is a list of mappings, each converted like an object of a JSON
+// array, or a stream of documents ("---") each holding a mapping or a list
+// of mappings. Values are converted to their JSON equivalents, with numbers
+// as json.Number keeping their text, so fields and transformers see the
+// same records as for the equivalent JSON. Aliases and merge keys ("<<")
+// are resolved; timestamps and other scalars are kept as strings.
+//
+// It is a separate package so that programs not reading YAML do not depend
+// on yaml.v3.
+package yaml
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	goyaml "gopkg.in/yaml.v3"
+
+	"github.com/pradnyoday/go-json2csv/json2csv"
+)
+
+// Convert converts the YAML records of r like json2csv.ConvertRecords.
+func Convert(r io.Reader, w io.Writer, options json2csv.Options) error {
+	return json2csv.ConvertRecords(Records(r), w, options)
+}
+
+// Records returns a RecordSource that decodes the records of r. Documents
+// are decoded one at a time, so a stream of documents is converted without
+// holding it in memory, while a single list is decoded whole.
+func Records(r io.Reader) json2csv.RecordSource {
+	decoder := goyaml.NewDecoder(r)
+	var (
+		pending []*goyaml.Node
+		record  int
+	)
+	return json2csv.RecordFunc(func() (map[string]interface{}, error) {
+		for len(pending) == 0 {
+			var document goyaml.Node
+			if err := decoder.Decode(&document); err == io.EOF {
+				return nil, io.EOF
+			} else if err != nil {
+				return nil, fmt.Errorf("json2csv: failed to decode yaml document: %w", err)
+			}
+			if len(document.Content) == 0 {
+				continue
+			}
+			switch root := resolve(document.Content[0]); {
+			case root.Kind == goyaml.SequenceNode:
+				pending = root.Content
+			case root.Kind == goyaml.MappingNode:
+				pending = []*goyaml.Node{root}
+			case root.Tag == "!!null":
+				// An empty document
+			default:
+				return nil, fmt.Errorf("json2csv: yaml document at line %d is not a list of mappings", root.Line)
+			}
+		}
+		node := pending[0]
+		pending = pending[1:]
+		record++
+
+		v, err := value(node, nil)
+		if err != nil {
+			return nil, fmt.Errorf("json2csv: failed to convert yaml record %d: %w", record-1, err)
+		}
+		if v == nil {
+			return nil, nil
+		}
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("json2csv: yaml record %d at line %d is not a mapping", record-1, node.Line)
+		}
+		return m, nil
+	})
+}
+
+// resolve returns the node an alias refers to, or node itself.
+func resolve(node *goyaml.Node) *goyaml.Node {
+	for node.Kind == goyaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+// value returns the JSON equivalent of node. aliases holds the aliases
+// being expanded, to reject recursive ones.
+func value(node *goyaml.Node, aliases map[*goyaml.Node]bool) (interface{}, error) {
+	switch node.Kind {
+	case goyaml.AliasNode:
+		if aliases[node] {
+			return nil, fmt.Errorf("line %d: recursive alias *%s", node.Line, node.Value)
+		}
+		if aliases == nil {
+			aliases = map[*goyaml.Node]bool{}
+		}
+		aliases[node] = true
+		defer delete(aliases, node)
+		return value(node.Alias, aliases)
+
+	case goyaml.SequenceNode:
+		list := make([]interface{}, len(node.Content))
+		for i, item := range node.Content {
+			v, err := value(item, aliases)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = v
+		}
+		return list, nil
+
+	case goyaml.MappingNode:
+		m := make(map[string]interface{}, len(node.Content)/2)
+		if err := merge(m, node, aliases); err != nil {
+			return nil, err
+		}
+		return m, nil
+
+	case goyaml.ScalarNode:
+		return scalar(node)
+	}
+	return nil, fmt.Errorf("line %d: unsupported yaml node", node.Line)
+}
+
+// merge adds the keys of mapping to m. Keys of the mapping itself override
+// keys merged with "<<", which override keys already in m; of a list of
+// merged mappings, the first takes precedence.
+func merge(m map[string]interface{}, mapping *goyaml.Node, aliases map[*goyaml.Node]bool) error {
+	var merged []*goyaml.Node
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if key := mapping.Content[i]; key.Kind == goyaml.ScalarNode && key.Tag == "!!merge" {
+			merged = append(merged, mapping.Content[i+1])
+		}
+	}
+	for _, source := range merged {
+		sources := []*goyaml.Node{source}
+		if source = resolve(source); source.Kind == goyaml.SequenceNode {
+			sources = source.Content
+		}
+		for i := len(sources) - 1; i >= 0; i-- {
+			source := resolve(sources[i])
+			if source.Kind != goyaml.MappingNode {
+				return fmt.Errorf("line %d: merge key value is not a mapping", source.Line)
+			}
+			if err := merge(m, source, aliases); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key := resolve(mapping.Content[i])
+		if key.Tag == "!!merge" {
+			continue
+		}
+		if key.Kind != goyaml.ScalarNode {
+			return fmt.Errorf("line %d: mapping key is not a scalar", key.Line)
+		}
+		v, err := value(mapping.Content[i+1], aliases)
+		if err != nil {
+			return err
+		}
+		m[key.Value] = v
+	}
+	return nil
+}
+
+// scalar returns the JSON equivalent of a scalar node.
+func scalar(node *goyaml.Node) (interface{}, error) {
+	switch node.Tag {
+	case "!!null":
+		return nil, nil
+	case "!!bool":
+		var b bool
+		if err := node.Decode(&b); err != nil {
+			return nil, err
+		}
+		return b, nil
+	case "!!int", "!!float":
+		if json.Valid([]byte(node.Value)) {
+			return json.Number(node.Value), nil
+		}
+		// E.g. 0x1F, 1_000 or .inf
+		var v interface{}
+		if err := node.Decode(&v); err != nil {
+			return nil, err
+		}
+		switch v := v.(type) {
+		case int:
+			return json.Number(strconv.Itoa(v)), nil
+		case int64:
+			return json.Number(strconv.FormatInt(v, 10)), nil
+		case uint64:
+			return json.Number(strconv.FormatUint(v, 10)), nil
+		case float64:
+			return v, nil
+		}
+		return nil, errors.New("unsupported yaml number " + strconv.Quote(node.Value))
+	}
+	return node.Value, nil
+}
@@ -0,0 +1,216 @@
+// json2csv/zip.go
+
+package json2csv
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+)
+
+// ZipOptions configures ConvertToZip.
+type ZipOptions struct {
+	// PartName is the fmt pattern for data file names, formatted with the
+	// 1-based part number. Defaults to "part-%04d.csv" (".md" or ".html" for
+	// the other formats).
+	PartName string
+
+	// MaxRowsPerPart starts a new part file after this many data rows. Every
+	// part has its own header row. Zero means a single part.
+	MaxRowsPerPart int
+
+	// StatsName is the name of the column statistics file (see
+	// Options.StatsWriter). Defaults to "stats.csv".
+	StatsName string
+
+	// OmitStats leaves the statistics file out of the archive.
+	OmitStats bool
+
+	// ManifestName is the name of the manifest file. Defaults to "manifest.json".
+	ManifestName string
+}
+
+// ZipManifest is the content of the manifest file written by ConvertToZip.
+type ZipManifest struct {
+	// Generator identifies the producing library and version.
+	Generator string `json:"generator"`
+
+	// Created is the time the archive was written.
+	Created time.Time `json:"created"`
+
+	// Header is the header row of the data files.
+	Header []string `json:"header"`
+
+	// TotalRows is the number of data rows over all parts.
+	TotalRows int `json:"total_rows"`
+
+	// Files lists the data and statistics files in the archive.
+	Files []ZipManifestFile `json:"files"`
+}
+
+// ZipManifestFile describes one file listed in a ZipManifest.
+type ZipManifestFile struct {
+	Name   string `json:"name"`
+	Rows   int    `json:"rows"` // Rows excluding the header.
+	Bytes  int64  `json:"bytes"`
+	SHA256 string `json:"sha256"`
+}
+
+// ConvertToZip converts r like Convert and writes a single zip archive to w
+// containing the data as one or more part files, the column statistics and
+// a JSON manifest (see ZipManifest) listing every file with its row count,
+// size and SHA-256 checksum. Options.WrapOutput applies to the whole archive.
+func ConvertToZip(r io.Reader, w io.Writer, options Options, zipOptions ZipOptions) error {
+	zipOptions.setDefaults(options.Format)
+
+	return withOutput(w, options, func(w io.Writer) error {
+		archive := zip.NewWriter(w)
+		manifest := ZipManifest{
+			Generator: "json2csv " + Version,
+			Created:   time.Now().UTC(),
+		}
+
+		var stats bytes.Buffer
+		if !zipOptions.OmitStats {
+			if options.StatsWriter != nil {
+				options.StatsWriter = io.MultiWriter(options.StatsWriter, &stats)
+			} else {
+				options.StatsWriter = &stats
+			}
+		}
+
+		parts := &splitRowWriter{
+			maxRows: zipOptions.MaxRowsPerPart,
+			open: func(part int) (RowWriter, error) {
+				name := fmt.Sprintf(zipOptions.PartName, part)
+				entry, err := archive.Create(name)
+				if err != nil {
+					return nil, fmt.Errorf("json2csv: failed to create zip entry %q: %w", name, err)
+				}
+				hw := newHashingWriter(entry)
+				return &zipPartWriter{
+					RowWriter: NewRowWriter(hw, options),
+					hw:        hw,
+					onClose: func(rows int) {
+						manifest.TotalRows += rows
+						manifest.Files = append(manifest.Files, hw.manifestFile(name, rows))
+					},
+				}, nil
+			},
+		}
+		if err := ConvertTo(r, parts, options); err != nil {
+			return err
+		}
+		manifest.Header = parts.header
+
+		if !zipOptions.OmitStats {
+			// The statistics file has one row per output column.
+			if err := writeZipFile(archive, zipOptions.StatsName, stats.Bytes(), len(parts.header), &manifest); err != nil {
+				return err
+			}
+		}
+
+		manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+		if err != nil {
+			return fmt.Errorf("json2csv: failed to encode zip manifest: %w", err)
+		}
+		if err := writeZipFile(archive, zipOptions.ManifestName, manifestJSON, 0, nil); err != nil {
+			return err
+		}
+
+		if err := archive.Close(); err != nil {
+			return fmt.Errorf("json2csv: failed to finish zip archive: %w", err)
+		}
+		return nil
+	})
+}
+
+func (z *ZipOptions) setDefaults(format Format) {
+	if z.PartName == "" {
+		switch format {
+		case FormatMarkdown:
+			z.PartName = "part-%04d.md"
+		case FormatHTML:
+			z.PartName = "part-%04d.html"
+		default:
+			z.PartName = "part-%04d.csv"
+		}
+	}
+	if z.StatsName == "" {
+		z.StatsName = "stats.csv"
+	}
+	if z.ManifestName == "" {
+		z.ManifestName = "manifest.json"
+	}
+}
+
+// writeZipFile adds a file with content to archive, listing it in manifest
+// with rows rows if manifest is not nil.
+func writeZipFile(archive *zip.Writer, name string, content []byte, rows int, manifest *ZipManifest) error {
+	entry, err := archive.Create(name)
+	if err != nil {
+		return fmt.Errorf("json2csv: failed to create zip entry %q: %w", name, err)
+	}
+	hw := newHashingWriter(entry)
+	if _, err := hw.Write(content); err != nil {
+		return fmt.Errorf("json2csv: failed to write zip entry %q: %w", name, err)
+	}
+	if manifest != nil {
+		manifest.Files = append(manifest.Files, hw.manifestFile(name, rows))
+	}
+	return nil
+}
+
+// zipPartWriter counts the data rows of a part and reports them on Close.
+type zipPartWriter struct {
+	RowWriter
+	hw      *hashingWriter
+	rows    int
+	onClose func(rows int)
+}
+
+func (z *zipPartWriter) WriteRow(row []string) error {
+	z.rows++
+	return z.RowWriter.WriteRow(row)
+}
+
+func (z *zipPartWriter) Close() error {
+	if err := z.RowWriter.Close(); err != nil {
+		return err
+	}
+	z.onClose(z.rows)
+	return nil
+}
+
+// hashingWriter counts and hashes the bytes written through it.
+type hashingWriter struct {
+	w     io.Writer
+	hash  hash.Hash
+	bytes int64
+}
+
+func newHashingWriter(w io.Writer) *hashingWriter {
+	return &hashingWriter{w: w, hash: sha256.New()}
+}
+
+func (h *hashingWriter) Write(p []byte) (int, error) {
+	n, err := h.w.Write(p)
+	h.hash.Write(p[:n])
+	h.bytes += int64(n)
+	return n, err
+}
+
+func (h *hashingWriter) manifestFile(name string, rows int) ZipManifestFile {
+	return ZipManifestFile{
+		Name:   name,
+		Rows:   rows,
+		Bytes:  h.bytes,
+		SHA256: hex.EncodeToString(h.hash.Sum(nil)),
+	}
+}